@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/email"
+	"github.com/starttoaster/trivy-operator-explorer/internal/jira"
 	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
 	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
 	"github.com/starttoaster/trivy-operator-explorer/internal/web"
@@ -46,7 +52,105 @@ var rootCmd = &cobra.Command{
 		if viper.GetString("server-port") == "" {
 			log.Fatal("server port flag not set. Should be 8080 by default. This likely means it was overridden by user input with no value.")
 		}
-		cobra.CheckErr(web.Start(viper.GetString("server-port")))
+		if viper.GetString("ops-port") == "" {
+			log.Fatal("ops port flag not set. Should be 8081 by default. This likely means it was overridden by user input with no value.")
+		}
+
+		// Shut down gracefully on SIGTERM, which is what Kubernetes sends when stopping a Pod
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+
+		err = web.Start(ctx, web.Config{
+			Port:    viper.GetString("server-port"),
+			OpsPort: viper.GetString("ops-port"),
+			Auth: web.AuthConfig{
+				Mode:              viper.GetString("auth-mode"),
+				ProxyUserHeader:   viper.GetString("auth-proxy-user-header"),
+				ProxyEmailHeader:  viper.GetString("auth-proxy-email-header"),
+				ProxyTrustedCIDRs: splitList(viper.GetString("auth-proxy-trusted-cidrs")),
+				ProxyAdmins:       splitList(viper.GetString("auth-proxy-admins")),
+			},
+			TrustedOrigins: splitList(viper.GetString("server-trusted-origins")),
+			MaxBodyBytes:   viper.GetInt64("server-max-body-bytes"),
+			Timeouts: web.TimeoutConfig{
+				ReadHeader: viper.GetDuration("server-read-header-timeout"),
+				Read:       viper.GetDuration("server-read-timeout"),
+				Write:      viper.GetDuration("server-write-timeout"),
+				Idle:       viper.GetDuration("server-idle-timeout"),
+				Shutdown:   viper.GetDuration("server-shutdown-timeout"),
+			},
+			KubeRequestTimeout: viper.GetDuration("kube-request-timeout"),
+			TLSCertFile:        viper.GetString("tls-cert-file"),
+			TLSKeyFile:         viper.GetString("tls-key-file"),
+			Snapshots: web.SnapshotConfig{
+				Interval:       viper.GetDuration("snapshot-interval"),
+				Retention:      viper.GetDuration("snapshot-retention"),
+				LedgerInterval: viper.GetDuration("ledger-interval"),
+			},
+			SLA: web.SLAConfig{
+				Policies:   viper.GetString("sla-policies"),
+				Warning:    viper.GetDuration("sla-warning"),
+				OwnerLabel: viper.GetString("sla-owner-label"),
+			},
+			Notifications: web.NotificationConfig{
+				Webhooks:            viper.GetString("notify-webhooks"),
+				Interval:            viper.GetDuration("notify-interval"),
+				MinSeverity:         viper.GetString("notify-min-severity"),
+				RateLimit:           viper.GetInt("notify-rate-limit"),
+				IgnoreExpiryWarning: viper.GetDuration("notify-ignore-expiry-warning"),
+			},
+			Digest: web.DigestConfig{
+				Recipients: viper.GetString("digest-recipients"),
+				Schedule:   viper.GetString("digest-schedule"),
+				SMTP: email.Config{
+					Host:     viper.GetString("smtp-host"),
+					Port:     viper.GetInt("smtp-port"),
+					Username: viper.GetString("smtp-username"),
+					Password: viper.GetString("smtp-password"),
+					From:     viper.GetString("smtp-from"),
+					TLS:      viper.GetString("smtp-tls"),
+					Timeout:  viper.GetDuration("smtp-timeout"),
+				},
+			},
+			Tickets: web.TicketConfig{
+				Jira: jira.Config{
+					URL:                 viper.GetString("jira-url"),
+					Project:             viper.GetString("jira-project"),
+					IssueType:           viper.GetString("jira-issue-type"),
+					Username:            viper.GetString("jira-username"),
+					Token:               viper.GetString("jira-token"),
+					Labels:              splitList(viper.GetString("jira-labels")),
+					SummaryTemplate:     viper.GetString("jira-summary-template"),
+					DescriptionTemplate: viper.GetString("jira-description-template"),
+					Timeout:             viper.GetDuration("jira-timeout"),
+				},
+				Automatic: viper.GetBool("jira-automatic"),
+				Interval:  viper.GetDuration("jira-interval"),
+			},
+			KubeEvents: web.KubeEventsConfig{
+				Enabled:  viper.GetBool("kube-events"),
+				Interval: viper.GetDuration("kube-events-interval"),
+			},
+			PolicyReports: web.PolicyReportsConfig{
+				Enabled:  viper.GetBool("policy-reports"),
+				Interval: viper.GetDuration("policy-reports-interval"),
+			},
+			Admission: web.AdmissionConfig{
+				Mode:             viper.GetString("admission-mode"),
+				Port:             viper.GetString("admission-port"),
+				TLSCertFile:      viper.GetString("admission-tls-cert-file"),
+				TLSKeyFile:       viper.GetString("admission-tls-key-file"),
+				ExemptNamespaces: splitList(viper.GetString("admission-exempt-namespaces")),
+				Interval:         viper.GetDuration("admission-interval"),
+			},
+			ExternalURL: viper.GetString("external-url"),
+		})
+
+		if dbErr := db.Close(); dbErr != nil {
+			log.Logger.Error("error closing database", "error", dbErr)
+		}
+		cobra.CheckErr(err)
+		log.Logger.Info("shutdown complete")
 	},
 }
 
@@ -66,6 +170,64 @@ func init() {
 	rootCmd.PersistentFlags().Uint16("server-port", 8080, "The port the metrics server binds to.")
 	rootCmd.PersistentFlags().String("kubeconfig", "", "The path to a kubeconfig. Assumes in-cluster configuration if left blank.")
 	rootCmd.PersistentFlags().String("db-path", "./", "The path to the directory containing the sqlite database.")
+	rootCmd.PersistentFlags().Uint16("ops-port", 8081, "The port the health check server binds to. Must be different from the server port.")
+	rootCmd.PersistentFlags().Duration("server-read-header-timeout", 10*time.Second, "The maximum duration for reading request headers.")
+	rootCmd.PersistentFlags().Duration("server-read-timeout", 30*time.Second, "The maximum duration for reading an entire request, including the body.")
+	rootCmd.PersistentFlags().Duration("server-write-timeout", 60*time.Second, "The maximum duration before timing out writes of a response. Large clusters may need this raised.")
+	rootCmd.PersistentFlags().Duration("server-idle-timeout", 120*time.Second, "The maximum amount of time to wait for the next request on a keep-alive connection.")
+	rootCmd.PersistentFlags().Duration("server-shutdown-timeout", 25*time.Second, "The maximum duration to wait for in-flight requests to finish after receiving SIGTERM.")
+	rootCmd.PersistentFlags().Duration("kube-request-timeout", 30*time.Second, "The maximum duration for the Kubernetes API calls made while loading a page, including retries of transient errors.")
+	rootCmd.PersistentFlags().String("tls-cert-file", "", "The path to a TLS certificate. Enables TLS on the server port when set along with the key file. Reloaded when the file changes.")
+	rootCmd.PersistentFlags().String("tls-key-file", "", "The path to a TLS private key. Enables TLS on the server port when set along with the certificate file. Reloaded when the file changes.")
+	rootCmd.PersistentFlags().String("server-trusted-origins", "", "A comma separated list of origins, like https://explorer.example.com, allowed to make changes from a browser in addition to the server's own host. Useful when a proxy rewrites the Host header.")
+	rootCmd.PersistentFlags().Int64("server-max-body-bytes", 1<<20, "The maximum request body size in bytes for requests that make changes, like ignoring CVEs.")
+	rootCmd.PersistentFlags().Duration("snapshot-interval", time.Hour, "How often finding counts are recorded to the database for the trend charts, updating the findings ledger as they are. Set to 0 to disable snapshots, the ledger is then updated on the ledger interval.")
+	rootCmd.PersistentFlags().Duration("snapshot-retention", 90*24*time.Hour, "How long snapshots are kept in the database. Set to 0 to keep them forever.")
+	rootCmd.PersistentFlags().Duration("ledger-interval", time.Hour, "How often the findings ledger behind first seen times, ages, MTTR and SLAs is updated while snapshots are disabled. Snapshots update it when they're enabled. Set to 0 to stop updating it.")
+	rootCmd.PersistentFlags().String("sla-policies", "", "A YAML or JSON list of remediation SLA policies, each with a name, the days critical, high, medium and low findings must be fixed in, and an optional namespaceSelector of labels. The first policy matching a namespace applies. SLAs aren't evaluated when empty.")
+	rootCmd.PersistentFlags().Duration("sla-warning", 7*24*time.Hour, "How long before its SLA deadline a finding is listed as due soon.")
+	rootCmd.PersistentFlags().String("sla-owner-label", "owner", "The namespace label naming the team that owns the namespace's findings.")
+	rootCmd.PersistentFlags().String("notify-webhooks", "", "A YAML or JSON list of webhooks notified about new findings, each with a name, a url, an optional format of slack, teams or json, an optional minSeverity, optional kinds of event and an optional Go template for the payload. Notifications are disabled when empty.")
+	rootCmd.PersistentFlags().Duration("notify-interval", 5*time.Minute, "How often the reports are checked for new findings to notify webhooks about. Set to 0 to disable notifications.")
+	rootCmd.PersistentFlags().String("notify-min-severity", "CRITICAL", "The lowest severity of new finding webhooks are notified about, unless a webhook sets its own. Can be one of CRITICAL, HIGH, MEDIUM, LOW, UNKNOWN.")
+	rootCmd.PersistentFlags().Int("notify-rate-limit", 10, "The maximum number of messages sent to each webhook per hour. Findings held back are sent once the limit allows. Set to 0 for no limit.")
+	rootCmd.PersistentFlags().Duration("notify-ignore-expiry-warning", 72*time.Hour, "How long before an ignored CVE's ignore expires that webhooks are notified about it. Set to 0 to disable these notifications.")
+	rootCmd.PersistentFlags().String("digest-recipients", "", "A YAML or JSON list of digest recipients, each with a name, a list of to addresses and an optional list of namespaces to scope their digest to. Digests are disabled when empty.")
+	rootCmd.PersistentFlags().String("digest-schedule", "Mon 08:00", "When digests are emailed, as a day of the week or daily followed by a 24 hour time, like \"Mon 08:00\" or \"daily 08:00\". Uses the server's time zone, set with the TZ environment variable.")
+	rootCmd.PersistentFlags().String("smtp-host", "", "The SMTP server digests are sent through. Required when there are digest recipients.")
+	rootCmd.PersistentFlags().Int("smtp-port", 587, "The SMTP server's port.")
+	rootCmd.PersistentFlags().String("smtp-username", "", "The username to authenticate to the SMTP server with. Authentication is skipped when empty.")
+	rootCmd.PersistentFlags().String("smtp-password", "", "The password to authenticate to the SMTP server with.")
+	rootCmd.PersistentFlags().String("smtp-from", "", "The address digests are sent from, like \"Trivy Explorer <trivy@example.com>\".")
+	rootCmd.PersistentFlags().String("smtp-tls", "starttls", "How the SMTP connection is encrypted, can be one of starttls, tls, none. The starttls mode fails if the server doesn't support STARTTLS.")
+	rootCmd.PersistentFlags().Duration("smtp-timeout", 30*time.Second, "The maximum duration for connecting to the SMTP server and sending a digest.")
+	rootCmd.PersistentFlags().String("jira-url", "", "The base URL of the Jira compatible tracker tickets are created in, like https://example.atlassian.net. Tickets are disabled when empty.")
+	rootCmd.PersistentFlags().String("jira-project", "", "The key of the Jira project tickets are created in. Required when a Jira URL is set.")
+	rootCmd.PersistentFlags().String("jira-issue-type", "Bug", "The name of the type of Jira issue created for findings.")
+	rootCmd.PersistentFlags().String("jira-username", "", "The username to authenticate to Jira with, alongside the API token. When empty the token is sent as a bearer token, like a personal access token.")
+	rootCmd.PersistentFlags().String("jira-token", "", "The API token or personal access token to authenticate to Jira with.")
+	rootCmd.PersistentFlags().String("jira-labels", "", "A comma separated list of labels added to every Jira issue created.")
+	rootCmd.PersistentFlags().String("jira-summary-template", "", "A Go template for the summary of Jira issues, rendered with the finding. Uses a default naming the CVE, severity and image repository when empty.")
+	rootCmd.PersistentFlags().String("jira-description-template", "", "A Go template for the description of Jira issues, rendered with the finding. Uses a default in Jira wiki markup listing the vulnerable packages and workloads when empty.")
+	rootCmd.PersistentFlags().Duration("jira-timeout", 30*time.Second, "The maximum duration of each request to Jira.")
+	rootCmd.PersistentFlags().Bool("jira-automatic", false, "Automatically create tickets for critical vulnerabilities first seen after this is enabled. Requires snapshots, since new vulnerabilities are found through the findings ledger.")
+	rootCmd.PersistentFlags().Duration("jira-interval", 15*time.Minute, "How often ticket statuses are refreshed from Jira, and new critical vulnerabilities ticketed in automatic mode.")
+	rootCmd.PersistentFlags().Bool("kube-events", false, "Emit Kubernetes Warning Events on the objects affected by new critical vulnerabilities, exposed secrets and critical config audit failures. Needs permission to create events and get the affected objects.")
+	rootCmd.PersistentFlags().Duration("kube-events-interval", 5*time.Minute, "How often the reports are checked for new findings to emit Kubernetes Events for.")
+	rootCmd.PersistentFlags().Bool("policy-reports", false, "Publish vulnerability, config audit, RBAC assessment and exposed secret findings, minus ignored vulnerabilities, as a wgpolicyk8s.io PolicyReport per namespace and a ClusterPolicyReport. Needs the Policy Reports CRDs installed and permission to manage policy reports.")
+	rootCmd.PersistentFlags().Duration("policy-reports-interval", 5*time.Minute, "How often the published policy reports are reconciled with the Trivy Operator reports.")
+	rootCmd.PersistentFlags().String("admission-mode", "off", "The validating admission webhook mode, can be one of off, audit, enforce. Pods and workloads adding images with unignored critical vulnerabilities are admitted with a warning in audit mode, and denied in enforce mode.")
+	rootCmd.PersistentFlags().Uint16("admission-port", 8443, "The port the admission webhook is served on over TLS, at the /validate path. Must be different from the server and ops ports.")
+	rootCmd.PersistentFlags().String("admission-tls-cert-file", "", "The path to the admission webhook's TLS certificate, required unless the webhook is off. Reloaded when the file changes.")
+	rootCmd.PersistentFlags().String("admission-tls-key-file", "", "The path to the admission webhook's TLS private key, required unless the webhook is off. Reloaded when the file changes.")
+	rootCmd.PersistentFlags().String("admission-exempt-namespaces", "kube-system", "A comma separated list of namespaces whose objects the admission webhook always admits.")
+	rootCmd.PersistentFlags().Duration("admission-interval", time.Minute, "How often the vulnerability reports the admission webhook reviews objects against are reloaded. Ignores apply right away.")
+	rootCmd.PersistentFlags().String("external-url", "", "The URL users reach the explorer at, like https://explorer.example.com, used to link to it from notifications, digests and tickets. Links are left out when empty.")
+	rootCmd.PersistentFlags().String("auth-mode", "none", "The authentication mode, can be one of none, proxy. The proxy mode trusts identity headers set by a reverse proxy such as oauth2-proxy.")
+	rootCmd.PersistentFlags().String("auth-proxy-user-header", "X-Forwarded-User", "The request header containing the username when using the proxy auth mode.")
+	rootCmd.PersistentFlags().String("auth-proxy-email-header", "X-Forwarded-Email", "The request header containing the user's email when using the proxy auth mode. Optional.")
+	rootCmd.PersistentFlags().String("auth-proxy-trusted-cidrs", "", "A comma separated list of CIDRs the reverse proxy connects from when using the proxy auth mode. Requests from other addresses are rejected.")
+	rootCmd.PersistentFlags().String("auth-proxy-admins", "", "A comma separated list of usernames granted the admin scope when using the proxy auth mode. Admins can manage every user's API tokens, other users only their own.")
 
 	err := viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
 	if err != nil {
@@ -88,4 +250,306 @@ func init() {
 	if err != nil {
 		log.Fatal("Error binding db-path to key", "error", err)
 	}
+
+	err = viper.BindPFlag("ops-port", rootCmd.PersistentFlags().Lookup("ops-port"))
+	if err != nil {
+		log.Fatal("Error binding ops-port to key", "error", err)
+	}
+
+	err = viper.BindPFlag("server-read-header-timeout", rootCmd.PersistentFlags().Lookup("server-read-header-timeout"))
+	if err != nil {
+		log.Fatal("Error binding server-read-header-timeout to key", "error", err)
+	}
+
+	err = viper.BindPFlag("server-read-timeout", rootCmd.PersistentFlags().Lookup("server-read-timeout"))
+	if err != nil {
+		log.Fatal("Error binding server-read-timeout to key", "error", err)
+	}
+
+	err = viper.BindPFlag("server-write-timeout", rootCmd.PersistentFlags().Lookup("server-write-timeout"))
+	if err != nil {
+		log.Fatal("Error binding server-write-timeout to key", "error", err)
+	}
+
+	err = viper.BindPFlag("server-idle-timeout", rootCmd.PersistentFlags().Lookup("server-idle-timeout"))
+	if err != nil {
+		log.Fatal("Error binding server-idle-timeout to key", "error", err)
+	}
+
+	err = viper.BindPFlag("server-shutdown-timeout", rootCmd.PersistentFlags().Lookup("server-shutdown-timeout"))
+	if err != nil {
+		log.Fatal("Error binding server-shutdown-timeout to key", "error", err)
+	}
+
+	err = viper.BindPFlag("kube-request-timeout", rootCmd.PersistentFlags().Lookup("kube-request-timeout"))
+	if err != nil {
+		log.Fatal("Error binding kube-request-timeout to key", "error", err)
+	}
+
+	err = viper.BindPFlag("tls-cert-file", rootCmd.PersistentFlags().Lookup("tls-cert-file"))
+	if err != nil {
+		log.Fatal("Error binding tls-cert-file to key", "error", err)
+	}
+
+	err = viper.BindPFlag("tls-key-file", rootCmd.PersistentFlags().Lookup("tls-key-file"))
+	if err != nil {
+		log.Fatal("Error binding tls-key-file to key", "error", err)
+	}
+
+	err = viper.BindPFlag("server-trusted-origins", rootCmd.PersistentFlags().Lookup("server-trusted-origins"))
+	if err != nil {
+		log.Fatal("Error binding server-trusted-origins to key", "error", err)
+	}
+
+	err = viper.BindPFlag("server-max-body-bytes", rootCmd.PersistentFlags().Lookup("server-max-body-bytes"))
+	if err != nil {
+		log.Fatal("Error binding server-max-body-bytes to key", "error", err)
+	}
+
+	err = viper.BindPFlag("snapshot-interval", rootCmd.PersistentFlags().Lookup("snapshot-interval"))
+	if err != nil {
+		log.Fatal("Error binding snapshot-interval to key", "error", err)
+	}
+
+	err = viper.BindPFlag("snapshot-retention", rootCmd.PersistentFlags().Lookup("snapshot-retention"))
+	if err != nil {
+		log.Fatal("Error binding snapshot-retention to key", "error", err)
+	}
+
+	err = viper.BindPFlag("ledger-interval", rootCmd.PersistentFlags().Lookup("ledger-interval"))
+	if err != nil {
+		log.Fatal("Error binding ledger-interval to key", "error", err)
+	}
+
+	err = viper.BindPFlag("sla-policies", rootCmd.PersistentFlags().Lookup("sla-policies"))
+	if err != nil {
+		log.Fatal("Error binding sla-policies to key", "error", err)
+	}
+
+	err = viper.BindPFlag("sla-warning", rootCmd.PersistentFlags().Lookup("sla-warning"))
+	if err != nil {
+		log.Fatal("Error binding sla-warning to key", "error", err)
+	}
+
+	err = viper.BindPFlag("sla-owner-label", rootCmd.PersistentFlags().Lookup("sla-owner-label"))
+	if err != nil {
+		log.Fatal("Error binding sla-owner-label to key", "error", err)
+	}
+
+	err = viper.BindPFlag("notify-webhooks", rootCmd.PersistentFlags().Lookup("notify-webhooks"))
+	if err != nil {
+		log.Fatal("Error binding notify-webhooks to key", "error", err)
+	}
+
+	err = viper.BindPFlag("notify-interval", rootCmd.PersistentFlags().Lookup("notify-interval"))
+	if err != nil {
+		log.Fatal("Error binding notify-interval to key", "error", err)
+	}
+
+	err = viper.BindPFlag("notify-min-severity", rootCmd.PersistentFlags().Lookup("notify-min-severity"))
+	if err != nil {
+		log.Fatal("Error binding notify-min-severity to key", "error", err)
+	}
+
+	err = viper.BindPFlag("notify-rate-limit", rootCmd.PersistentFlags().Lookup("notify-rate-limit"))
+	if err != nil {
+		log.Fatal("Error binding notify-rate-limit to key", "error", err)
+	}
+
+	err = viper.BindPFlag("notify-ignore-expiry-warning", rootCmd.PersistentFlags().Lookup("notify-ignore-expiry-warning"))
+	if err != nil {
+		log.Fatal("Error binding notify-ignore-expiry-warning to key", "error", err)
+	}
+
+	err = viper.BindPFlag("digest-recipients", rootCmd.PersistentFlags().Lookup("digest-recipients"))
+	if err != nil {
+		log.Fatal("Error binding digest-recipients to key", "error", err)
+	}
+
+	err = viper.BindPFlag("digest-schedule", rootCmd.PersistentFlags().Lookup("digest-schedule"))
+	if err != nil {
+		log.Fatal("Error binding digest-schedule to key", "error", err)
+	}
+
+	err = viper.BindPFlag("smtp-host", rootCmd.PersistentFlags().Lookup("smtp-host"))
+	if err != nil {
+		log.Fatal("Error binding smtp-host to key", "error", err)
+	}
+
+	err = viper.BindPFlag("smtp-port", rootCmd.PersistentFlags().Lookup("smtp-port"))
+	if err != nil {
+		log.Fatal("Error binding smtp-port to key", "error", err)
+	}
+
+	err = viper.BindPFlag("smtp-username", rootCmd.PersistentFlags().Lookup("smtp-username"))
+	if err != nil {
+		log.Fatal("Error binding smtp-username to key", "error", err)
+	}
+
+	err = viper.BindPFlag("smtp-password", rootCmd.PersistentFlags().Lookup("smtp-password"))
+	if err != nil {
+		log.Fatal("Error binding smtp-password to key", "error", err)
+	}
+
+	err = viper.BindPFlag("smtp-from", rootCmd.PersistentFlags().Lookup("smtp-from"))
+	if err != nil {
+		log.Fatal("Error binding smtp-from to key", "error", err)
+	}
+
+	err = viper.BindPFlag("smtp-tls", rootCmd.PersistentFlags().Lookup("smtp-tls"))
+	if err != nil {
+		log.Fatal("Error binding smtp-tls to key", "error", err)
+	}
+
+	err = viper.BindPFlag("smtp-timeout", rootCmd.PersistentFlags().Lookup("smtp-timeout"))
+	if err != nil {
+		log.Fatal("Error binding smtp-timeout to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-url", rootCmd.PersistentFlags().Lookup("jira-url"))
+	if err != nil {
+		log.Fatal("Error binding jira-url to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-project", rootCmd.PersistentFlags().Lookup("jira-project"))
+	if err != nil {
+		log.Fatal("Error binding jira-project to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-issue-type", rootCmd.PersistentFlags().Lookup("jira-issue-type"))
+	if err != nil {
+		log.Fatal("Error binding jira-issue-type to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-username", rootCmd.PersistentFlags().Lookup("jira-username"))
+	if err != nil {
+		log.Fatal("Error binding jira-username to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-token", rootCmd.PersistentFlags().Lookup("jira-token"))
+	if err != nil {
+		log.Fatal("Error binding jira-token to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-labels", rootCmd.PersistentFlags().Lookup("jira-labels"))
+	if err != nil {
+		log.Fatal("Error binding jira-labels to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-summary-template", rootCmd.PersistentFlags().Lookup("jira-summary-template"))
+	if err != nil {
+		log.Fatal("Error binding jira-summary-template to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-description-template", rootCmd.PersistentFlags().Lookup("jira-description-template"))
+	if err != nil {
+		log.Fatal("Error binding jira-description-template to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-timeout", rootCmd.PersistentFlags().Lookup("jira-timeout"))
+	if err != nil {
+		log.Fatal("Error binding jira-timeout to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-automatic", rootCmd.PersistentFlags().Lookup("jira-automatic"))
+	if err != nil {
+		log.Fatal("Error binding jira-automatic to key", "error", err)
+	}
+
+	err = viper.BindPFlag("jira-interval", rootCmd.PersistentFlags().Lookup("jira-interval"))
+	if err != nil {
+		log.Fatal("Error binding jira-interval to key", "error", err)
+	}
+
+	err = viper.BindPFlag("kube-events", rootCmd.PersistentFlags().Lookup("kube-events"))
+	if err != nil {
+		log.Fatal("Error binding kube-events to key", "error", err)
+	}
+
+	err = viper.BindPFlag("kube-events-interval", rootCmd.PersistentFlags().Lookup("kube-events-interval"))
+	if err != nil {
+		log.Fatal("Error binding kube-events-interval to key", "error", err)
+	}
+
+	err = viper.BindPFlag("policy-reports", rootCmd.PersistentFlags().Lookup("policy-reports"))
+	if err != nil {
+		log.Fatal("Error binding policy-reports to key", "error", err)
+	}
+
+	err = viper.BindPFlag("policy-reports-interval", rootCmd.PersistentFlags().Lookup("policy-reports-interval"))
+	if err != nil {
+		log.Fatal("Error binding policy-reports-interval to key", "error", err)
+	}
+
+	err = viper.BindPFlag("admission-mode", rootCmd.PersistentFlags().Lookup("admission-mode"))
+	if err != nil {
+		log.Fatal("Error binding admission-mode to key", "error", err)
+	}
+
+	err = viper.BindPFlag("admission-port", rootCmd.PersistentFlags().Lookup("admission-port"))
+	if err != nil {
+		log.Fatal("Error binding admission-port to key", "error", err)
+	}
+
+	err = viper.BindPFlag("admission-tls-cert-file", rootCmd.PersistentFlags().Lookup("admission-tls-cert-file"))
+	if err != nil {
+		log.Fatal("Error binding admission-tls-cert-file to key", "error", err)
+	}
+
+	err = viper.BindPFlag("admission-tls-key-file", rootCmd.PersistentFlags().Lookup("admission-tls-key-file"))
+	if err != nil {
+		log.Fatal("Error binding admission-tls-key-file to key", "error", err)
+	}
+
+	err = viper.BindPFlag("admission-exempt-namespaces", rootCmd.PersistentFlags().Lookup("admission-exempt-namespaces"))
+	if err != nil {
+		log.Fatal("Error binding admission-exempt-namespaces to key", "error", err)
+	}
+
+	err = viper.BindPFlag("admission-interval", rootCmd.PersistentFlags().Lookup("admission-interval"))
+	if err != nil {
+		log.Fatal("Error binding admission-interval to key", "error", err)
+	}
+
+	err = viper.BindPFlag("external-url", rootCmd.PersistentFlags().Lookup("external-url"))
+	if err != nil {
+		log.Fatal("Error binding external-url to key", "error", err)
+	}
+
+	err = viper.BindPFlag("auth-mode", rootCmd.PersistentFlags().Lookup("auth-mode"))
+	if err != nil {
+		log.Fatal("Error binding auth-mode to key", "error", err)
+	}
+
+	err = viper.BindPFlag("auth-proxy-user-header", rootCmd.PersistentFlags().Lookup("auth-proxy-user-header"))
+	if err != nil {
+		log.Fatal("Error binding auth-proxy-user-header to key", "error", err)
+	}
+
+	err = viper.BindPFlag("auth-proxy-email-header", rootCmd.PersistentFlags().Lookup("auth-proxy-email-header"))
+	if err != nil {
+		log.Fatal("Error binding auth-proxy-email-header to key", "error", err)
+	}
+
+	err = viper.BindPFlag("auth-proxy-trusted-cidrs", rootCmd.PersistentFlags().Lookup("auth-proxy-trusted-cidrs"))
+	if err != nil {
+		log.Fatal("Error binding auth-proxy-trusted-cidrs to key", "error", err)
+	}
+
+	err = viper.BindPFlag("auth-proxy-admins", rootCmd.PersistentFlags().Lookup("auth-proxy-admins"))
+	if err != nil {
+		log.Fatal("Error binding auth-proxy-admins to key", "error", err)
+	}
+}
+
+// splitList splits a comma separated flag value into its non-empty items
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
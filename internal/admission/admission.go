@@ -0,0 +1,449 @@
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Modes the webhook can run in
+const (
+	// ModeOff disables the webhook
+	ModeOff = "off"
+	// ModeAudit admits objects running images with critical vulnerabilities, warning the client and adding an audit annotation
+	ModeAudit = "audit"
+	// ModeEnforce denies objects running images with critical vulnerabilities
+	ModeEnforce = "enforce"
+)
+
+// AuditAnnotation is the audit annotation key listing the critical vulnerabilities an admitted object's images have
+const AuditAnnotation = "critical-vulnerabilities"
+
+// maxReviewBytes bounds the size of an AdmissionReview, objects are stored up to about 1.5MiB and an update's review includes the old object too
+const maxReviewBytes = 4 << 20
+
+// maxListedVulnerabilities keeps messages short when an image has many critical vulnerabilities
+const maxListedVulnerabilities = 5
+
+// Config contains the settings for reviewing objects
+type Config struct {
+	// Mode is one of ModeAudit or ModeEnforce
+	Mode string
+	// ExemptNamespaces are namespaces whose objects are always admitted
+	ExemptNamespaces []string
+	// ExternalURL is the URL users reach the explorer at, used to link to the vulnerable images, links are left out when empty
+	ExternalURL string
+}
+
+// Image is a scanned image and its critical vulnerabilities
+type Image struct {
+	// Registry is the registry server from the report, like index.docker.io, ignores are recorded with it
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+	Critical   []Vulnerability
+}
+
+// Vulnerability is a critical vulnerability in an image
+type Vulnerability struct {
+	ID               string
+	Package          string
+	InstalledVersion string
+	FixedVersion     string
+}
+
+// IgnoredFunc returns the IDs of the CVEs ignored for an image repository's tag
+type IgnoredFunc func(registry, repository, tag string) (map[string]struct{}, error)
+
+// NewImages lists the images in the vulnerability reports along with their critical vulnerabilities, ignored or not
+func NewImages(data *v1alpha1.VulnerabilityReportList) []Image {
+	var images []Image
+	seen := make(map[string]struct{})
+
+	for _, item := range data.Items {
+		image := Image{
+			Registry:   item.Report.Registry.Server,
+			Repository: utils.FormatPrettyImageRepo(item.Report.Artifact.Repository),
+			Tag:        item.Report.Artifact.Tag,
+			Digest:     item.Report.Artifact.Digest,
+		}
+		// Every workload running an image has a report, they all list the same vulnerabilities
+		key := utils.AssembleImageFullName(image.Registry, image.Repository, image.Tag, "") + "@" + image.Digest
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		vulnerabilities := make(map[string]struct{})
+		for _, v := range item.Report.Vulnerabilities {
+			if v.Severity != v1alpha1.SeverityCritical {
+				continue
+			}
+			vulnerabilityKey := v.VulnerabilityID + "/" + v.Resource
+			if _, ok := vulnerabilities[vulnerabilityKey]; ok {
+				continue
+			}
+			vulnerabilities[vulnerabilityKey] = struct{}{}
+			image.Critical = append(image.Critical, Vulnerability{
+				ID:               v.VulnerabilityID,
+				Package:          v.Resource,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+			})
+		}
+		images = append(images, image)
+	}
+	return images
+}
+
+// Reviewer reviews admission requests for Pods and workloads against the images known from the vulnerability reports
+type Reviewer struct {
+	config  Config
+	exempt  map[string]struct{}
+	ignored IgnoredFunc
+
+	mu     sync.RWMutex
+	images []Image
+	loaded bool
+}
+
+// NewReviewer returns a reviewer for the config, ignores are looked up with the ignored func on each review so they apply right away
+func NewReviewer(config Config, ignored IgnoredFunc) (*Reviewer, error) {
+	if config.Mode != ModeAudit && config.Mode != ModeEnforce {
+		return nil, fmt.Errorf("admission mode must be one of %s, %s, or %s, got %q", ModeOff, ModeAudit, ModeEnforce, config.Mode)
+	}
+	if config.ExternalURL != "" {
+		u, err := url.Parse(config.ExternalURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("external URL must be an absolute http or https URL, got %q", config.ExternalURL)
+		}
+	}
+
+	exempt := make(map[string]struct{}, len(config.ExemptNamespaces))
+	for _, ns := range config.ExemptNamespaces {
+		exempt[ns] = struct{}{}
+	}
+	return &Reviewer{
+		config:  config,
+		exempt:  exempt,
+		ignored: ignored,
+	}, nil
+}
+
+// SetImages replaces the images objects are reviewed against
+func (r *Reviewer) SetImages(images []Image) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.images = images
+	r.loaded = true
+}
+
+// ServeHTTP reviews an AdmissionReview request and responds with the review's response
+// Errors looking up ignores respond with a server error, so the webhook's failure policy decides whether the object is admitted
+func (r *Reviewer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxReviewBytes)).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid AdmissionReview: %s", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "Invalid AdmissionReview: missing request", http.StatusBadRequest)
+		return
+	}
+
+	response, err := r.Review(review.Request)
+	if err != nil {
+		log.Logger.Error("error reviewing admission request", "kind", review.Request.Kind.Kind, "namespace", review.Request.Namespace, "name", review.Request.Name, "error", err)
+		http.Error(w, "Error reviewing admission request", http.StatusInternalServerError)
+		return
+	}
+
+	review.Request = nil
+	review.Response = response
+	if review.APIVersion == "" {
+		review.APIVersion = admissionv1.SchemeGroupVersion.String()
+		review.Kind = "AdmissionReview"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Logger.Error("encountered error writing admission response", "error", err)
+	}
+}
+
+// Review decides whether an object is admitted
+// Objects are admitted unless they add an image with unignored critical vulnerabilities, updates are only checked for images the update adds,
+// so workloads that are already running can still be scaled or patched
+// Objects created by a workload controller, like a Deployment's ReplicaSets and Pods, are left to the review of the controller's object so rescheduling isn't blocked,
+// as long as the request comes from the controller itself, see controlled
+// Review only needs the images set with SetImages, so requests like the AdmissionReview fixtures in testdata can be reviewed without a cluster
+func (r *Reviewer) Review(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	response := &admissionv1.AdmissionResponse{
+		UID:     req.UID,
+		Allowed: true,
+	}
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return response, nil
+	}
+	if _, ok := r.exempt[req.Namespace]; ok {
+		return response, nil
+	}
+	if controlled(req.Object.Raw, req.UserInfo.Username) {
+		return response, nil
+	}
+
+	refs, err := podSpecImages(req.Kind, req.Object.Raw)
+	if err != nil {
+		// The API server only sends the kinds the webhook is registered for, so this is a misconfigured webhook rather than a bad object
+		response.Warnings = []string{fmt.Sprintf("trivy-operator-explorer: %s", err)}
+		return response, nil
+	}
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+		old, err := podSpecImages(req.Kind, req.OldObject.Raw)
+		if err == nil {
+			refs = withoutImages(refs, old)
+		}
+	}
+	if len(refs) == 0 {
+		return response, nil
+	}
+
+	r.mu.RLock()
+	images, loaded := r.images, r.loaded
+	r.mu.RUnlock()
+	if !loaded {
+		response.Warnings = []string{"trivy-operator-explorer: vulnerability reports haven't been loaded yet, images weren't checked"}
+		return response, nil
+	}
+
+	var violations []string
+	for _, ref := range refs {
+		violation, err := r.violation(ref, images)
+		if err != nil {
+			return nil, err
+		}
+		if violation != "" {
+			violations = append(violations, violation)
+		}
+	}
+	if len(violations) == 0 {
+		return response, nil
+	}
+
+	object := req.Kind.Kind + " " + req.Namespace + "/" + req.Name
+	if req.Name == "" {
+		object = req.Kind.Kind + " in namespace " + req.Namespace
+	}
+	log.Logger.Info("admission review found images with critical vulnerabilities", "mode", r.config.Mode, "operation", req.Operation, "object", object, "user", req.UserInfo.Username, "violations", strings.Join(violations, "; "))
+
+	if r.config.Mode == ModeEnforce {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: strings.Join(violations, "; ") + ". Use a patched image, or ignore the vulnerabilities in Trivy Operator Explorer if they don't apply",
+		}
+		return response, nil
+	}
+
+	for _, v := range violations {
+		response.Warnings = append(response.Warnings, "trivy-operator-explorer: "+v)
+	}
+	response.AuditAnnotations = map[string]string{AuditAnnotation: strings.Join(violations, "; ")}
+	return response, nil
+}
+
+// violation describes the unignored critical vulnerabilities in the scanned images an image reference names, or returns an empty string if there are none
+// Images that haven't been scanned yet aren't violations, there's nothing known about them
+func (r *Reviewer) violation(ref string, images []Image) (string, error) {
+	var ids []string
+	var link string
+	seen := make(map[string]struct{})
+	for _, image := range images {
+		if len(image.Critical) == 0 || !utils.ImageReferenceMatches(ref, utils.FormatPrettyImageRegistry(image.Registry), image.Repository, image.Tag, image.Digest) {
+			continue
+		}
+
+		ignored, err := r.ignored(image.Registry, image.Repository, image.Tag)
+		if err != nil {
+			return "", fmt.Errorf("error getting ignored CVEs for %s: %w", ref, err)
+		}
+		for _, v := range image.Critical {
+			if _, ok := ignored[v.ID]; ok {
+				continue
+			}
+			if _, ok := seen[v.ID]; ok {
+				continue
+			}
+			seen[v.ID] = struct{}{}
+			ids = append(ids, v.ID)
+			if link == "" {
+				link = r.imageLink(image)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	sort.Strings(ids)
+	listed := strings.Join(ids, ", ")
+	if len(ids) > maxListedVulnerabilities {
+		listed = fmt.Sprintf("%s and %d more", strings.Join(ids[:maxListedVulnerabilities], ", "), len(ids)-maxListedVulnerabilities)
+	}
+	noun := "vulnerabilities"
+	if len(ids) == 1 {
+		noun = "vulnerability"
+	}
+	violation := fmt.Sprintf("image %s has %d unignored critical %s known to Trivy Operator (%s)", ref, len(ids), noun, listed)
+	if link != "" {
+		violation += ", see " + link
+	}
+	return violation, nil
+}
+
+// imageLink returns an absolute link to an image's page in the explorer, or an empty string without an external URL
+func (r *Reviewer) imageLink(image Image) string {
+	if r.config.ExternalURL == "" {
+		return ""
+	}
+	q := url.Values{}
+	q.Set("registry", image.Registry)
+	q.Set("repository", image.Repository)
+	q.Set("tag", image.Tag)
+	q.Set("digest", image.Digest)
+	return strings.TrimSuffix(r.config.ExternalURL, "/") + "/image?" + q.Encode()
+}
+
+// podSpecImages returns the images of every container in a Pod or a workload's Pod template
+func podSpecImages(kind metav1.GroupVersionKind, raw []byte) ([]string, error) {
+	var spec corev1.PodSpec
+	var err error
+	switch kind.Group + "/" + kind.Kind {
+	case "/Pod":
+		var o corev1.Pod
+		err = json.Unmarshal(raw, &o)
+		spec = o.Spec
+	case "apps/Deployment":
+		var o appsv1.Deployment
+		err = json.Unmarshal(raw, &o)
+		spec = o.Spec.Template.Spec
+	case "apps/StatefulSet":
+		var o appsv1.StatefulSet
+		err = json.Unmarshal(raw, &o)
+		spec = o.Spec.Template.Spec
+	case "apps/DaemonSet":
+		var o appsv1.DaemonSet
+		err = json.Unmarshal(raw, &o)
+		spec = o.Spec.Template.Spec
+	case "apps/ReplicaSet":
+		var o appsv1.ReplicaSet
+		err = json.Unmarshal(raw, &o)
+		spec = o.Spec.Template.Spec
+	case "batch/Job":
+		var o batchv1.Job
+		err = json.Unmarshal(raw, &o)
+		spec = o.Spec.Template.Spec
+	case "batch/CronJob":
+		var o batchv1.CronJob
+		err = json.Unmarshal(raw, &o)
+		spec = o.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return nil, fmt.Errorf("unsupported kind %s, only Pods and workloads with a Pod template are reviewed", kind.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", kind.Kind, err)
+	}
+
+	var refs []string
+	seen := make(map[string]struct{})
+	add := func(image string) {
+		if _, ok := seen[image]; ok || image == "" {
+			return
+		}
+		seen[image] = struct{}{}
+		refs = append(refs, image)
+	}
+	for _, c := range spec.InitContainers {
+		add(c.Image)
+	}
+	for _, c := range spec.Containers {
+		add(c.Image)
+	}
+	for _, c := range spec.EphemeralContainers {
+		add(c.Image)
+	}
+	return refs, nil
+}
+
+// controllerUsers are the users the kube-controller-manager creates objects as for each workload kind the webhook reviews, by group/Kind
+// Each controller has its own service account when the controller manager runs with --use-service-account-credentials, and otherwise uses its own user
+var controllerUsers = map[string]string{
+	"apps/Deployment":  "system:serviceaccount:kube-system:deployment-controller",
+	"apps/ReplicaSet":  "system:serviceaccount:kube-system:replicaset-controller",
+	"apps/StatefulSet": "system:serviceaccount:kube-system:statefulset-controller",
+	"apps/DaemonSet":   "system:serviceaccount:kube-system:daemon-set-controller",
+	"batch/Job":        "system:serviceaccount:kube-system:job-controller",
+	"batch/CronJob":    "system:serviceaccount:kube-system:cronjob-controller",
+}
+
+// controllerManagerUser is the user the kube-controller-manager's controllers share without --use-service-account-credentials
+const controllerManagerUser = "system:kube-controller-manager"
+
+// controlled returns true if an object was created by the controller of a workload kind the webhook reviews, like a Pod created by a ReplicaSet
+// Anyone creating an object can set its ownerReferences, so the request must also come from that kind's controller, otherwise the object is reviewed itself
+func controlled(raw []byte, username string) bool {
+	var object metav1.PartialObjectMetadata
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return false
+	}
+	owner := metav1.GetControllerOfNoCopy(&object)
+	if owner == nil {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return false
+	}
+	user, ok := controllerUsers[gv.Group+"/"+owner.Kind]
+	if !ok {
+		return false
+	}
+	return username == user || username == controllerManagerUser
+}
+
+// withoutImages returns the images in refs that aren't in old
+func withoutImages(refs, old []string) []string {
+	existing := make(map[string]struct{}, len(old))
+	for _, ref := range old {
+		existing[ref] = struct{}{}
+	}
+	var added []string
+	for _, ref := range refs {
+		if _, ok := existing[ref]; !ok {
+			added = append(added, ref)
+		}
+	}
+	return added
+}
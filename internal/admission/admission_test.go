@@ -0,0 +1,200 @@
+package admission
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+func TestMain(m *testing.M) {
+	log.Init("error")
+	os.Exit(m.Run())
+}
+
+const (
+	nginxViolation    = "image nginx:1.25 has 1 unignored critical vulnerability known to Trivy Operator (CVE-2024-0001)"
+	digestViolation   = "image docker.io/library/nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31 has 1 unignored critical vulnerability known to Trivy Operator (CVE-2024-0001)"
+	postgresViolation = "image ghcr.io/acme/postgres:16 has 1 unignored critical vulnerability known to Trivy Operator (CVE-2024-0003)"
+	denialSuffix      = ". Use a patched image, or ignore the vulnerabilities in Trivy Operator Explorer if they don't apply"
+)
+
+// testImages are the scanned images the fixtures are reviewed against, busybox:1.36 hasn't been scanned
+var testImages = []Image{
+	{
+		Registry:   "index.docker.io",
+		Repository: "nginx",
+		Tag:        "1.25",
+		Digest:     "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+		Critical:   []Vulnerability{{ID: "CVE-2024-0001", Package: "openssl"}},
+	},
+	{
+		Registry:   "ghcr.io",
+		Repository: "acme/postgres",
+		Tag:        "16",
+		Critical:   []Vulnerability{{ID: "CVE-2024-0002", Package: "libpq"}, {ID: "CVE-2024-0003", Package: "zlib"}},
+	},
+}
+
+// testIgnored ignores CVE-2024-0002 for ghcr.io/acme/postgres:16
+func testIgnored(registry, repository, tag string) (map[string]struct{}, error) {
+	if registry == "ghcr.io" && repository == "acme/postgres" && tag == "16" {
+		return map[string]struct{}{"CVE-2024-0002": {}}, nil
+	}
+	return nil, nil
+}
+
+func TestReview(t *testing.T) {
+	tests := []struct {
+		fixture          string
+		mode             string
+		externalURL      string
+		allowed          bool
+		message          string
+		warnings         []string
+		auditAnnotations map[string]string
+	}{
+		{
+			fixture:          "pod-create.json",
+			mode:             ModeAudit,
+			allowed:          true,
+			warnings:         []string{"trivy-operator-explorer: " + nginxViolation},
+			auditAnnotations: map[string]string{AuditAnnotation: nginxViolation},
+		},
+		{
+			fixture: "pod-create.json",
+			mode:    ModeEnforce,
+			message: nginxViolation + denialSuffix,
+		},
+		{
+			fixture:     "pod-create.json",
+			mode:        ModeEnforce,
+			externalURL: "https://explorer.example.com/",
+			message:     nginxViolation + ", see https://explorer.example.com/image?digest=sha256%3A0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31&registry=index.docker.io&repository=nginx&tag=1.25" + denialSuffix,
+		},
+		{
+			fixture: "pod-create-by-digest.json",
+			mode:    ModeEnforce,
+			message: digestViolation + denialSuffix,
+		},
+		{
+			fixture: "pod-create-exempt-namespace.json",
+			mode:    ModeEnforce,
+			allowed: true,
+		},
+		{
+			fixture: "pod-create-by-replicaset.json",
+			mode:    ModeEnforce,
+			allowed: true,
+		},
+		{
+			fixture: "pod-create-forged-owner.json",
+			mode:    ModeEnforce,
+			message: nginxViolation + denialSuffix,
+		},
+		{
+			fixture: "deployment-create.json",
+			mode:    ModeAudit,
+			allowed: true,
+			warnings: []string{
+				"trivy-operator-explorer: " + nginxViolation,
+				"trivy-operator-explorer: " + postgresViolation,
+			},
+			auditAnnotations: map[string]string{AuditAnnotation: nginxViolation + "; " + postgresViolation},
+		},
+		{
+			fixture: "deployment-create.json",
+			mode:    ModeEnforce,
+			message: nginxViolation + "; " + postgresViolation + denialSuffix,
+		},
+		{
+			fixture: "deployment-update-new-image.json",
+			mode:    ModeEnforce,
+			message: nginxViolation + denialSuffix,
+		},
+		{
+			fixture: "deployment-update-scale.json",
+			mode:    ModeEnforce,
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.fixture, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var review admissionv1.AdmissionReview
+			if err := json.Unmarshal(raw, &review); err != nil {
+				t.Fatalf("failed to decode fixture: %s", err)
+			}
+
+			reviewer, err := NewReviewer(Config{
+				Mode:             tt.mode,
+				ExemptNamespaces: []string{"kube-system"},
+				ExternalURL:      tt.externalURL,
+			}, testIgnored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reviewer.SetImages(testImages)
+
+			response, err := reviewer.Review(review.Request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.UID != review.Request.UID {
+				t.Errorf("UID = %q, want %q", response.UID, review.Request.UID)
+			}
+			if response.Allowed != tt.allowed {
+				t.Errorf("Allowed = %t, want %t", response.Allowed, tt.allowed)
+			}
+			var message string
+			if response.Result != nil {
+				message = response.Result.Message
+			}
+			if message != tt.message {
+				t.Errorf("message = %q, want %q", message, tt.message)
+			}
+			if !reflect.DeepEqual(response.Warnings, tt.warnings) {
+				t.Errorf("Warnings = %q, want %q", response.Warnings, tt.warnings)
+			}
+			if !reflect.DeepEqual(response.AuditAnnotations, tt.auditAnnotations) {
+				t.Errorf("AuditAnnotations = %v, want %v", response.AuditAnnotations, tt.auditAnnotations)
+			}
+		})
+	}
+}
+
+func TestReviewBeforeImagesLoaded(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "pod-create.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(raw, &review); err != nil {
+		t.Fatalf("failed to decode fixture: %s", err)
+	}
+
+	reviewer, err := NewReviewer(Config{Mode: ModeEnforce}, testIgnored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := reviewer.Review(review.Request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Allowed {
+		t.Error("objects must be admitted before the vulnerability reports are loaded")
+	}
+	want := []string{"trivy-operator-explorer: vulnerability reports haven't been loaded yet, images weren't checked"}
+	if !reflect.DeepEqual(response.Warnings, want) {
+		t.Errorf("Warnings = %q, want %q", response.Warnings, want)
+	}
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+const (
+	// ScopeRead allows reading report data
+	ScopeRead = "read"
+	// ScopeIgnoreWrite allows ignoring and unignoring vulnerabilities
+	ScopeIgnoreWrite = "ignore:write"
+	// ScopeAdmin allows everything, including managing API tokens
+	ScopeAdmin = "admin"
+
+	// apiTokenPrefix is prepended to generated tokens so they're easy to recognize in secret scanners
+	apiTokenPrefix = "toe_"
+)
+
+// APITokenScopes is the list of all valid API token scopes
+var APITokenScopes = []string{ScopeRead, ScopeIgnoreWrite, ScopeAdmin}
+
+// ErrAPITokenNotFound is returned when revoking a token that doesn't exist or is already revoked
+var ErrAPITokenNotFound = errors.New("no active api token found to revoke")
+
+// APIToken represents a row in the apiTokens table
+type APIToken struct {
+	ID         int          `db:"id" json:"id"`
+	Name       string       `db:"name" json:"name"`
+	Owner      string       `db:"owner" json:"owner"`
+	TokenHash  string       `db:"token_hash" json:"-"`
+	Scopes     string       `db:"scopes" json:"scopes"`
+	CreatedAt  time.Time    `db:"created_at" json:"created_at"`
+	LastUsedAt sql.NullTime `db:"last_used_at" json:"last_used_at"`
+	RevokedAt  sql.NullTime `db:"revoked_at" json:"revoked_at"`
+}
+
+// ScopeList returns the token's scopes as a slice
+func (t APIToken) ScopeList() []string {
+	return strings.Split(t.Scopes, ",")
+}
+
+// IsRevoked returns true if the token has been revoked
+func (t APIToken) IsRevoked() bool {
+	return t.RevokedAt.Valid
+}
+
+func initAPITokensTable() error {
+	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS apiTokens (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		owner TEXT NOT NULL DEFAULT '',
+		token_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP
+	);`)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info("✓ apiTokens table created/verified")
+	return nil
+}
+
+// CreateAPIToken generates a new API token and stores its hash in the apiTokens table
+// The plaintext token is returned and can not be retrieved again later
+func CreateAPIToken(name, owner string, scopes []string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("token name is required")
+	}
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !isValidScope(scope) {
+			return "", fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := apiTokenPrefix + hex.EncodeToString(b)
+
+	query := `INSERT INTO apiTokens (name, owner, token_hash, scopes, created_at)
+			  VALUES (?, ?, ?, ?, ?)`
+
+	result, err := Client.Exec(query, strings.TrimSpace(name), owner, hashAPIToken(token), strings.Join(scopes, ","), time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("failed to insert api token: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	log.Logger.Info("Successfully created api token", "id", id, "name", name, "owner", owner, "scopes", scopes)
+	return token, nil
+}
+
+// GetAPITokens returns all API tokens, newest first
+func GetAPITokens() ([]APIToken, error) {
+	query := `SELECT * FROM apiTokens ORDER BY created_at DESC`
+
+	var tokens []APIToken
+	err := Client.Select(&tokens, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// GetAPITokensByOwner returns the API tokens owned by the given user, newest first
+func GetAPITokensByOwner(owner string) ([]APIToken, error) {
+	query := `SELECT * FROM apiTokens WHERE owner = ? ORDER BY created_at DESC`
+
+	var tokens []APIToken
+	err := Client.Select(&tokens, query, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// AuthenticateAPIToken looks up an unrevoked API token by its plaintext value and records that it was used
+// Returns false if the token does not exist or was revoked
+func AuthenticateAPIToken(token string) (APIToken, bool, error) {
+	query := `SELECT * FROM apiTokens WHERE token_hash = ? AND revoked_at IS NULL`
+
+	var t APIToken
+	err := Client.Get(&t, query, hashAPIToken(token))
+	if err == sql.ErrNoRows {
+		return APIToken{}, false, nil
+	}
+	if err != nil {
+		return APIToken{}, false, fmt.Errorf("failed to get api token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	_, err = Client.Exec(`UPDATE apiTokens SET last_used_at = ? WHERE id = ?`, now, t.ID)
+	if err != nil {
+		// Not fatal to the request, the token is still valid
+		log.Logger.Error("failed to update api token last used timestamp", "id", t.ID, "error", err)
+	} else {
+		t.LastUsedAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	return t, true, nil
+}
+
+// RevokeAPIToken marks an API token as revoked, whoever owns it
+// ErrAPITokenNotFound is returned if there's no active token with the ID
+func RevokeAPIToken(id int, revokedBy string) error {
+	return revokeAPIToken(`UPDATE apiTokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, []any{time.Now().UTC(), id}, id, revokedBy)
+}
+
+// RevokeOwnedAPIToken marks an API token as revoked if it's owned by the given user
+// ErrAPITokenNotFound is returned if the user has no active token with the ID, so other users' tokens look like they don't exist
+func RevokeOwnedAPIToken(id int, owner, revokedBy string) error {
+	return revokeAPIToken(`UPDATE apiTokens SET revoked_at = ? WHERE id = ? AND owner = ? AND revoked_at IS NULL`, []any{time.Now().UTC(), id, owner}, id, revokedBy)
+}
+
+func revokeAPIToken(query string, args []any, id int, revokedBy string) error {
+	result, err := Client.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrAPITokenNotFound
+	}
+
+	log.Logger.Info("Successfully revoked api token", "id", id, "revoked_by", revokedBy)
+	return nil
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func isValidScope(scope string) bool {
+	for _, s := range APITokenScopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// ConfigAuditFinding is a failed config audit check on a resource, tracked across scans like the findings ledger
+type ConfigAuditFinding struct {
+	Namespace  string       `db:"namespace" json:"namespace"`
+	Kind       string       `db:"kind" json:"kind"`
+	Name       string       `db:"name" json:"name"`
+	CheckID    string       `db:"check_id" json:"check_id"`
+	Title      string       `db:"title" json:"title"`
+	Severity   string       `db:"severity" json:"severity"`
+	FirstSeen  time.Time    `db:"first_seen" json:"first_seen"`
+	ResolvedAt sql.NullTime `db:"resolved_at" json:"-"`
+}
+
+// ExposedSecretFinding is a secret found in an image repository, tracked across scans like the findings ledger
+type ExposedSecretFinding struct {
+	Registry   string       `db:"registry" json:"registry"`
+	Repository string       `db:"repository" json:"repository"`
+	RuleID     string       `db:"rule_id" json:"rule_id"`
+	Target     string       `db:"target" json:"target"`
+	Title      string       `db:"title" json:"title"`
+	Severity   string       `db:"severity" json:"severity"`
+	FirstSeen  time.Time    `db:"first_seen" json:"first_seen"`
+	ResolvedAt sql.NullTime `db:"resolved_at" json:"-"`
+}
+
+func initAuditFindingsTables() error {
+	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS configAuditFindings (
+		id INTEGER PRIMARY KEY,
+		namespace TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		name TEXT NOT NULL,
+		check_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		first_seen TIMESTAMP NOT NULL,
+		last_seen TIMESTAMP NOT NULL,
+		resolved_at TIMESTAMP,
+		UNIQUE(namespace, kind, name, check_id)
+	);
+	CREATE TABLE IF NOT EXISTS exposedSecretFindings (
+		id INTEGER PRIMARY KEY,
+		registry TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		rule_id TEXT NOT NULL,
+		target TEXT NOT NULL,
+		title TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		first_seen TIMESTAMP NOT NULL,
+		last_seen TIMESTAMP NOT NULL,
+		resolved_at TIMESTAMP,
+		UNIQUE(registry, repository, rule_id, target)
+	);`)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info("✓ config audit and exposed secret findings tables created/verified")
+	return nil
+}
+
+// RecordConfigAuditObservations updates the config audit ledger with every failed check in the current config audit reports
+// It works like RecordFindingObservations, failed checks that weren't observed are resolved at observedAt
+func RecordConfigAuditObservations(observedAt time.Time, observations []ConfigAuditFinding) error {
+	observedAt = observedAt.UTC()
+
+	tx, err := Client.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Do nothing, this happens commonly when the transaction has already been committed
+		}
+	}()
+
+	for _, o := range observations {
+		_, err = tx.Exec(`INSERT INTO configAuditFindings (namespace, kind, name, check_id, title, severity, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(namespace, kind, name, check_id) DO UPDATE SET
+				title = excluded.title,
+				severity = excluded.severity,
+				last_seen = excluded.last_seen,
+				resolved_at = NULL`,
+			o.Namespace, o.Kind, o.Name, o.CheckID, o.Title, o.Severity, observedAt, observedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record config audit finding %s on %s/%s: %w", o.CheckID, o.Kind, o.Name, err)
+		}
+	}
+
+	resolved, err := resolveUnobserved(tx, "configAuditFindings", observedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Logger.Debug("Successfully recorded config audit observations", "observed", len(observations), "resolved", resolved)
+	return nil
+}
+
+// RecordExposedSecretObservations updates the exposed secret ledger with every secret in the current exposed secret reports
+// It works like RecordFindingObservations, secrets that weren't observed are resolved at observedAt
+func RecordExposedSecretObservations(observedAt time.Time, observations []ExposedSecretFinding) error {
+	observedAt = observedAt.UTC()
+
+	tx, err := Client.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Do nothing, this happens commonly when the transaction has already been committed
+		}
+	}()
+
+	for _, o := range observations {
+		_, err = tx.Exec(`INSERT INTO exposedSecretFindings (registry, repository, rule_id, target, title, severity, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(registry, repository, rule_id, target) DO UPDATE SET
+				title = excluded.title,
+				severity = excluded.severity,
+				last_seen = excluded.last_seen,
+				resolved_at = NULL`,
+			o.Registry, o.Repository, o.RuleID, o.Target, o.Title, o.Severity, observedAt, observedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record exposed secret %s in %s: %w", o.RuleID, o.Repository, err)
+		}
+	}
+
+	resolved, err := resolveUnobserved(tx, "exposedSecretFindings", observedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Logger.Debug("Successfully recorded exposed secret observations", "observed", len(observations), "resolved", resolved)
+	return nil
+}
+
+// resolveUnobserved resolves the open rows of a ledger table that weren't seen at observedAt, and returns the number resolved
+func resolveUnobserved(tx *sqlx.Tx, table string, observedAt time.Time) (int64, error) {
+	result, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET resolved_at = ? WHERE resolved_at IS NULL AND last_seen < ?`, table), observedAt, observedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve rows in %s: %w", table, err)
+	}
+	resolved, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get resolved row count: %w", err)
+	}
+	return resolved, nil
+}
+
+// GetConfigAuditFindingsOpenAt returns the failed config audit checks that were open at the given time
+func GetConfigAuditFindingsOpenAt(at time.Time) ([]ConfigAuditFinding, error) {
+	var findings []ConfigAuditFinding
+	err := Client.Select(&findings, `SELECT namespace, kind, name, check_id, title, severity, first_seen, resolved_at FROM configAuditFindings
+		WHERE first_seen <= ? AND (resolved_at IS NULL OR resolved_at > ?)`, at.UTC(), at.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config audit findings: %w", err)
+	}
+	return findings, nil
+}
+
+// GetExposedSecretFindingsOpenAt returns the exposed secrets that were open at the given time
+func GetExposedSecretFindingsOpenAt(at time.Time) ([]ExposedSecretFinding, error) {
+	var findings []ExposedSecretFinding
+	err := Client.Select(&findings, `SELECT registry, repository, rule_id, target, title, severity, first_seen, resolved_at FROM exposedSecretFindings
+		WHERE first_seen <= ? AND (resolved_at IS NULL OR resolved_at > ?)`, at.UTC(), at.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exposed secret findings: %w", err)
+	}
+	return findings, nil
+}
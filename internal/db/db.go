@@ -27,9 +27,57 @@ func Init(path string) error {
 		return err
 	}
 
+	err = initAPITokensTable()
+	if err != nil {
+		return err
+	}
+
+	err = initSnapshotsTables()
+	if err != nil {
+		return err
+	}
+
+	err = initFindingsTables()
+	if err != nil {
+		return err
+	}
+
+	err = initAuditFindingsTables()
+	if err != nil {
+		return err
+	}
+
+	err = initNotificationsTables()
+	if err != nil {
+		return err
+	}
+
+	err = initDigestsTable()
+	if err != nil {
+		return err
+	}
+
+	err = initTicketsTables()
+	if err != nil {
+		return err
+	}
+
+	err = initKubeEventsTables()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// Close closes the database client
+func Close() error {
+	if Client == nil {
+		return nil
+	}
+	return Client.Close()
+}
+
 func initIgnoredImageVulnerabilitiesTable() error {
 	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS ignoredImageVulnerabilities (
 		id INTEGER PRIMARY KEY,
@@ -38,12 +86,56 @@ func initIgnoredImageVulnerabilitiesTable() error {
 		tag TEXT NOT NULL,
 		cve_id TEXT NOT NULL,
 		reason TEXT,
+		ignored_by TEXT NOT NULL DEFAULT '',
+		expires_at TIMESTAMP,
 		UNIQUE(registry, repository, tag, cve_id)
 	);`)
 	if err != nil {
 		return err
 	}
 
+	// Databases created before ignores were attributed to a user need the column added
+	err = ensureColumn("ignoredImageVulnerabilities", "ignored_by", "TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		return err
+	}
+
+	// Databases created before ignores could expire need the column added, their existing ignores never expire
+	err = ensureColumn("ignoredImageVulnerabilities", "expires_at", "TIMESTAMP")
+	if err != nil {
+		return err
+	}
+
 	log.Logger.Info("✓ ignoredImageVulnerabilities table created/verified")
 	return nil
 }
+
+// ensureColumn adds a column to an existing table if it is not already present
+func ensureColumn(table, column, definition string) error {
+	var columns []struct {
+		CID          int     `db:"cid"`
+		Name         string  `db:"name"`
+		Type         string  `db:"type"`
+		NotNull      bool    `db:"notnull"`
+		DefaultValue *string `db:"dflt_value"`
+		PrimaryKey   int     `db:"pk"`
+	}
+	err := Client.Select(&columns, fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return fmt.Errorf("failed to get columns for table %s: %w", table, err)
+	}
+
+	for _, c := range columns {
+		if c.Name == column {
+			return nil
+		}
+	}
+
+	_, err = Client.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("failed to add column %s to table %s: %w", column, table, err)
+	}
+
+	log.Logger.Info("✓ added column to table", "table", table, "column", column)
+	return nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+func initDigestsTable() error {
+	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS digests (
+		recipient TEXT PRIMARY KEY,
+		sent_at TIMESTAMP NOT NULL
+	);`)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info("✓ digests table created/verified")
+	return nil
+}
+
+// GetDigestSentAt returns when a digest was last sent to a recipient
+// The returned bool is false if the recipient has never been sent a digest or baselined by SetDigestSentAt, meaning it's new
+func GetDigestSentAt(recipient string) (time.Time, bool, error) {
+	var sentAt []time.Time
+	err := Client.Select(&sentAt, `SELECT sent_at FROM digests WHERE recipient = ?`, recipient)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get digest sent time: %w", err)
+	}
+	if len(sentAt) == 0 {
+		return time.Time{}, false, nil
+	}
+	return sentAt[0], true, nil
+}
+
+// SetDigestSentAt records when a digest was last sent to a recipient, the next digest lists changes since then
+func SetDigestSentAt(recipient string, sentAt time.Time) error {
+	_, err := Client.Exec(`INSERT INTO digests (recipient, sent_at) VALUES (?, ?)
+		ON CONFLICT(recipient) DO UPDATE SET sent_at = excluded.sent_at`, recipient, sentAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to set digest sent time: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// Finding represents a row in the findings ledger, a vulnerability in an image repository tracked across scans
+type Finding struct {
+	ID         int          `db:"id" json:"id"`
+	Registry   string       `db:"registry" json:"registry"`
+	Repository string       `db:"repository" json:"repository"`
+	CVEID      string       `db:"cve_id" json:"cve_id"`
+	Package    string       `db:"package" json:"package"`
+	Severity   string       `db:"severity" json:"severity"`
+	FirstSeen  time.Time    `db:"first_seen" json:"first_seen"`
+	LastSeen   time.Time    `db:"last_seen" json:"last_seen"`
+	ResolvedAt sql.NullTime `db:"resolved_at" json:"resolved_at"`
+
+	// Namespaces are every namespace the finding was observed in
+	Namespaces []string `db:"-" json:"namespaces"`
+}
+
+// IsResolved returns true if the finding has disappeared from the vulnerability reports
+func (f Finding) IsResolved() bool {
+	return f.ResolvedAt.Valid
+}
+
+// FindingKey identifies a finding in the ledger
+// Findings are tracked per repository rather than per tag, so upgrading an image's tag resolves the findings it fixes
+type FindingKey struct {
+	Registry   string
+	Repository string
+	CVEID      string
+	Package    string
+}
+
+// FindingObservation is a finding seen in the vulnerability reports, along with the namespaces running the vulnerable image
+type FindingObservation struct {
+	FindingKey
+	Severity   string
+	Namespaces []string
+}
+
+func initFindingsTables() error {
+	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS findings (
+		id INTEGER PRIMARY KEY,
+		registry TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		cve_id TEXT NOT NULL,
+		package TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		first_seen TIMESTAMP NOT NULL,
+		last_seen TIMESTAMP NOT NULL,
+		resolved_at TIMESTAMP,
+		UNIQUE(registry, repository, cve_id, package)
+	);
+	CREATE TABLE IF NOT EXISTS findingNamespaces (
+		finding_id INTEGER NOT NULL REFERENCES findings(id) ON DELETE CASCADE,
+		namespace TEXT NOT NULL,
+		UNIQUE(finding_id, namespace)
+	);`)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info("✓ findings tables created/verified")
+	return nil
+}
+
+// RecordFindingObservations updates the findings ledger with every finding in the current vulnerability reports
+// New findings are added as first seen at observedAt, findings seen again have their last seen time updated and are reopened if they were resolved,
+// and open findings that weren't observed are resolved at observedAt
+func RecordFindingObservations(observedAt time.Time, observations []FindingObservation) error {
+	observedAt = observedAt.UTC()
+
+	tx, err := Client.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Do nothing, this happens commonly when the transaction has already been committed
+		}
+	}()
+
+	upsert, err := tx.Preparex(`INSERT INTO findings (registry, repository, cve_id, package, severity, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(registry, repository, cve_id, package) DO UPDATE SET
+			severity = excluded.severity,
+			last_seen = excluded.last_seen,
+			resolved_at = NULL
+		RETURNING id`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		if err := upsert.Close(); err != nil {
+			log.Logger.Error("Failed to close statement", "error", err)
+		}
+	}()
+
+	for _, o := range observations {
+		var id int
+		err = upsert.Get(&id, o.Registry, o.Repository, o.CVEID, o.Package, o.Severity, observedAt, observedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record finding %s in %s: %w", o.CVEID, o.Repository, err)
+		}
+		for _, ns := range o.Namespaces {
+			_, err = tx.Exec(`INSERT OR IGNORE INTO findingNamespaces (finding_id, namespace) VALUES (?, ?)`, id, ns)
+			if err != nil {
+				return fmt.Errorf("failed to record finding namespace: %w", err)
+			}
+		}
+	}
+
+	// Every observed finding was just given a last seen time of observedAt, so any open finding seen before it has disappeared
+	resolved, err := resolveUnobserved(tx, "findings", observedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Logger.Debug("Successfully recorded finding observations", "observed", len(observations), "resolved", resolved)
+	return nil
+}
+
+// GetFindings returns every finding in the ledger along with the namespaces they were observed in
+func GetFindings() ([]Finding, error) {
+	var findings []Finding
+	err := Client.Select(&findings, `SELECT id, registry, repository, cve_id, package, severity, first_seen, last_seen, resolved_at FROM findings ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get findings: %w", err)
+	}
+
+	var namespaces []struct {
+		FindingID int    `db:"finding_id"`
+		Namespace string `db:"namespace"`
+	}
+	err = Client.Select(&namespaces, `SELECT finding_id, namespace FROM findingNamespaces ORDER BY namespace`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get finding namespaces: %w", err)
+	}
+
+	byID := make(map[int]*Finding, len(findings))
+	for i := range findings {
+		byID[findings[i].ID] = &findings[i]
+	}
+	for _, ns := range namespaces {
+		if f, ok := byID[ns.FindingID]; ok {
+			f.Namespaces = append(f.Namespaces, ns.Namespace)
+		}
+	}
+
+	return findings, nil
+}
+
+// GetOpenFindingsFirstSeen returns when each open finding was first seen
+// The registry and repository limit the results to a single image repository, when both are empty every open finding is returned
+func GetOpenFindingsFirstSeen(registry, repository string) (map[FindingKey]time.Time, error) {
+	query := `SELECT registry, repository, cve_id, package, first_seen FROM findings WHERE resolved_at IS NULL`
+	var args []any
+	if registry != "" || repository != "" {
+		query += ` AND registry = ? AND repository = ?`
+		args = append(args, registry, repository)
+	}
+
+	var rows []struct {
+		Registry   string    `db:"registry"`
+		Repository string    `db:"repository"`
+		CVEID      string    `db:"cve_id"`
+		Package    string    `db:"package"`
+		FirstSeen  time.Time `db:"first_seen"`
+	}
+	err := Client.Select(&rows, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open findings: %w", err)
+	}
+
+	firstSeen := make(map[FindingKey]time.Time, len(rows))
+	for _, row := range rows {
+		firstSeen[FindingKey{
+			Registry:   row.Registry,
+			Repository: row.Repository,
+			CVEID:      row.CVEID,
+			Package:    row.Package,
+		}] = row.FirstSeen
+	}
+	return firstSeen, nil
+}
+
+// GetFindingsOpenAt returns the findings that were open at the given time
+// Only the latest resolution is kept, so a finding that was resolved and has since reopened is treated as open the whole time
+func GetFindingsOpenAt(at time.Time) ([]Finding, error) {
+	var findings []Finding
+	err := Client.Select(&findings, `SELECT id, registry, repository, cve_id, package, severity, first_seen, last_seen, resolved_at FROM findings
+		WHERE first_seen <= ? AND (resolved_at IS NULL OR resolved_at > ?) ORDER BY id`, at.UTC(), at.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get findings: %w", err)
+	}
+	return findings, nil
+}
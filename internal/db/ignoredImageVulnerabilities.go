@@ -3,11 +3,13 @@ package db
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
 )
 
 // IgnoredImageVulnerability represents a row in the ignoredImageVulnerabilities table
+// An ignore with an expiry stops applying once it passes, reads leave it out from then on, and the row stays until the CVE is ignored again
 type IgnoredImageVulnerability struct {
 	ID         int    `db:"id" json:"id"`
 	Registry   string `db:"registry" json:"registry"`
@@ -15,12 +17,28 @@ type IgnoredImageVulnerability struct {
 	Tag        string `db:"tag" json:"tag"`
 	CVEID      string `db:"cve_id" json:"cve_id"`
 	Reason     string `db:"reason" json:"reason"`
+	IgnoredBy  string `db:"ignored_by" json:"ignored_by"`
+	// ExpiresAt is when the ignore stops applying, nil for ignores that never expire
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at,omitempty"`
 }
 
+// deleteExpiredIgnoreQuery removes an expired ignore, so the CVE can be ignored again without violating the unique constraint
+const deleteExpiredIgnoreQuery = `DELETE FROM ignoredImageVulnerabilities
+	WHERE registry = ? AND repository = ? AND tag = ? AND cve_id = ? AND expires_at IS NOT NULL AND expires_at <= ?`
+
 // InsertIgnoredImageVulnerability inserts a new row into the ignoredImageVulnerabilities table
 func InsertIgnoredImageVulnerability(vuln IgnoredImageVulnerability) error {
-	query := `INSERT INTO ignoredImageVulnerabilities (registry, repository, tag, cve_id, reason) 
-			  VALUES (:registry, :repository, :tag, :cve_id, :reason)`
+	_, err := Client.Exec(deleteExpiredIgnoreQuery, vuln.Registry, vuln.Repository, vuln.Tag, vuln.CVEID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to delete expired ignore: %w", err)
+	}
+	if vuln.ExpiresAt != nil {
+		expiresAt := vuln.ExpiresAt.UTC()
+		vuln.ExpiresAt = &expiresAt
+	}
+
+	query := `INSERT INTO ignoredImageVulnerabilities (registry, repository, tag, cve_id, reason, ignored_by, expires_at) 
+			  VALUES (:registry, :repository, :tag, :cve_id, :reason, :ignored_by, :expires_at)`
 
 	result, err := Client.NamedExec(query, vuln)
 	if err != nil {
@@ -32,12 +50,14 @@ func InsertIgnoredImageVulnerability(vuln IgnoredImageVulnerability) error {
 		return fmt.Errorf("failed to get last insert ID: %w", err)
 	}
 
-	log.Logger.Info("Successfully inserted ignored image vulnerability", "id", id)
+	log.Logger.Info("Successfully inserted ignored image vulnerability", "id", id, "ignored_by", vuln.IgnoredBy)
 	return nil
 }
 
 // BulkInsertIgnoredImageVulnerabilities inserts multiple ignored vulnerabilities in a transaction
-func BulkInsertIgnoredImageVulnerabilities(registry, repository, tag, reason string, cveIDs []string) error {
+// ignoredBy is the name of the user that requested the ignores, and may be empty for anonymous requests
+// expiresAt is when the ignores stop applying, and may be nil for ignores that never expire
+func BulkInsertIgnoredImageVulnerabilities(registry, repository, tag, reason, ignoredBy string, expiresAt *time.Time, cveIDs []string) error {
 	if len(cveIDs) == 0 {
 		return fmt.Errorf("no CVE IDs provided")
 	}
@@ -53,8 +73,8 @@ func BulkInsertIgnoredImageVulnerabilities(registry, repository, tag, reason str
 		}
 	}()
 
-	query := `INSERT INTO ignoredImageVulnerabilities (registry, repository, tag, cve_id, reason) 
-			  VALUES (?, ?, ?, ?, ?)`
+	query := `INSERT INTO ignoredImageVulnerabilities (registry, repository, tag, cve_id, reason, ignored_by, expires_at) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?)`
 
 	stmt, err := tx.Preparex(query)
 	if err != nil {
@@ -67,8 +87,12 @@ func BulkInsertIgnoredImageVulnerabilities(registry, repository, tag, reason str
 	}()
 
 	// Insert each CVE
+	now := time.Now().UTC()
 	for _, cveID := range cveIDs {
-		_, err := stmt.Exec(registry, repository, tag, cveID, reason)
+		if _, err := tx.Exec(deleteExpiredIgnoreQuery, registry, repository, tag, cveID, now); err != nil {
+			return fmt.Errorf("failed to delete expired ignore %s: %w", cveID, err)
+		}
+		_, err := stmt.Exec(registry, repository, tag, cveID, reason, ignoredBy, utcOrNil(expiresAt))
 		if err != nil {
 			// If it's a unique constraint violation, log and continue (idempotent)
 			if strings.Contains(err.Error(), "UNIQUE constraint") {
@@ -84,17 +108,77 @@ func BulkInsertIgnoredImageVulnerabilities(registry, repository, tag, reason str
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Logger.Info("Successfully bulk inserted ignored image vulnerabilities", "count", len(cveIDs), "registry", registry, "repository", repository, "tag", tag)
+	log.Logger.Info("Successfully bulk inserted ignored image vulnerabilities", "count", len(cveIDs), "registry", registry, "repository", repository, "tag", tag, "ignored_by", ignoredBy)
+	return nil
+}
+
+// BulkInsertIgnoredCVEForImages ignores a single CVE for multiple images in a transaction
+// Only the Registry, Repository and Tag fields of each image are used
+// ignoredBy is the name of the user that requested the ignores, and may be empty for anonymous requests
+// expiresAt is when the ignores stop applying, and may be nil for ignores that never expire
+func BulkInsertIgnoredCVEForImages(cveID, reason, ignoredBy string, expiresAt *time.Time, images []IgnoredImageVulnerability) error {
+	if len(images) == 0 {
+		return fmt.Errorf("no images provided")
+	}
+
+	// Start a transaction
+	tx, err := Client.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Do nothing, this happens commonly when the transaction has already been committed
+		}
+	}()
+
+	query := `INSERT INTO ignoredImageVulnerabilities (registry, repository, tag, cve_id, reason, ignored_by, expires_at) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	stmt, err := tx.Preparex(query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		if err := stmt.Close(); err != nil {
+			log.Logger.Error("Failed to close statement", "error", err)
+		}
+	}()
+
+	// Insert the CVE for each image
+	now := time.Now().UTC()
+	for _, image := range images {
+		if _, err := tx.Exec(deleteExpiredIgnoreQuery, image.Registry, image.Repository, image.Tag, cveID, now); err != nil {
+			return fmt.Errorf("failed to delete expired ignore %s: %w", cveID, err)
+		}
+		_, err := stmt.Exec(image.Registry, image.Repository, image.Tag, cveID, reason, ignoredBy, utcOrNil(expiresAt))
+		if err != nil {
+			// If it's a unique constraint violation, log and continue (idempotent)
+			if strings.Contains(err.Error(), "UNIQUE constraint") {
+				log.Logger.Debug("CVE already ignored, skipping", "cve_id", cveID, "registry", image.Registry, "repository", image.Repository, "tag", image.Tag)
+				continue
+			}
+			return fmt.Errorf("failed to insert ignored vulnerability %s for image %s/%s:%s: %w", cveID, image.Registry, image.Repository, image.Tag, err)
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Logger.Info("Successfully bulk inserted ignored CVE for images", "cve_id", cveID, "count", len(images), "ignored_by", ignoredBy)
 	return nil
 }
 
 // GetIgnoredCVEsForImage returns a map of CVE IDs that are ignored for the given image
+// Expired ignores are left out, so their CVEs show up again
 func GetIgnoredCVEsForImage(registry, repository, tag string) (map[string]IgnoredImageVulnerability, error) {
-	query := `SELECT cve_id, reason FROM ignoredImageVulnerabilities 
-			  WHERE registry = ? AND repository = ? AND tag = ?`
+	query := `SELECT cve_id, reason, ignored_by, expires_at FROM ignoredImageVulnerabilities 
+			  WHERE registry = ? AND repository = ? AND tag = ? AND (expires_at IS NULL OR expires_at > ?)`
 
 	var cves []IgnoredImageVulnerability
-	err := Client.Select(&cves, query, registry, repository, tag)
+	err := Client.Select(&cves, query, registry, repository, tag, time.Now().UTC())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get ignores: %w", err)
 	}
@@ -110,7 +194,8 @@ func GetIgnoredCVEsForImage(registry, repository, tag string) (map[string]Ignore
 }
 
 // DeleteIgnoredImageVulnerability removes an ignored CVE from the database
-func DeleteIgnoredImageVulnerability(registry, repository, tag, cveID string) error {
+// deletedBy is the name of the user that requested the unignore, and may be empty for anonymous requests
+func DeleteIgnoredImageVulnerability(registry, repository, tag, cveID, deletedBy string) error {
 	query := `DELETE FROM ignoredImageVulnerabilities 
 			  WHERE registry = ? AND repository = ? AND tag = ? AND cve_id = ?`
 
@@ -128,6 +213,25 @@ func DeleteIgnoredImageVulnerability(registry, repository, tag, cveID string) er
 		return fmt.Errorf("no ignored vulnerability found to delete")
 	}
 
-	log.Logger.Info("Successfully deleted ignored image vulnerability", "registry", registry, "repository", repository, "tag", tag, "cve_id", cveID)
+	log.Logger.Info("Successfully deleted ignored image vulnerability", "registry", registry, "repository", repository, "tag", tag, "cve_id", cveID, "deleted_by", deletedBy)
 	return nil
 }
+
+// GetIgnoresExpiringBefore returns the ignores that haven't expired yet but will before the given time, soonest first
+func GetIgnoresExpiringBefore(before time.Time) ([]IgnoredImageVulnerability, error) {
+	var ignores []IgnoredImageVulnerability
+	err := Client.Select(&ignores, `SELECT id, registry, repository, tag, cve_id, reason, ignored_by, expires_at FROM ignoredImageVulnerabilities
+		WHERE expires_at IS NOT NULL AND expires_at > ? AND expires_at <= ? ORDER BY expires_at`, time.Now().UTC(), before.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring ignores: %w", err)
+	}
+	return ignores, nil
+}
+
+// utcOrNil returns the time in UTC, so stored times compare correctly, or nil if there is no time
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	log.Init("error")
+	os.Exit(m.Run())
+}
+
+// initTestDB points the package at a new database for the test
+func initTestDB(t *testing.T) {
+	if err := Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := Close(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// ignore inserts an ignore for nginx:1.25, expiring at expiresAt unless it's nil
+func ignore(t *testing.T, cveID, reason string, expiresAt *time.Time) {
+	t.Helper()
+	err := InsertIgnoredImageVulnerability(IgnoredImageVulnerability{
+		Registry:   "index.docker.io",
+		Repository: "nginx",
+		Tag:        "1.25",
+		CVEID:      cveID,
+		Reason:     reason,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ignoredReasons returns the reasons of the ignores applying to nginx:1.25 by CVE
+func ignoredReasons(t *testing.T) map[string]string {
+	t.Helper()
+	ignored, err := GetIgnoredCVEsForImage("index.docker.io", "nginx", "1.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := make(map[string]string, len(ignored))
+	for cveID, i := range ignored {
+		reasons[cveID] = i.Reason
+	}
+	return reasons
+}
+
+func at(t time.Time) *time.Time {
+	return &t
+}
+
+func TestExpiredIgnoresStopApplying(t *testing.T) {
+	initTestDB(t)
+	now := time.Now()
+	ignore(t, "CVE-2024-0001", "expired", at(now.Add(-time.Minute)))
+	ignore(t, "CVE-2024-0002", "expires later", at(now.Add(time.Hour)))
+	ignore(t, "CVE-2024-0003", "never expires", nil)
+
+	reasons := ignoredReasons(t)
+	if _, ok := reasons["CVE-2024-0001"]; ok {
+		t.Error("an expired ignore still hides its CVE")
+	}
+	if len(reasons) != 2 || reasons["CVE-2024-0002"] != "expires later" || reasons["CVE-2024-0003"] != "never expires" {
+		t.Errorf("got ignores %v, want the unexpired CVE-2024-0002 and CVE-2024-0003", reasons)
+	}
+}
+
+func TestReignoreExpiredIgnore(t *testing.T) {
+	initTestDB(t)
+	expired := at(time.Now().Add(-time.Minute))
+
+	// Ignoring a single CVE again replaces its expired ignore
+	ignore(t, "CVE-2024-0001", "expired", expired)
+	ignore(t, "CVE-2024-0001", "ignored again", nil)
+	if got := ignoredReasons(t)["CVE-2024-0001"]; got != "ignored again" {
+		t.Errorf("got reason %q after ignoring an expired ignore again, want %q", got, "ignored again")
+	}
+
+	// So does ignoring CVEs in bulk, while unexpired ignores are kept as they are
+	ignore(t, "CVE-2024-0002", "expired", expired)
+	ignore(t, "CVE-2024-0003", "still ignored", nil)
+	err := BulkInsertIgnoredImageVulnerabilities("index.docker.io", "nginx", "1.25", "bulk ignored", "jane", at(time.Now().Add(time.Hour)), []string{"CVE-2024-0002", "CVE-2024-0003"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := ignoredReasons(t)
+	if reasons["CVE-2024-0002"] != "bulk ignored" || reasons["CVE-2024-0003"] != "still ignored" {
+		t.Errorf("got ignores %v after bulk ignoring, want CVE-2024-0002 ignored again and CVE-2024-0003 unchanged", reasons)
+	}
+
+	// And ignoring a CVE across images
+	ignore(t, "CVE-2024-0004", "expired", expired)
+	err = BulkInsertIgnoredCVEForImages("CVE-2024-0004", "ignored everywhere", "jane", nil, []IgnoredImageVulnerability{{Registry: "index.docker.io", Repository: "nginx", Tag: "1.25"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ignoredReasons(t)["CVE-2024-0004"]; got != "ignored everywhere" {
+		t.Errorf("got reason %q after ignoring an expired ignore across images, want %q", got, "ignored everywhere")
+	}
+}
+
+func TestGetIgnoresExpiringBefore(t *testing.T) {
+	initTestDB(t)
+	now := time.Now()
+	ignore(t, "CVE-2024-0001", "expired", at(now.Add(-time.Minute)))
+	ignore(t, "CVE-2024-0002", "expires in two hours", at(now.Add(2*time.Hour)))
+	ignore(t, "CVE-2024-0003", "expires in an hour", at(now.Add(time.Hour)))
+	ignore(t, "CVE-2024-0004", "expires next week", at(now.Add(7*24*time.Hour)))
+	ignore(t, "CVE-2024-0005", "never expires", nil)
+
+	ignores, err := GetIgnoresExpiringBefore(now.Add(24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, i := range ignores {
+		got = append(got, i.CVEID)
+	}
+	if len(got) != 2 || got[0] != "CVE-2024-0003" || got[1] != "CVE-2024-0002" {
+		t.Errorf("got expiring ignores %v, want CVE-2024-0003 then CVE-2024-0002", got)
+	}
+}
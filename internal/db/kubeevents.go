@@ -0,0 +1,107 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+func initKubeEventsTables() error {
+	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS kubeEventsBaseline (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		baselined_at TIMESTAMP NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS emittedKubeEvents (
+		event_key TEXT PRIMARY KEY,
+		emitted_at TIMESTAMP NOT NULL,
+		last_seen TIMESTAMP NOT NULL
+	);`)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info("✓ kubernetes events tables created/verified")
+	return nil
+}
+
+// GetEmittedKubeEventKeys returns the keys of the findings that Kubernetes Events have been emitted for
+// The returned bool is false if the findings have never been baselined by RecordEmittedKubeEvents, meaning events were just enabled
+func GetEmittedKubeEventKeys() (map[string]struct{}, bool, error) {
+	var baselined []time.Time
+	err := Client.Select(&baselined, `SELECT baselined_at FROM kubeEventsBaseline`)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get kubernetes events baseline: %w", err)
+	}
+	if len(baselined) == 0 {
+		return nil, false, nil
+	}
+
+	var keys []string
+	err = Client.Select(&keys, `SELECT event_key FROM emittedKubeEvents`)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get emitted kubernetes events: %w", err)
+	}
+
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return set, true, nil
+}
+
+// RecordEmittedKubeEvents records that events have been emitted for findings, or that the findings are still present after being emitted, as of seenAt
+// The findings are marked as baselined, so findings recorded when events are first enabled never have events emitted
+func RecordEmittedKubeEvents(keys []string, seenAt time.Time) error {
+	seenAt = seenAt.UTC()
+
+	tx, err := Client.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Do nothing, this happens commonly when the transaction has already been committed
+		}
+	}()
+
+	_, err = tx.Exec(`INSERT OR IGNORE INTO kubeEventsBaseline (id, baselined_at) VALUES (1, ?)`, seenAt)
+	if err != nil {
+		return fmt.Errorf("failed to record kubernetes events baseline: %w", err)
+	}
+
+	stmt, err := tx.Preparex(`INSERT INTO emittedKubeEvents (event_key, emitted_at, last_seen) VALUES (?, ?, ?)
+		ON CONFLICT(event_key) DO UPDATE SET last_seen = excluded.last_seen`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		if err := stmt.Close(); err != nil {
+			log.Logger.Error("Failed to close statement", "error", err)
+		}
+	}()
+
+	for _, key := range keys {
+		if _, err := stmt.Exec(key, seenAt, seenAt); err != nil {
+			return fmt.Errorf("failed to record emitted kubernetes event %s: %w", key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteEmittedKubeEventsNotSeenSince forgets the findings that haven't been present since the given time, so events are emitted again if they reappear
+func DeleteEmittedKubeEventsNotSeenSince(before time.Time) (int64, error) {
+	result, err := Client.Exec(`DELETE FROM emittedKubeEvents WHERE last_seen < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete emitted kubernetes events: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted row count: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+func initNotificationsTables() error {
+	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS notificationWebhooks (
+		name TEXT PRIMARY KEY,
+		baselined_at TIMESTAMP NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS notifiedEvents (
+		webhook TEXT NOT NULL,
+		event_key TEXT NOT NULL,
+		notified_at TIMESTAMP NOT NULL,
+		last_seen TIMESTAMP NOT NULL,
+		PRIMARY KEY(webhook, event_key)
+	);`)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info("✓ notification tables created/verified")
+	return nil
+}
+
+// GetNotifiedEventKeys returns the keys of the events that have been sent to a webhook
+// The returned bool is false if the webhook has never been baselined by RecordNotifiedEvents, meaning it's new
+func GetNotifiedEventKeys(webhook string) (map[string]struct{}, bool, error) {
+	var baselined []string
+	err := Client.Select(&baselined, `SELECT name FROM notificationWebhooks WHERE name = ?`, webhook)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get notification webhook: %w", err)
+	}
+	if len(baselined) == 0 {
+		return nil, false, nil
+	}
+
+	var keys []string
+	err = Client.Select(&keys, `SELECT event_key FROM notifiedEvents WHERE webhook = ?`, webhook)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get notified events: %w", err)
+	}
+
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return set, true, nil
+}
+
+// RecordNotifiedEvents records that events have been sent to a webhook, or are still present after being sent, as of seenAt
+// The webhook is marked as baselined, so events recorded when it's first configured are never sent
+func RecordNotifiedEvents(webhook string, keys []string, seenAt time.Time) error {
+	seenAt = seenAt.UTC()
+
+	tx, err := Client.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Do nothing, this happens commonly when the transaction has already been committed
+		}
+	}()
+
+	_, err = tx.Exec(`INSERT OR IGNORE INTO notificationWebhooks (name, baselined_at) VALUES (?, ?)`, webhook, seenAt)
+	if err != nil {
+		return fmt.Errorf("failed to record notification webhook: %w", err)
+	}
+
+	stmt, err := tx.Preparex(`INSERT INTO notifiedEvents (webhook, event_key, notified_at, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT(webhook, event_key) DO UPDATE SET last_seen = excluded.last_seen`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer func() {
+		if err := stmt.Close(); err != nil {
+			log.Logger.Error("Failed to close statement", "error", err)
+		}
+	}()
+
+	for _, key := range keys {
+		if _, err := stmt.Exec(webhook, key, seenAt, seenAt); err != nil {
+			return fmt.Errorf("failed to record notified event %s: %w", key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteNotifiedEventsNotSeenSince forgets the events that haven't been present since the given time, so they're sent again if they reappear
+func DeleteNotifiedEventsNotSeenSince(before time.Time) (int64, error) {
+	result, err := Client.Exec(`DELETE FROM notifiedEvents WHERE last_seen < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notified events: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted row count: %w", err)
+	}
+	return deleted, nil
+}
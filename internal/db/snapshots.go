@@ -0,0 +1,334 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// Snapshot is a point in time record of the cluster's finding counts, written periodically so trends survive workloads being deleted or rescanned
+type Snapshot struct {
+	TakenAt    time.Time
+	Totals     SeverityCounts
+	Images     []ImageSnapshot
+	Namespaces []NamespaceSnapshot
+	Compliance []ComplianceSnapshot
+
+	// Detailed is true when every findings ledger was updated alongside the snapshot,
+	// so the ledgers' state at TakenAt is accurate and the snapshot can be diffed against
+	Detailed bool
+}
+
+// SnapshotInfo identifies a snapshot
+type SnapshotInfo struct {
+	ID      int       `db:"id"`
+	TakenAt time.Time `db:"taken_at"`
+}
+
+// SeverityCounts contains vulnerability counts by severity, net of ignored vulnerabilities
+type SeverityCounts struct {
+	Critical int `db:"critical" json:"critical"`
+	High     int `db:"high" json:"high"`
+	Medium   int `db:"medium" json:"medium"`
+	Low      int `db:"low" json:"low"`
+}
+
+// ImageSnapshot contains an image's vulnerability counts in a snapshot
+type ImageSnapshot struct {
+	Registry   string `db:"registry"`
+	Repository string `db:"repository"`
+	Tag        string `db:"tag"`
+	Digest     string `db:"digest"`
+	SeverityCounts
+}
+
+// NamespaceSnapshot contains the vulnerability counts of the images run in a namespace in a snapshot
+type NamespaceSnapshot struct {
+	Namespace string `db:"namespace"`
+	SeverityCounts
+}
+
+// ComplianceSnapshot contains a compliance report's pass and fail counts in a snapshot
+type ComplianceSnapshot struct {
+	ReportID  string `db:"report_id"`
+	Title     string `db:"title"`
+	PassCount int    `db:"pass_count"`
+	FailCount int    `db:"fail_count"`
+}
+
+// TrendPoint is a single snapshot's severity counts for a trend chart
+type TrendPoint struct {
+	TakenAt time.Time `db:"taken_at" json:"taken_at"`
+	SeverityCounts
+}
+
+// ComplianceTrendPoint is a single snapshot's pass and fail counts of a compliance report for a trend chart
+type ComplianceTrendPoint struct {
+	TakenAt   time.Time `db:"taken_at" json:"taken_at"`
+	PassCount int       `db:"pass_count" json:"pass_count"`
+	FailCount int       `db:"fail_count" json:"fail_count"`
+}
+
+// ComplianceTrend contains a compliance report's pass and fail counts over time
+type ComplianceTrend struct {
+	ReportID string                 `json:"report_id"`
+	Title    string                 `json:"title"`
+	Points   []ComplianceTrendPoint `json:"points"`
+}
+
+func initSnapshotsTables() error {
+	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS snapshots (
+		id INTEGER PRIMARY KEY,
+		taken_at TIMESTAMP NOT NULL,
+		critical INTEGER NOT NULL,
+		high INTEGER NOT NULL,
+		medium INTEGER NOT NULL,
+		low INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_snapshots_taken_at ON snapshots(taken_at);
+	CREATE TABLE IF NOT EXISTS snapshotImages (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,
+		registry TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		digest TEXT NOT NULL,
+		critical INTEGER NOT NULL,
+		high INTEGER NOT NULL,
+		medium INTEGER NOT NULL,
+		low INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_snapshotImages_image ON snapshotImages(registry, repository, tag, digest);
+	CREATE TABLE IF NOT EXISTS snapshotNamespaces (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,
+		namespace TEXT NOT NULL,
+		critical INTEGER NOT NULL,
+		high INTEGER NOT NULL,
+		medium INTEGER NOT NULL,
+		low INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_snapshotNamespaces_namespace ON snapshotNamespaces(namespace);
+	CREATE TABLE IF NOT EXISTS snapshotCompliance (
+		snapshot_id INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,
+		report_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		pass_count INTEGER NOT NULL,
+		fail_count INTEGER NOT NULL
+	);`)
+	if err != nil {
+		return err
+	}
+
+	// Databases created before snapshots were diffed need the column added, their existing snapshots aren't detailed
+	err = ensureColumn("snapshots", "detailed", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info("✓ snapshot tables created/verified")
+	return nil
+}
+
+// InsertSnapshot writes a snapshot and its per-image, per-namespace and compliance rows in a transaction
+func InsertSnapshot(s Snapshot) error {
+	tx, err := Client.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Do nothing, this happens commonly when the transaction has already been committed
+		}
+	}()
+
+	result, err := tx.Exec(`INSERT INTO snapshots (taken_at, critical, high, medium, low, detailed) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.TakenAt.UTC(), s.Totals.Critical, s.Totals.High, s.Totals.Medium, s.Totals.Low, s.Detailed)
+	if err != nil {
+		return fmt.Errorf("failed to insert snapshot: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	for _, image := range s.Images {
+		_, err = tx.Exec(`INSERT INTO snapshotImages (snapshot_id, registry, repository, tag, digest, critical, high, medium, low) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, image.Registry, image.Repository, image.Tag, image.Digest, image.Critical, image.High, image.Medium, image.Low)
+		if err != nil {
+			return fmt.Errorf("failed to insert image snapshot: %w", err)
+		}
+	}
+
+	for _, ns := range s.Namespaces {
+		_, err = tx.Exec(`INSERT INTO snapshotNamespaces (snapshot_id, namespace, critical, high, medium, low) VALUES (?, ?, ?, ?, ?, ?)`,
+			id, ns.Namespace, ns.Critical, ns.High, ns.Medium, ns.Low)
+		if err != nil {
+			return fmt.Errorf("failed to insert namespace snapshot: %w", err)
+		}
+	}
+
+	for _, report := range s.Compliance {
+		_, err = tx.Exec(`INSERT INTO snapshotCompliance (snapshot_id, report_id, title, pass_count, fail_count) VALUES (?, ?, ?, ?, ?)`,
+			id, report.ReportID, report.Title, report.PassCount, report.FailCount)
+		if err != nil {
+			return fmt.Errorf("failed to insert compliance snapshot: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Logger.Debug("Successfully inserted snapshot", "id", id, "images", len(s.Images), "namespaces", len(s.Namespaces))
+	return nil
+}
+
+// DeleteSnapshotsBefore deletes the snapshots taken before the given time, and returns the number deleted
+func DeleteSnapshotsBefore(before time.Time) (int64, error) {
+	tx, err := Client.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			// Do nothing, this happens commonly when the transaction has already been committed
+		}
+	}()
+
+	// SQLite doesn't enforce foreign keys unless enabled per connection, so the child rows are deleted explicitly
+	for _, table := range []string{"snapshotImages", "snapshotNamespaces", "snapshotCompliance"} {
+		_, err = tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE snapshot_id IN (SELECT id FROM snapshots WHERE taken_at < ?)`, table), before.UTC())
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete expired rows from %s: %w", table, err)
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM snapshots WHERE taken_at < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired snapshots: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted row count: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deleted, nil
+}
+
+// GetClusterTrend returns the cluster-wide severity counts of every snapshot, oldest first
+func GetClusterTrend() ([]TrendPoint, error) {
+	var points []TrendPoint
+	err := Client.Select(&points, `SELECT taken_at, critical, high, medium, low FROM snapshots ORDER BY taken_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster trend: %w", err)
+	}
+	return points, nil
+}
+
+// GetNamespaceTrend returns a namespace's severity counts in every snapshot, oldest first
+// Snapshots taken while nothing in the namespace was scanned are counted as zero
+func GetNamespaceTrend(namespace string) ([]TrendPoint, error) {
+	var points []TrendPoint
+	err := Client.Select(&points, `SELECT s.taken_at,
+			COALESCE(n.critical, 0) AS critical, COALESCE(n.high, 0) AS high, COALESCE(n.medium, 0) AS medium, COALESCE(n.low, 0) AS low
+		FROM snapshots s
+		LEFT JOIN snapshotNamespaces n ON n.snapshot_id = s.id AND n.namespace = ?
+		ORDER BY s.taken_at`, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace trend: %w", err)
+	}
+	return points, nil
+}
+
+// GetImageTrend returns an image's severity counts in the snapshots it was in, oldest first
+func GetImageTrend(registry, repository, tag, digest string) ([]TrendPoint, error) {
+	var points []TrendPoint
+	err := Client.Select(&points, `SELECT s.taken_at, i.critical, i.high, i.medium, i.low
+		FROM snapshotImages i
+		JOIN snapshots s ON s.id = i.snapshot_id
+		WHERE i.registry = ? AND i.repository = ? AND i.tag = ? AND i.digest = ?
+		ORDER BY s.taken_at`, registry, repository, tag, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image trend: %w", err)
+	}
+	return points, nil
+}
+
+// GetComplianceTrends returns the pass and fail counts of every compliance report over time, sorted by report ID
+func GetComplianceTrends() ([]ComplianceTrend, error) {
+	var rows []struct {
+		ReportID string `db:"report_id"`
+		Title    string `db:"title"`
+		ComplianceTrendPoint
+	}
+	err := Client.Select(&rows, `SELECT c.report_id, c.title, s.taken_at, c.pass_count, c.fail_count
+		FROM snapshotCompliance c
+		JOIN snapshots s ON s.id = c.snapshot_id
+		ORDER BY c.report_id, s.taken_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get compliance trends: %w", err)
+	}
+
+	var trends []ComplianceTrend
+	for _, row := range rows {
+		if len(trends) == 0 || trends[len(trends)-1].ReportID != row.ReportID {
+			trends = append(trends, ComplianceTrend{ReportID: row.ReportID})
+		}
+		// Titles can change between operator versions, so the latest one is kept
+		trends[len(trends)-1].Title = row.Title
+		trends[len(trends)-1].Points = append(trends[len(trends)-1].Points, row.ComplianceTrendPoint)
+	}
+	return trends, nil
+}
+
+// GetLatestSnapshotTime returns when the most recent snapshot was taken, and false if there are no snapshots
+func GetLatestSnapshotTime() (time.Time, bool, error) {
+	var takenAt []time.Time
+	err := Client.Select(&takenAt, `SELECT taken_at FROM snapshots ORDER BY taken_at DESC LIMIT 1`)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get latest snapshot time: %w", err)
+	}
+	if len(takenAt) == 0 {
+		return time.Time{}, false, nil
+	}
+	return takenAt[0], true, nil
+}
+
+// GetDetailedSnapshotAtOrBefore returns the latest detailed snapshot taken at or before the given time, and false if there isn't one
+func GetDetailedSnapshotAtOrBefore(at time.Time) (SnapshotInfo, bool, error) {
+	var snapshots []SnapshotInfo
+	err := Client.Select(&snapshots, `SELECT id, taken_at FROM snapshots WHERE detailed = 1 AND taken_at <= ? ORDER BY taken_at DESC LIMIT 1`, at.UTC())
+	if err != nil {
+		return SnapshotInfo{}, false, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	if len(snapshots) == 0 {
+		return SnapshotInfo{}, false, nil
+	}
+	return snapshots[0], true, nil
+}
+
+// GetOldestDetailedSnapshotTime returns when the oldest detailed snapshot was taken, and false if there are none
+func GetOldestDetailedSnapshotTime() (time.Time, bool, error) {
+	var takenAt []time.Time
+	err := Client.Select(&takenAt, `SELECT taken_at FROM snapshots WHERE detailed = 1 ORDER BY taken_at LIMIT 1`)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get oldest snapshot time: %w", err)
+	}
+	if len(takenAt) == 0 {
+		return time.Time{}, false, nil
+	}
+	return takenAt[0], true, nil
+}
+
+// GetSnapshotImages returns the images recorded in a snapshot
+func GetSnapshotImages(id int) ([]ImageSnapshot, error) {
+	var images []ImageSnapshot
+	err := Client.Select(&images, `SELECT registry, repository, tag, digest, critical, high, medium, low FROM snapshotImages WHERE snapshot_id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot images: %w", err)
+	}
+	return images, nil
+}
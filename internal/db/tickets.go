@@ -0,0 +1,138 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// Ticket represents a row in the tickets table, an issue created in the ticket tracker for a CVE in an image repository
+// Tickets are tracked per repository like the findings ledger, so a ticket covers every tag and package the CVE is found in
+type Ticket struct {
+	Registry   string `db:"registry" json:"registry"`
+	Repository string `db:"repository" json:"repository"`
+	CVEID      string `db:"cve_id" json:"cve_id"`
+	IssueKey   string `db:"issue_key" json:"issue_key"`
+	// URL links to the issue in the tracker's web interface
+	URL string `db:"url" json:"url"`
+	// Status is the issue's status when it was last checked, like "To Do" or "Done", and empty until it has been checked
+	Status    string    `db:"status" json:"status"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// CreatedBy is the user that created the ticket, empty when created anonymously or automatically
+	CreatedBy string `db:"created_by" json:"created_by,omitempty"`
+	// Automatic is true for tickets created for new critical vulnerabilities rather than by a user
+	Automatic bool `db:"automatic" json:"automatic"`
+}
+
+// TicketKey identifies the finding a ticket was created for
+type TicketKey struct {
+	Registry   string
+	Repository string
+	CVEID      string
+}
+
+// Key returns the finding the ticket was created for
+func (t Ticket) Key() TicketKey {
+	return TicketKey{
+		Registry:   t.Registry,
+		Repository: t.Repository,
+		CVEID:      t.CVEID,
+	}
+}
+
+func initTicketsTables() error {
+	_, err := Client.Exec(`CREATE TABLE IF NOT EXISTS tickets (
+		registry TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		cve_id TEXT NOT NULL,
+		issue_key TEXT NOT NULL,
+		url TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		created_by TEXT NOT NULL DEFAULT '',
+		automatic BOOLEAN NOT NULL DEFAULT FALSE,
+		PRIMARY KEY(registry, repository, cve_id)
+	);
+	CREATE TABLE IF NOT EXISTS ticketAutomation (
+		project TEXT PRIMARY KEY,
+		started_at TIMESTAMP NOT NULL
+	);`)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info("✓ tickets tables created/verified")
+	return nil
+}
+
+// InsertTicket records a ticket created for a finding
+func InsertTicket(t Ticket) error {
+	_, err := Client.Exec(`INSERT INTO tickets (registry, repository, cve_id, issue_key, url, status, created_at, created_by, automatic)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Registry, t.Repository, t.CVEID, t.IssueKey, t.URL, t.Status, t.CreatedAt.UTC(), t.CreatedBy, t.Automatic)
+	if err != nil {
+		return fmt.Errorf("failed to insert ticket %s: %w", t.IssueKey, err)
+	}
+	return nil
+}
+
+// GetTickets returns the tickets matching the filter, keyed by the finding they were created for
+// Empty fields in the filter match every ticket, so an empty filter returns every ticket
+func GetTickets(filter TicketKey) (map[TicketKey]Ticket, error) {
+	query := `SELECT registry, repository, cve_id, issue_key, url, status, created_at, created_by, automatic FROM tickets WHERE 1 = 1`
+	var args []any
+	if filter.Registry != "" || filter.Repository != "" {
+		query += ` AND registry = ? AND repository = ?`
+		args = append(args, filter.Registry, filter.Repository)
+	}
+	if filter.CVEID != "" {
+		query += ` AND cve_id = ?`
+		args = append(args, filter.CVEID)
+	}
+
+	var rows []Ticket
+	if err := Client.Select(&rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", err)
+	}
+
+	tickets := make(map[TicketKey]Ticket, len(rows))
+	for _, t := range rows {
+		tickets[t.Key()] = t
+	}
+	return tickets, nil
+}
+
+// SetTicketStatus records the latest status of an issue
+func SetTicketStatus(issueKey, status string) error {
+	_, err := Client.Exec(`UPDATE tickets SET status = ? WHERE issue_key = ?`, status, issueKey)
+	if err != nil {
+		return fmt.Errorf("failed to set ticket status: %w", err)
+	}
+	return nil
+}
+
+// GetTicketAutomationStart returns when automatic ticket creation was first enabled for a project
+// The returned bool is false if it has never been enabled for the project
+func GetTicketAutomationStart(project string) (time.Time, bool, error) {
+	var startedAt []time.Time
+	err := Client.Select(&startedAt, `SELECT started_at FROM ticketAutomation WHERE project = ?`, project)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get ticket automation start time: %w", err)
+	}
+	if len(startedAt) == 0 {
+		return time.Time{}, false, nil
+	}
+	return startedAt[0], true, nil
+}
+
+// SetTicketAutomationStart records when automatic ticket creation was enabled for a project
+// Only vulnerabilities first seen after then are ticketed automatically, so enabling it doesn't flood the project with existing findings
+func SetTicketAutomationStart(project string, startedAt time.Time) error {
+	_, err := Client.Exec(`INSERT INTO ticketAutomation (project, started_at) VALUES (?, ?)
+		ON CONFLICT(project) DO UPDATE SET started_at = excluded.started_at`, project, startedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to set ticket automation start time: %w", err)
+	}
+	return nil
+}
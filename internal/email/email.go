@@ -0,0 +1,276 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// TLS modes for connecting to the SMTP server
+const (
+	// TLSStartTLS upgrades a plain connection with STARTTLS, and fails if the server doesn't support it
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects over TLS from the start, usually on port 465
+	TLSImplicit = "tls"
+	// TLSNone never encrypts the connection, and is only meant for relays on a trusted network
+	TLSNone = "none"
+)
+
+// Config contains the settings for connecting to an SMTP server
+type Config struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN auth when Username is set
+	Username string
+	Password string
+	// From is the sender address, optionally with a display name
+	From string
+	// TLS is one of starttls, tls or none
+	TLS string
+	// Timeout bounds connecting to the server and sending a message
+	Timeout time.Duration
+}
+
+// Message is an email with a plain text and an HTML body
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender sends email through an SMTP server
+type Sender struct {
+	config Config
+	from   *mail.Address
+	// rootCAs verify the server's certificate, the system's roots are used when it's nil
+	rootCAs *x509.CertPool
+}
+
+// New validates the config and returns a sender
+func New(config Config) (*Sender, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("an SMTP host is required")
+	}
+	if config.Port <= 0 || config.Port > 65535 {
+		return nil, fmt.Errorf("SMTP port must be between 1 and 65535, got %d", config.Port)
+	}
+	if config.TLS != TLSStartTLS && config.TLS != TLSImplicit && config.TLS != TLSNone {
+		return nil, fmt.Errorf("SMTP TLS mode must be one of starttls, tls or none, got %q", config.TLS)
+	}
+	if config.Timeout <= 0 {
+		return nil, fmt.Errorf("SMTP timeout must be greater than 0, got %s", config.Timeout)
+	}
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP from address %q: %w", config.From, err)
+	}
+
+	return &Sender{
+		config: config,
+		from:   from,
+	}, nil
+}
+
+// ValidateAddresses returns an error naming the first address that isn't a valid email address
+func ValidateAddresses(addresses []string) error {
+	for _, address := range addresses {
+		if _, err := mail.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid email address %q: %w", address, err)
+		}
+	}
+	return nil
+}
+
+// Send sends the message to each of its recipients in a single SMTP transaction
+func (s *Sender) Send(ctx context.Context, m Message) error {
+	if len(m.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+	var to []*mail.Address
+	for _, address := range m.To {
+		a, err := mail.ParseAddress(address)
+		if err != nil {
+			return fmt.Errorf("invalid recipient address %q: %w", address, err)
+		}
+		to = append(to, a)
+	}
+
+	body, err := s.build(m, to, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	c, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.Close(); err != nil {
+			log.Logger.Debug("Failed to close SMTP connection", "error", err)
+		}
+	}()
+
+	if s.config.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s doesn't support STARTTLS, set the TLS mode to tls or none to connect without it", s.config.Host)
+		}
+		if err := c.StartTLS(s.tlsConfig()); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if s.config.Username != "" {
+		// PLAIN auth refuses to send credentials over an unencrypted connection to anything but localhost
+		if err := c.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, a := range to {
+		if err := c.Rcpt(a.Address); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", a.Address, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	if err := c.Quit(); err != nil {
+		log.Logger.Debug("Failed to quit SMTP session after sending", "error", err)
+	}
+	return nil
+}
+
+// dial connects to the SMTP server, bounding the whole session by the context's deadline
+func (s *Sender) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			closeConn(conn)
+			return nil, fmt.Errorf("failed to set SMTP connection deadline: %w", err)
+		}
+	}
+	if s.config.TLS == TLSImplicit {
+		conn = tls.Client(conn, s.tlsConfig())
+	}
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		closeConn(conn)
+		return nil, fmt.Errorf("failed to start SMTP session with %s: %w", addr, err)
+	}
+	return c, nil
+}
+
+func (s *Sender) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: s.config.Host, RootCAs: s.rootCAs, MinVersion: tls.VersionTLS12}
+}
+
+func closeConn(conn net.Conn) {
+	if err := conn.Close(); err != nil {
+		log.Logger.Debug("Failed to close SMTP connection", "error", err)
+	}
+}
+
+// build renders the message as a multipart/alternative email, so clients that don't render HTML show the plain text
+func (s *Sender) build(m Message, to []*mail.Address, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var recipients []string
+	for _, a := range to {
+		recipients = append(recipients, a.String())
+	}
+	messageID, err := newMessageID(s.from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	mw := multipart.NewWriter(&buf)
+	headers := []struct{ key, value string }{
+		{"From", s.from.String()},
+		{"To", strings.Join(recipients, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.key, h.value)
+	}
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create message part: %w", err)
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.body)); err != nil {
+			return nil, fmt.Errorf("failed to write message part: %w", err)
+		}
+		if err := qw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write message part: %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish message: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain
+func newMessageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
+}
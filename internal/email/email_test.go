@@ -0,0 +1,347 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	log.Init("error")
+	os.Exit(m.Run())
+}
+
+// smtpSession is what the fake SMTP server received in a session
+type smtpSession struct {
+	// auth is the decoded PLAIN auth response
+	auth string
+	from string
+	to   []string
+	data []byte
+	// tls is true if the message was sent over TLS
+	tls bool
+	// err is the error that ended the session early, if any
+	err error
+}
+
+// smtpServer is a fake SMTP server that accepts a single session
+type smtpServer struct {
+	listener net.Listener
+	// startTLS upgrades connections with STARTTLS when set, and STARTTLS isn't advertised when it's nil
+	startTLS *tls.Config
+	done     chan smtpSession
+}
+
+// newSMTPServer starts a fake SMTP server, listening over TLS from the start when implicitTLS is set
+func newSMTPServer(t *testing.T, implicitTLS, startTLS *tls.Config) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicitTLS != nil {
+		listener = tls.NewListener(listener, implicitTLS)
+	}
+	s := &smtpServer{listener: listener, startTLS: startTLS, done: make(chan smtpSession, 1)}
+	t.Cleanup(func() {
+		if err := listener.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		session, err := s.serve(conn)
+		session.err = err
+		s.done <- session
+	}()
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// session waits for the session to end and returns what was received
+func (s *smtpServer) session(t *testing.T) smtpSession {
+	select {
+	case session := <-s.done:
+		if session.err != nil {
+			t.Fatalf("fake SMTP server: %s", session.err)
+		}
+		return session
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the SMTP session to end")
+		return smtpSession{}
+	}
+}
+
+func (s *smtpServer) serve(conn net.Conn) (smtpSession, error) {
+	var session smtpSession
+	_, session.tls = conn.(*tls.Conn)
+	text := textproto.NewConn(conn)
+	reply := func(lines ...string) error {
+		for _, line := range lines {
+			if err := text.PrintfLine("%s", line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := reply("220 localhost ESMTP"); err != nil {
+		return session, err
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return session, err
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			lines := []string{"250-localhost", "250-AUTH PLAIN"}
+			if s.startTLS != nil && !session.tls {
+				lines = append(lines, "250-STARTTLS")
+			}
+			err = reply(append(lines, "250 8BITMIME")...)
+		case "STARTTLS":
+			if err := reply("220 ready to start TLS"); err != nil {
+				return session, err
+			}
+			tlsConn := tls.Server(conn, s.startTLS)
+			if err := tlsConn.Handshake(); err != nil {
+				return session, err
+			}
+			conn, text, session.tls = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			mechanism, response, _ := strings.Cut(arg, " ")
+			decoded, decodeErr := base64.StdEncoding.DecodeString(response)
+			if mechanism != "PLAIN" || decodeErr != nil {
+				err = reply("504 unsupported authentication")
+				break
+			}
+			session.auth = string(decoded)
+			err = reply("235 authenticated")
+		case "MAIL":
+			session.from = envelopeAddress(arg)
+			err = reply("250 ok")
+		case "RCPT":
+			session.to = append(session.to, envelopeAddress(arg))
+			err = reply("250 ok")
+		case "DATA":
+			if err := reply("354 send the message"); err != nil {
+				return session, err
+			}
+			session.data, err = text.ReadDotBytes()
+			if err != nil {
+				return session, err
+			}
+			err = reply("250 queued")
+		case "QUIT":
+			return session, reply("221 bye")
+		default:
+			err = reply("502 unknown command")
+		}
+		if err != nil {
+			return session, err
+		}
+	}
+}
+
+// envelopeAddress returns the address in a MAIL FROM:<address> or RCPT TO:<address> argument
+func envelopeAddress(arg string) string {
+	_, rest, _ := strings.Cut(arg, "<")
+	address, _, _ := strings.Cut(rest, ">")
+	return address
+}
+
+// newTestCertificate returns a self-signed certificate for 127.0.0.1 and a pool trusting it
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "smtp test"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+func newTestSender(t *testing.T, port int, mode string, rootCAs *x509.CertPool) *Sender {
+	s, err := New(Config{
+		Host:     "127.0.0.1",
+		Port:     port,
+		Username: "explorer",
+		Password: "secret",
+		From:     "Trivy Operator Explorer <explorer@example.com>",
+		TLS:      mode,
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rootCAs = rootCAs
+	return s
+}
+
+var testMessage = Message{
+	To:      []string{"Jane Doe <jane@example.com>", "ops@example.com"},
+	Subject: "Weekly digest – 3 new vulnerabilities",
+	Text:    "3 new vulnerabilities\nCVE-2024-0001 in nginx:1.25 = critical, and a line long enough that quoted-printable has to wrap it with a soft line break",
+	HTML:    `<h1 style="color: red">3 new vulnerabilities</h1>`,
+}
+
+// checkMessage checks the session delivered testMessage to each recipient
+func checkMessage(t *testing.T, session smtpSession) {
+	t.Helper()
+	if session.auth != "\x00explorer\x00secret" {
+		t.Errorf("got PLAIN auth %q", session.auth)
+	}
+	if session.from != "explorer@example.com" {
+		t.Errorf("envelope sender = %q, want explorer@example.com", session.from)
+	}
+	if want := []string{"jane@example.com", "ops@example.com"}; !reflect.DeepEqual(session.to, want) {
+		t.Errorf("envelope recipients = %q, want %q", session.to, want)
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(session.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Header.Get("To"); got != `"Jane Doe" <jane@example.com>, <ops@example.com>` {
+		t.Errorf("To header = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != testMessage.Subject {
+		t.Errorf("Subject header = %q, want %q", subject, testMessage.Subject)
+	}
+	if !strings.HasSuffix(m.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID header = %q, want one in the sender's domain", m.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type header = %q, want multipart/alternative", m.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(m.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", testMessage.Text},
+		{"text/html; charset=utf-8", testMessage.HTML},
+	} {
+		// The reader decodes quoted-printable parts
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, want.contentType)
+		}
+		if string(body) != want.body {
+			t.Errorf("%s part = %q, want %q", want.contentType, body, want.body)
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("got more than a text and an HTML part: %v", err)
+	}
+}
+
+func TestSendWithoutTLS(t *testing.T) {
+	server := newSMTPServer(t, nil, nil)
+	if err := newTestSender(t, server.port(), TLSNone, nil).Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	session := server.session(t)
+	if session.tls {
+		t.Error("message was sent over TLS, want a plain connection")
+	}
+	checkMessage(t, session)
+}
+
+func TestSendImplicitTLS(t *testing.T) {
+	cert, pool := newTestCertificate(t)
+	server := newSMTPServer(t, &tls.Config{Certificates: []tls.Certificate{cert}}, nil)
+	if err := newTestSender(t, server.port(), TLSImplicit, pool).Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	session := server.session(t)
+	if !session.tls {
+		t.Error("message was sent over a plain connection, want TLS")
+	}
+	checkMessage(t, session)
+}
+
+func TestSendImplicitTLSUntrustedCertificate(t *testing.T) {
+	cert, _ := newTestCertificate(t)
+	server := newSMTPServer(t, &tls.Config{Certificates: []tls.Certificate{cert}}, nil)
+	err := newTestSender(t, server.port(), TLSImplicit, nil).Send(context.Background(), testMessage)
+	if err == nil {
+		t.Fatal("sent over TLS to a server with an untrusted certificate")
+	}
+}
+
+func TestSendStartTLS(t *testing.T) {
+	cert, pool := newTestCertificate(t)
+	server := newSMTPServer(t, nil, &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err := newTestSender(t, server.port(), TLSStartTLS, pool).Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	session := server.session(t)
+	if !session.tls {
+		t.Error("message was sent over a plain connection, want it upgraded with STARTTLS")
+	}
+	checkMessage(t, session)
+}
+
+func TestSendStartTLSUnsupported(t *testing.T) {
+	server := newSMTPServer(t, nil, nil)
+	err := newTestSender(t, server.port(), TLSStartTLS, nil).Send(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "doesn't support STARTTLS") {
+		t.Fatalf("got error %v, want STARTTLS to be required", err)
+	}
+}
@@ -0,0 +1,305 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// DefaultSummaryTemplate is the issue summary used when no summary template is configured
+const DefaultSummaryTemplate = `{{ .CVEID }} ({{ .Severity }}) in {{ .Image }}`
+
+// DefaultDescriptionTemplate is the issue description used when no description template is configured, written in Jira's wiki markup
+const DefaultDescriptionTemplate = `{{ .Severity }} vulnerability {{ .CVEID }}{{ if .Title }}: {{ .Title }}{{ end }}
+
+*Image repository:* {{ .Image }}
+{{- if .Tags }}
+*Tags:* {{ join .Tags ", " }}
+{{- end }}
+{{- if .Score }}
+*Score:* {{ .Score }}
+{{- end }}
+{{- if .Namespaces }}
+*Namespaces:* {{ join .Namespaces ", " }}
+{{- end }}
+
+h3. Vulnerable packages
+||Package||Installed||Fixed in||
+{{- range .Packages }}
+|{{ .Name }}|{{ .InstalledVersion }}|{{ if .FixedVersion }}{{ .FixedVersion }}{{ else }}no fix available{{ end }}|
+{{- end }}
+{{- if .Workloads }}
+
+h3. Affected workloads
+{{- range .Workloads }}
+* {{ . }}
+{{- end }}
+{{- end }}
+{{- if .Description }}
+
+h3. Description
+{{ .Description }}
+{{- end }}
+{{- if .Links }}
+
+h3. References
+{{- range .Links }}
+* {{ . }}
+{{- end }}
+{{- end }}
+{{- if .URL }}
+
+[View in Trivy Operator Explorer|{{ .URL }}]
+{{- end }}`
+
+// maxSummaryLength is the longest summary Jira accepts
+const maxSummaryLength = 255
+
+// Config contains the settings for creating issues in a Jira compatible tracker
+type Config struct {
+	// URL is the tracker's base URL, like https://example.atlassian.net
+	URL string
+	// Project is the key of the project issues are created in
+	Project string
+	// IssueType is the name of the type of issue created, like Bug or Task
+	IssueType string
+	// Username is used with Token for basic authentication
+	Username string
+	// Token is an API token, used as the password with Username or as a bearer token (like a personal access token) without one
+	Token string
+	// Labels are added to every issue created
+	Labels []string
+	// SummaryTemplate and DescriptionTemplate are Go templates rendered with a Finding, the defaults are used when they're empty
+	SummaryTemplate     string
+	DescriptionTemplate string
+	// Timeout bounds each request to the tracker
+	Timeout time.Duration
+}
+
+// Finding is a vulnerability in an image repository, and the data the issue templates are rendered with
+type Finding struct {
+	CVEID       string
+	Severity    string
+	Score       float64
+	Title       string
+	Description string
+	// Registry and Repository identify the vulnerable image repository, Image is their full name for display
+	Registry   string
+	Repository string
+	Image      string
+	// Tags are the image repository's tags containing the vulnerability
+	Tags     []string
+	Packages []Package
+	// Namespaces and Workloads are where the vulnerable images are running, workloads are written like namespace/kind/name
+	Namespaces []string
+	Workloads  []string
+	Links      []string
+	// URL links to the finding in the explorer, and is empty when no external URL is configured
+	URL string
+}
+
+// Package is a vulnerable package in a finding
+type Package struct {
+	Name             string
+	InstalledVersion string
+	FixedVersion     string
+}
+
+// Issue is an issue created in the tracker
+type Issue struct {
+	Key string
+	// URL links to the issue in the tracker's web interface
+	URL string
+}
+
+// Client creates and looks up issues with the Jira REST API
+type Client struct {
+	config      Config
+	baseURL     string
+	client      *http.Client
+	summary     *template.Template
+	description *template.Template
+}
+
+// New returns a client for the tracker in the config, after checking the config is complete and its templates parse
+func New(config Config) (*Client, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("jira URL must be an absolute http or https URL, got %q", config.URL)
+	}
+	if config.Project == "" {
+		return nil, fmt.Errorf("jira project must be set")
+	}
+	if config.IssueType == "" {
+		return nil, fmt.Errorf("jira issue type must be set")
+	}
+	if config.Username != "" && config.Token == "" {
+		return nil, fmt.Errorf("jira username is set without an API token")
+	}
+	if config.Timeout <= 0 {
+		return nil, fmt.Errorf("jira timeout must be positive, got %s", config.Timeout)
+	}
+	for _, label := range config.Labels {
+		// Jira rejects labels containing spaces
+		if label == "" || strings.ContainsAny(label, " \t\n") {
+			return nil, fmt.Errorf("jira label %q must be non-empty and contain no spaces", label)
+		}
+	}
+
+	if config.SummaryTemplate == "" {
+		config.SummaryTemplate = DefaultSummaryTemplate
+	}
+	if config.DescriptionTemplate == "" {
+		config.DescriptionTemplate = DefaultDescriptionTemplate
+	}
+	funcs := template.FuncMap{"join": strings.Join}
+	summary, err := template.New("summary").Funcs(funcs).Parse(config.SummaryTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jira summary template: %w", err)
+	}
+	description, err := template.New("description").Funcs(funcs).Parse(config.DescriptionTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jira description template: %w", err)
+	}
+
+	// Templates referring to fields a finding doesn't have only fail when rendered, so render them once up front
+	for _, tmpl := range []*template.Template{summary, description} {
+		if _, err := render(tmpl, Finding{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Client{
+		config:      config,
+		baseURL:     strings.TrimSuffix(config.URL, "/"),
+		client:      &http.Client{Timeout: config.Timeout},
+		summary:     summary,
+		description: description,
+	}, nil
+}
+
+// Project returns the key of the project issues are created in
+func (c *Client) Project() string {
+	return c.config.Project
+}
+
+// BrowseURL returns the link to an issue in the tracker's web interface
+func (c *Client) BrowseURL(key string) string {
+	return c.baseURL + "/browse/" + url.PathEscape(key)
+}
+
+// CreateIssue creates an issue for the finding, rendering its summary and description from the templates
+func (c *Client) CreateIssue(ctx context.Context, f Finding) (Issue, error) {
+	summary, err := render(c.summary, f)
+	if err != nil {
+		return Issue{}, err
+	}
+	// Summaries are a single line, and Jira rejects long ones rather than truncating them
+	summary = strings.Join(strings.Fields(summary), " ")
+	if runes := []rune(summary); len(runes) > maxSummaryLength {
+		summary = string(runes[:maxSummaryLength-3]) + "..."
+	}
+	description, err := render(c.description, f)
+	if err != nil {
+		return Issue{}, err
+	}
+
+	fields := map[string]any{
+		"project":     map[string]string{"key": c.config.Project},
+		"issuetype":   map[string]string{"name": c.config.IssueType},
+		"summary":     summary,
+		"description": description,
+	}
+	if len(c.config.Labels) > 0 {
+		fields["labels"] = c.config.Labels
+	}
+	body, err := json.Marshal(map[string]any{"fields": fields})
+	if err != nil {
+		return Issue{}, fmt.Errorf("failed to encode issue: %w", err)
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/rest/api/2/issue", body, &created); err != nil {
+		return Issue{}, fmt.Errorf("failed to create issue: %w", err)
+	}
+	if created.Key == "" {
+		return Issue{}, fmt.Errorf("failed to create issue: the response didn't include an issue key")
+	}
+	return Issue{Key: created.Key, URL: c.BrowseURL(created.Key)}, nil
+}
+
+// Status returns the name of an issue's status, like "To Do" or "Done"
+func (c *Client) Status(ctx context.Context, key string) (string, error) {
+	var issue struct {
+		Fields struct {
+			Status struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"?fields=status", nil, &issue); err != nil {
+		return "", fmt.Errorf("failed to get issue %s: %w", key, err)
+	}
+	return issue.Fields.Status.Name, nil
+}
+
+// do sends a request to the REST API and decodes its JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "trivy-operator-explorer")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Token)
+	} else if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Logger.Error("Failed to close jira response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Include the start of the response, Jira explains which fields it rejected
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("jira responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode jira response: %w", err)
+	}
+	return nil
+}
+
+func render(tmpl *template.Template, f Finding) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, f); err != nil {
+		return "", fmt.Errorf("failed to render jira %s template: %w", tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,220 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	log.Init("error")
+	os.Exit(m.Run())
+}
+
+// fakeJira is a fake of the Jira REST API endpoints the client uses
+type fakeJira struct {
+	mu sync.Mutex
+	// created are the fields of each issue created
+	created []map[string]any
+	// statuses are the status names of the issues by key
+	statuses map[string]string
+	// createStatus and createBody override the response to creating an issue when createStatus is set
+	createStatus int
+	createBody   string
+	// auth is the Authorization header of the last request
+	auth string
+}
+
+func newFakeJira(t *testing.T) (*fakeJira, *httptest.Server) {
+	f := &fakeJira{statuses: make(map[string]string)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = r.Header.Get("Authorization")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+		if f.createStatus != 0 {
+			w.WriteHeader(f.createStatus)
+			_, _ = fmt.Fprint(w, f.createBody)
+			return
+		}
+		var body struct {
+			Fields map[string]any `json:"fields"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.created = append(f.created, body.Fields)
+		key := fmt.Sprintf("SEC-%d", len(f.created))
+		f.statuses[key] = "To Do"
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"id":"%d","key":%q,"self":"http://%s/rest/api/2/issue/%d"}`, 10000+len(f.created), key, r.Host, 10000+len(f.created))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/rest/api/2/issue/"):
+		status, ok := f.statuses[strings.TrimPrefix(r.URL.Path, "/rest/api/2/issue/")]
+		if !ok || r.URL.Query().Get("fields") != "status" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"errorMessages":["Issue does not exist or you do not have permission to see it."],"errors":{}}`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"id":"10001","key":"SEC-1","fields":{"status":{"self":"http://jira/rest/api/2/status/1","name":%q,"id":"1","statusCategory":{"key":"new"}}}}`, status)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var testFinding = Finding{
+	CVEID:      "CVE-2024-0001",
+	Severity:   "CRITICAL",
+	Score:      9.8,
+	Title:      "openssl: buffer overflow",
+	Registry:   "index.docker.io",
+	Repository: "library/nginx",
+	Image:      "nginx",
+	Tags:       []string{"1.25", "1.25.1"},
+	Packages:   []Package{{Name: "openssl", InstalledVersion: "3.0.0", FixedVersion: "3.0.1"}, {Name: "libssl3", InstalledVersion: "3.0.0"}},
+	Namespaces: []string{"default"},
+	Workloads:  []string{"default/Deployment/nginx"},
+	URL:        "https://explorer.example.com/cve?id=CVE-2024-0001",
+}
+
+func newTestClient(t *testing.T, config Config) *Client {
+	c, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCreateIssue(t *testing.T) {
+	fake, server := newFakeJira(t)
+	c := newTestClient(t, Config{
+		URL:             server.URL + "/",
+		Project:         "SEC",
+		IssueType:       "Bug",
+		Username:        "explorer@example.com",
+		Token:           "secret",
+		Labels:          []string{"security", "trivy"},
+		SummaryTemplate: "[{{ .Severity }}]\n{{ .CVEID }} in {{ .Image }}:{{ join .Tags \",\" }}",
+		Timeout:         5 * time.Second,
+	})
+
+	issue, err := c.CreateIssue(context.Background(), testFinding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Key != "SEC-1" || issue.URL != server.URL+"/browse/SEC-1" {
+		t.Errorf("got issue %+v, want SEC-1 linking to %s/browse/SEC-1", issue, server.URL)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.created) != 1 {
+		t.Fatalf("created %d issues, want 1", len(fake.created))
+	}
+	fields := fake.created[0]
+	if !strings.HasPrefix(fake.auth, "Basic ") {
+		t.Errorf("got Authorization header %q, want basic auth", fake.auth)
+	}
+	if got := fields["project"]; !reflect.DeepEqual(got, map[string]any{"key": "SEC"}) {
+		t.Errorf("project = %v, want SEC", got)
+	}
+	if got := fields["issuetype"]; !reflect.DeepEqual(got, map[string]any{"name": "Bug"}) {
+		t.Errorf("issuetype = %v, want Bug", got)
+	}
+	if got := fields["labels"]; !reflect.DeepEqual(got, []any{"security", "trivy"}) {
+		t.Errorf("labels = %v, want security and trivy", got)
+	}
+	// Summaries are collapsed onto a single line
+	if got, want := fields["summary"], "[CRITICAL] CVE-2024-0001 in nginx:1.25,1.25.1"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+
+	description, _ := fields["description"].(string)
+	for _, want := range []string{
+		"CRITICAL vulnerability CVE-2024-0001: openssl: buffer overflow",
+		"*Tags:* 1.25, 1.25.1",
+		"*Score:* 9.8",
+		"|openssl|3.0.0|3.0.1|",
+		"|libssl3|3.0.0|no fix available|",
+		"* default/Deployment/nginx",
+		"[View in Trivy Operator Explorer|https://explorer.example.com/cve?id=CVE-2024-0001]",
+	} {
+		if !strings.Contains(description, want) {
+			t.Errorf("description is missing %q:\n%s", want, description)
+		}
+	}
+}
+
+func TestCreateIssueTruncatesSummary(t *testing.T) {
+	fake, server := newFakeJira(t)
+	c := newTestClient(t, Config{URL: server.URL, Project: "SEC", IssueType: "Bug", Token: "pat", SummaryTemplate: "{{ .Title }}", Timeout: 5 * time.Second})
+
+	finding := testFinding
+	finding.Title = strings.Repeat("é", 300)
+	if _, err := c.CreateIssue(context.Background(), finding); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.auth != "Bearer pat" {
+		t.Errorf("got Authorization header %q, want the token as a bearer token", fake.auth)
+	}
+	summary, _ := fake.created[0]["summary"].(string)
+	if n := len([]rune(summary)); n != maxSummaryLength || !strings.HasSuffix(summary, "...") {
+		t.Errorf("got a summary of %d runes ending %q, want %d ending with ...", n, summary[len(summary)-3:], maxSummaryLength)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	fake, server := newFakeJira(t)
+	c := newTestClient(t, Config{URL: server.URL, Project: "SEC", IssueType: "Bug", Timeout: 5 * time.Second})
+	fake.statuses["SEC-7"] = "In Progress"
+
+	status, err := c.Status(context.Background(), "SEC-7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "In Progress" {
+		t.Errorf("got status %q, want In Progress", status)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	fake, server := newFakeJira(t)
+	c := newTestClient(t, Config{URL: server.URL, Project: "SEC", IssueType: "Bug", Timeout: 5 * time.Second})
+
+	fake.createStatus, fake.createBody = http.StatusBadRequest, `{"errorMessages":[],"errors":{"issuetype":"The issue type selected is invalid."}}`
+	_, err := c.CreateIssue(context.Background(), testFinding)
+	if err == nil || !strings.Contains(err.Error(), "status 400") || !strings.Contains(err.Error(), "The issue type selected is invalid.") {
+		t.Errorf("got error %v, want the status and Jira's explanation", err)
+	}
+
+	// A successful response without an issue key can't be tracked
+	fake.createStatus, fake.createBody = http.StatusCreated, `{}`
+	if _, err := c.CreateIssue(context.Background(), testFinding); err == nil {
+		t.Error("created an issue from a response without an issue key")
+	}
+
+	if _, err := c.Status(context.Background(), "SEC-404"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("got error %v looking up a missing issue, want a 404", err)
+	}
+}
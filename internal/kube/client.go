@@ -1,10 +1,20 @@
 package kube
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 
 	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -12,6 +22,8 @@ import (
 
 var client *rest.RESTClient
 var coreClient *rest.RESTClient
+var appsClient *rest.RESTClient
+var rbacClient *rest.RESTClient
 
 // InitClient initializes the Kubernetes client based on the provided configuration.
 // If inCluster is true, it uses in-cluster configuration; otherwise, it uses the kubeconfig file.
@@ -63,5 +75,76 @@ func InitClient(inCluster bool, kubeconfigPath string) error {
 
 	coreClient = coreClientset
 
+	// Client for workload resources (deployments, replicasets, etc.)
+	appsConfig := *config
+	appsConfig.ContentConfig.GroupVersion = &appsv1.SchemeGroupVersion
+	appsConfig.APIPath = "/apis"
+	appsConfig.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	appsConfig.UserAgent = rest.DefaultKubernetesUserAgent()
+
+	appsClientset, err := rest.RESTClientFor(&appsConfig)
+	if err != nil {
+		return fmt.Errorf("error creating apps clientset from config: %w", err)
+	}
+
+	appsClient = appsClientset
+
+	// Client for RBAC resources (rolebindings, etc.)
+	rbacConfig := *config
+	rbacConfig.ContentConfig.GroupVersion = &rbacv1.SchemeGroupVersion
+	rbacConfig.APIPath = "/apis"
+	rbacConfig.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	rbacConfig.UserAgent = rest.DefaultKubernetesUserAgent()
+
+	rbacClientset, err := rest.RESTClientFor(&rbacConfig)
+	if err != nil {
+		return fmt.Errorf("error creating rbac clientset from config: %w", err)
+	}
+
+	rbacClient = rbacClientset
+
 	return nil
 }
+
+// retryBackoff is the backoff used when retrying transient Kubernetes API errors
+var retryBackoff = wait.Backoff{
+	Duration: 200 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    4,
+}
+
+// withRetry calls fn, retrying with backoff while it returns transient errors and the context isn't done
+func withRetry(ctx context.Context, fn func() error) error {
+	backoff := retryBackoff
+	for {
+		err := fn()
+		if err == nil || !isTransientError(err) || backoff.Steps <= 1 {
+			return err
+		}
+
+		delay := backoff.Step()
+		log.Logger.Debug("retrying transient kubernetes api error", "error", err.Error(), "delay", delay)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// isTransientError returns true for errors that are likely to succeed when retried
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsConnectionRefused(err) ||
+		utilnet.IsProbableEOF(err)
+}
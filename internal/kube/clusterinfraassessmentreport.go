@@ -9,13 +9,15 @@ import (
 const clusterInfraAssessmentResource = "clusterinfraassessmentreports"
 
 // GetClusterInfraAssessmentReportList retrieves all resources of type clusterinfraassessmentreport in all namespaces.
-func GetClusterInfraAssessmentReportList() (*v1alpha1.ClusterInfraAssessmentReportList, error) {
+func GetClusterInfraAssessmentReportList(ctx context.Context) (*v1alpha1.ClusterInfraAssessmentReportList, error) {
 	var list v1alpha1.ClusterInfraAssessmentReportList
-	err := client.
-		Get().
-		Resource(clusterInfraAssessmentResource).
-		Do(context.TODO()).
-		Into(&list)
+	err := withRetry(ctx, func() error {
+		return client.
+			Get().
+			Resource(clusterInfraAssessmentResource).
+			Do(ctx).
+			Into(&list)
+	})
 	if err != nil {
 		return nil, err
 	}
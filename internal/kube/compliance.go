@@ -9,13 +9,15 @@ import (
 const complianceReportListResource = "clustercompliancereports"
 
 // GetComplianceReportList retrieves all resources of type compliance in all namespaces.
-func GetComplianceReportList() (*v1alpha1.ClusterComplianceReportList, error) {
+func GetComplianceReportList(ctx context.Context) (*v1alpha1.ClusterComplianceReportList, error) {
 	var list v1alpha1.ClusterComplianceReportList
-	err := client.
-		Get().
-		Resource(complianceReportListResource).
-		Do(context.TODO()).
-		Into(&list)
+	err := withRetry(ctx, func() error {
+		return client.
+			Get().
+			Resource(complianceReportListResource).
+			Do(ctx).
+			Into(&list)
+	})
 	if err != nil {
 		return nil, err
 	}
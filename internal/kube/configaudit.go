@@ -9,13 +9,15 @@ import (
 const configAuditReportsResource = "configauditreports"
 
 // GetConfigAuditReportList retrieves all resources of type configauditreport in all namespaces.
-func GetConfigAuditReportList() (*v1alpha1.ConfigAuditReportList, error) {
+func GetConfigAuditReportList(ctx context.Context) (*v1alpha1.ConfigAuditReportList, error) {
 	var list v1alpha1.ConfigAuditReportList
-	err := client.
-		Get().
-		Resource(configAuditReportsResource).
-		Do(context.TODO()).
-		Into(&list)
+	err := withRetry(ctx, func() error {
+		return client.
+			Get().
+			Resource(configAuditReportsResource).
+			Do(ctx).
+			Into(&list)
+	})
 	if err != nil {
 		return nil, err
 	}
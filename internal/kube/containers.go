@@ -27,13 +27,15 @@ type ResourceMetadata struct {
 }
 
 // GetContainerImagesMap retrieves all container image metadata about running images
-func GetContainerImagesMap() (map[string]ContainerImage, error) {
+func GetContainerImagesMap(ctx context.Context) (map[string]ContainerImage, error) {
 	var list corev1.PodList
-	err := coreClient.Get().
-		Resource("pods").
-		VersionedParams(&metav1.ListOptions{}, metav1.ParameterCodec).
-		Do(context.Background()).
-		Into(&list)
+	err := withRetry(ctx, func() error {
+		return coreClient.Get().
+			Resource("pods").
+			VersionedParams(&metav1.ListOptions{}, metav1.ParameterCodec).
+			Do(ctx).
+			Into(&list)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -126,8 +128,8 @@ func getImageResourceMetadata(pod corev1.Pod) map[ResourceMetadata]struct{} {
 }
 
 // GetContainerImages retrieves all container image metadata about running images
-func GetContainerImages() ([]ContainerImage, error) {
-	imageMap, err := GetContainerImagesMap()
+func GetContainerImages(ctx context.Context) ([]ContainerImage, error) {
+	imageMap, err := GetContainerImagesMap(ctx)
 	if err != nil {
 		return nil, err
 	}
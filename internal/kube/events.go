@@ -0,0 +1,117 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EventComponent is the component events are reported as coming from
+const EventComponent = "trivy-operator-explorer"
+
+// maxEventMessageLength keeps event messages within what the API server accepts
+const maxEventMessageLength = 1024
+
+// ErrUnsupportedKind is returned when resolving an object of a kind events can't be emitted on
+var ErrUnsupportedKind = errors.New("unsupported object kind")
+
+// objectResource is where objects of a kind are served in the API
+type objectResource struct {
+	apiVersion string
+	resource   string
+}
+
+// objectResources are the namespaced kinds Trivy Operator reports on, that events can be emitted on
+var objectResources = map[string]objectResource{
+	"Pod":                   {"v1", "pods"},
+	"ReplicationController": {"v1", "replicationcontrollers"},
+	"Service":               {"v1", "services"},
+	"ConfigMap":             {"v1", "configmaps"},
+	"ServiceAccount":        {"v1", "serviceaccounts"},
+	"ReplicaSet":            {"apps/v1", "replicasets"},
+	"Deployment":            {"apps/v1", "deployments"},
+	"StatefulSet":           {"apps/v1", "statefulsets"},
+	"DaemonSet":             {"apps/v1", "daemonsets"},
+	"Job":                   {"batch/v1", "jobs"},
+	"CronJob":               {"batch/v1", "cronjobs"},
+	"Role":                  {"rbac.authorization.k8s.io/v1", "roles"},
+	"RoleBinding":           {"rbac.authorization.k8s.io/v1", "rolebindings"},
+	"NetworkPolicy":         {"networking.k8s.io/v1", "networkpolicies"},
+	"Ingress":               {"networking.k8s.io/v1", "ingresses"},
+}
+
+// ResolveObject looks up an object by its kind, namespace and name, like the trivy-operator.resource.* labels of a report
+// The returned reference includes the object's UID, so events on it are shown when the object is described
+func ResolveObject(ctx context.Context, kind, namespace, name string) (corev1.ObjectReference, error) {
+	r, ok := objectResources[kind]
+	if !ok || namespace == "" || name == "" {
+		return corev1.ObjectReference{}, fmt.Errorf("%w: %s %s/%s", ErrUnsupportedKind, kind, namespace, name)
+	}
+
+	path := "/apis/" + r.apiVersion
+	if r.apiVersion == "v1" {
+		path = "/api/v1"
+	}
+	path += fmt.Sprintf("/namespaces/%s/%s/%s", namespace, r.resource, name)
+
+	var raw []byte
+	err := withRetry(ctx, func() error {
+		var err error
+		raw, err = coreClient.Get().AbsPath(path).DoRaw(ctx)
+		return err
+	})
+	if err != nil {
+		return corev1.ObjectReference{}, err
+	}
+
+	// Only the metadata is needed, so any kind decodes the same way
+	var object metav1.PartialObjectMetadata
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return corev1.ObjectReference{}, fmt.Errorf("failed to decode %s %s/%s: %w", kind, namespace, name, err)
+	}
+
+	return corev1.ObjectReference{
+		APIVersion:      r.apiVersion,
+		Kind:            kind,
+		Namespace:       object.Namespace,
+		Name:            object.Name,
+		UID:             object.UID,
+		ResourceVersion: object.ResourceVersion,
+	}, nil
+}
+
+// CreateWarningEvent emits a Warning event on an object
+func CreateWarningEvent(ctx context.Context, object corev1.ObjectReference, reason, message string) error {
+	if runes := []rune(message); len(runes) > maxEventMessageLength {
+		message = string(runes[:maxEventMessageLength-3]) + "..."
+	}
+
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: object.Name + ".",
+			Namespace:    object.Namespace,
+		},
+		InvolvedObject: object,
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeWarning,
+		Source:         corev1.EventSource{Component: EventComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	// Events aren't retried, a duplicate event would be worse than one emitted on the next check
+	return coreClient.Post().
+		Namespace(object.Namespace).
+		Resource("events").
+		Body(event).
+		Do(ctx).
+		Error()
+}
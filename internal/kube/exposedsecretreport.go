@@ -9,13 +9,15 @@ import (
 const exposedSecretReportsResource = "exposedsecretreports"
 
 // GetExposedSecretReportList retrieves all resources of type exposedsecretreports in all namespaces.
-func GetExposedSecretReportList() (*v1alpha1.ExposedSecretReportList, error) {
+func GetExposedSecretReportList(ctx context.Context) (*v1alpha1.ExposedSecretReportList, error) {
 	var list v1alpha1.ExposedSecretReportList
-	err := client.
-		Get().
-		Resource(exposedSecretReportsResource).
-		Do(context.TODO()).
-		Into(&list)
+	err := withRetry(ctx, func() error {
+		return client.
+			Get().
+			Resource(exposedSecretReportsResource).
+			Do(ctx).
+			Into(&list)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,50 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReportResources are the Trivy Operator report resources the explorer reads from
+var ReportResources = []string{
+	vulnerabilityReportsResource,
+	configAuditReportsResource,
+	exposedSecretReportsResource,
+	rbacAssessmentReportsResource,
+	clusterRbacAssessmentReportsResource,
+	clusterInfraAssessmentResource,
+	complianceReportListResource,
+}
+
+// CheckAPIServer returns an error if the Kubernetes API server can not be reached
+func CheckAPIServer(ctx context.Context) error {
+	_, err := coreClient.Get().AbsPath("/version").DoRaw(ctx)
+	if err != nil {
+		return fmt.Errorf("error reaching kubernetes api server: %w", err)
+	}
+	return nil
+}
+
+// GetServedReportResources returns the set of resources served by the Trivy Operator API group
+// A report resource missing from the set means its CRD isn't installed
+func GetServedReportResources(ctx context.Context) (map[string]struct{}, error) {
+	raw, err := client.Get().AbsPath("/apis", v1alpha1.SchemeGroupVersion.Group, v1alpha1.SchemeGroupVersion.Version).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering %s resources: %w", v1alpha1.SchemeGroupVersion.String(), err)
+	}
+
+	var list metav1.APIResourceList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("error decoding %s resources: %w", v1alpha1.SchemeGroupVersion.String(), err)
+	}
+
+	served := make(map[string]struct{}, len(list.APIResources))
+	for _, r := range list.APIResources {
+		served[r.Name] = struct{}{}
+	}
+	return served, nil
+}
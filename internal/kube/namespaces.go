@@ -0,0 +1,29 @@
+package kube
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetNamespaceLabels returns the labels of every namespace in the cluster, keyed by namespace name
+func GetNamespaceLabels(ctx context.Context) (map[string]map[string]string, error) {
+	var list corev1.NamespaceList
+	err := withRetry(ctx, func() error {
+		return coreClient.Get().
+			Resource("namespaces").
+			VersionedParams(&metav1.ListOptions{}, metav1.ParameterCodec).
+			Do(ctx).
+			Into(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]map[string]string, len(list.Items))
+	for _, ns := range list.Items {
+		labels[ns.Name] = ns.Labels
+	}
+	return labels, nil
+}
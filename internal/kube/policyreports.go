@@ -0,0 +1,171 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyReportAPIVersion is the Policy Reports API version reports are written with
+const PolicyReportAPIVersion = "wgpolicyk8s.io/v1alpha2"
+
+const (
+	policyReportsResource        = "policyreports"
+	clusterPolicyReportsResource = "clusterpolicyreports"
+)
+
+// PolicyReport is a wgpolicyk8s.io PolicyReport, or a ClusterPolicyReport when it has no namespace
+// Only the fields the explorer writes are included, the Policy Reports API module isn't a dependency
+type PolicyReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Summary           PolicyReportSummary  `json:"summary"`
+	Results           []PolicyReportResult `json:"results,omitempty"`
+}
+
+// PolicyReportSummary counts a report's results by their result
+type PolicyReportSummary struct {
+	Pass  int `json:"pass"`
+	Fail  int `json:"fail"`
+	Warn  int `json:"warn"`
+	Error int `json:"error"`
+	Skip  int `json:"skip"`
+}
+
+// PolicyReportResult is a single finding in a policy report
+type PolicyReportResult struct {
+	Source     string                   `json:"source,omitempty"`
+	Policy     string                   `json:"policy"`
+	Rule       string                   `json:"rule,omitempty"`
+	Category   string                   `json:"category,omitempty"`
+	Severity   string                   `json:"severity,omitempty"`
+	Result     string                   `json:"result"`
+	Message    string                   `json:"message,omitempty"`
+	Properties map[string]string        `json:"properties,omitempty"`
+	Resources  []corev1.ObjectReference `json:"resources,omitempty"`
+	Timestamp  metav1.Timestamp         `json:"timestamp"`
+}
+
+type policyReportList struct {
+	Items []PolicyReport `json:"items"`
+}
+
+// ObjectReference returns a reference to an object by its kind, namespace and name, like the trivy-operator.resource.* labels of a report
+// The API version is filled in for the kinds Trivy Operator commonly reports on
+func ObjectReference(kind, namespace, name string) corev1.ObjectReference {
+	return corev1.ObjectReference{
+		APIVersion: objectResources[kind].apiVersion,
+		Kind:       kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+// GetPolicyReports lists the PolicyReports in all namespaces and the ClusterPolicyReports matching a label selector
+func GetPolicyReports(ctx context.Context, labelSelector string) ([]PolicyReport, error) {
+	var reports []PolicyReport
+	for _, resource := range []string{policyReportsResource, clusterPolicyReportsResource} {
+		var raw []byte
+		err := withRetry(ctx, func() error {
+			var err error
+			raw, err = coreClient.Get().
+				AbsPath(policyReportPath(resource, "", "")).
+				Param("labelSelector", labelSelector).
+				DoRaw(ctx)
+			return err
+		})
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%s aren't served, the %s CRDs must be installed: %w", resource, PolicyReportAPIVersion, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var list policyReportList
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", resource, err)
+		}
+		reports = append(reports, list.Items...)
+	}
+	return reports, nil
+}
+
+// CreatePolicyReport creates a PolicyReport, or a ClusterPolicyReport when it has no namespace
+func CreatePolicyReport(ctx context.Context, report PolicyReport) error {
+	body, err := encodePolicyReport(&report)
+	if err != nil {
+		return err
+	}
+	return coreClient.Post().
+		AbsPath(policyReportPath(policyReportResource(report), report.Namespace, "")).
+		SetHeader("Content-Type", "application/json").
+		Body(body).
+		Do(ctx).
+		Error()
+}
+
+// UpdatePolicyReport replaces an existing report, its resource version must be set to the version being replaced
+func UpdatePolicyReport(ctx context.Context, report PolicyReport) error {
+	body, err := encodePolicyReport(&report)
+	if err != nil {
+		return err
+	}
+	return coreClient.Put().
+		AbsPath(policyReportPath(policyReportResource(report), report.Namespace, report.Name)).
+		SetHeader("Content-Type", "application/json").
+		Body(body).
+		Do(ctx).
+		Error()
+}
+
+// DeletePolicyReport deletes a report, a report that's already gone isn't an error
+func DeletePolicyReport(ctx context.Context, report PolicyReport) error {
+	err := withRetry(ctx, func() error {
+		return coreClient.Delete().
+			AbsPath(policyReportPath(policyReportResource(report), report.Namespace, report.Name)).
+			Do(ctx).
+			Error()
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func policyReportResource(report PolicyReport) string {
+	if report.Namespace == "" {
+		return clusterPolicyReportsResource
+	}
+	return policyReportsResource
+}
+
+// policyReportPath returns the path of a report resource, listing every namespace when namespace and name are empty
+func policyReportPath(resource, namespace, name string) string {
+	path := "/apis/" + PolicyReportAPIVersion
+	if namespace != "" {
+		path += "/namespaces/" + url.PathEscape(namespace)
+	}
+	path += "/" + resource
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
+
+func encodePolicyReport(report *PolicyReport) ([]byte, error) {
+	report.APIVersion = PolicyReportAPIVersion
+	report.Kind = "PolicyReport"
+	if report.Namespace == "" {
+		report.Kind = "ClusterPolicyReport"
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s %s: %w", report.Kind, report.Name, err)
+	}
+	return body, nil
+}
@@ -9,13 +9,15 @@ import (
 const rbacAssessmentReportsResource = "rbacassessmentreports"
 
 // GetRbacAssessmentReportList retrieves all resources of type rbacassessmentreports in all namespaces.
-func GetRbacAssessmentReportList() (*v1alpha1.RbacAssessmentReportList, error) {
+func GetRbacAssessmentReportList(ctx context.Context) (*v1alpha1.RbacAssessmentReportList, error) {
 	var rbacList v1alpha1.RbacAssessmentReportList
-	err := client.
-		Get().
-		Resource(rbacAssessmentReportsResource).
-		Do(context.TODO()).
-		Into(&rbacList)
+	err := withRetry(ctx, func() error {
+		return client.
+			Get().
+			Resource(rbacAssessmentReportsResource).
+			Do(ctx).
+			Into(&rbacList)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -26,13 +28,15 @@ func GetRbacAssessmentReportList() (*v1alpha1.RbacAssessmentReportList, error) {
 const clusterRbacAssessmentReportsResource = "clusterrbacassessmentreports"
 
 // GetClusterRbacAssessmentReportList retrieves all resources of type clusterrbacassessmentreports in all namespaces.
-func GetClusterRbacAssessmentReportList() (*v1alpha1.ClusterRbacAssessmentReportList, error) {
+func GetClusterRbacAssessmentReportList(ctx context.Context) (*v1alpha1.ClusterRbacAssessmentReportList, error) {
 	var rbacList v1alpha1.ClusterRbacAssessmentReportList
-	err := client.
-		Get().
-		Resource(clusterRbacAssessmentReportsResource).
-		Do(context.TODO()).
-		Into(&rbacList)
+	err := withRetry(ctx, func() error {
+		return client.
+			Get().
+			Resource(clusterRbacAssessmentReportsResource).
+			Do(ctx).
+			Into(&rbacList)
+	})
 	if err != nil {
 		return nil, err
 	}
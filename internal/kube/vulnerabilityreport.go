@@ -9,13 +9,15 @@ import (
 const vulnerabilityReportsResource = "vulnerabilityreports"
 
 // GetVulnerabilityReportList retrieves all resources of type vulnerabilityreports in all namespaces.
-func GetVulnerabilityReportList() (*v1alpha1.VulnerabilityReportList, error) {
+func GetVulnerabilityReportList(ctx context.Context) (*v1alpha1.VulnerabilityReportList, error) {
 	var vulnList v1alpha1.VulnerabilityReportList
-	err := client.
-		Get().
-		Resource(vulnerabilityReportsResource).
-		Do(context.TODO()).
-		Into(&vulnList)
+	err := withRetry(ctx, func() error {
+		return client.
+			Get().
+			Resource(vulnerabilityReportsResource).
+			Do(ctx).
+			Into(&vulnList)
+	})
 	if err != nil {
 		return nil, err
 	}
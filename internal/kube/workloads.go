@@ -0,0 +1,124 @@
+package kube
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetOwnedReplicaSets returns the ReplicaSets in a namespace owned by a Deployment
+// Trivy Operator reports on a Deployment's ReplicaSets rather than the Deployment itself
+func GetOwnedReplicaSets(ctx context.Context, namespace, deployment string) ([]ResourceMetadata, error) {
+	var list appsv1.ReplicaSetList
+	err := withRetry(ctx, func() error {
+		return appsClient.Get().
+			Namespace(namespace).
+			Resource("replicasets").
+			VersionedParams(&metav1.ListOptions{}, metav1.ParameterCodec).
+			Do(ctx).
+			Into(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var replicaSets []ResourceMetadata
+	for _, rs := range list.Items {
+		for _, owner := range rs.OwnerReferences {
+			if owner.Kind == "Deployment" && owner.Name == deployment {
+				replicaSets = append(replicaSets, ResourceMetadata{Kind: "ReplicaSet", Name: rs.Name, Namespace: rs.Namespace})
+				break
+			}
+		}
+	}
+	return replicaSets, nil
+}
+
+// GetServiceAccountsForOwners returns the ServiceAccounts used by the Pods in a namespace owned by any of the given resources
+func GetServiceAccountsForOwners(ctx context.Context, namespace string, owners []ResourceMetadata) ([]string, error) {
+	var list corev1.PodList
+	err := withRetry(ctx, func() error {
+		return coreClient.Get().
+			Namespace(namespace).
+			Resource("pods").
+			VersionedParams(&metav1.ListOptions{}, metav1.ParameterCodec).
+			Do(ctx).
+			Into(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	serviceAccounts := make(map[string]struct{})
+	for _, pod := range list.Items {
+		for owner := range getImageResourceMetadata(pod) {
+			if !containsResource(owners, owner) {
+				continue
+			}
+
+			serviceAccount := pod.Spec.ServiceAccountName
+			if serviceAccount == "" {
+				serviceAccount = "default"
+			}
+			serviceAccounts[serviceAccount] = struct{}{}
+		}
+	}
+
+	var names []string
+	for name := range serviceAccounts {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// GetRolesForServiceAccount returns the names of the Roles bound to a ServiceAccount by RoleBindings in its namespace
+func GetRolesForServiceAccount(ctx context.Context, namespace, serviceAccount string) ([]string, error) {
+	var list rbacv1.RoleBindingList
+	err := withRetry(ctx, func() error {
+		return rbacClient.Get().
+			Namespace(namespace).
+			Resource("rolebindings").
+			VersionedParams(&metav1.ListOptions{}, metav1.ParameterCodec).
+			Do(ctx).
+			Into(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]struct{})
+	for _, binding := range list.Items {
+		if binding.RoleRef.Kind != "Role" {
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			// A ServiceAccount subject without a namespace refers to the binding's namespace
+			subjectNamespace := subject.Namespace
+			if subjectNamespace == "" {
+				subjectNamespace = binding.Namespace
+			}
+			if subject.Kind == rbacv1.ServiceAccountKind && subject.Name == serviceAccount && subjectNamespace == namespace {
+				roles[binding.RoleRef.Name] = struct{}{}
+				break
+			}
+		}
+	}
+
+	var names []string
+	for name := range roles {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func containsResource(resources []ResourceMetadata, r ResourceMetadata) bool {
+	for _, resource := range resources {
+		if resource == r {
+			return true
+		}
+	}
+	return false
+}
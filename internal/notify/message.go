@@ -0,0 +1,200 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kind is a kind of event
+type Kind string
+
+// Kinds of event
+const (
+	KindVulnerability     Kind = "vulnerability"
+	KindExposedSecret     Kind = "exposed_secret"
+	KindComplianceFailure Kind = "compliance_failure"
+	KindIgnoreExpiring    Kind = "ignore_expiring"
+	KindTest              Kind = "test"
+)
+
+func (k Kind) valid() bool {
+	switch k {
+	case KindVulnerability, KindExposedSecret, KindComplianceFailure, KindIgnoreExpiring, KindTest:
+		return true
+	}
+	return false
+}
+
+// Event is something worth notifying about, like a newly appearing finding
+type Event struct {
+	// Key identifies the event for deduplication, an event is only sent to a webhook once per key
+	Key      string `json:"key"`
+	Kind     Kind   `json:"kind"`
+	Severity string `json:"severity,omitempty"`
+	Title    string `json:"title"`
+	Detail   string `json:"detail,omitempty"`
+	// URL links to the event in the explorer, and is empty when no base URL is configured
+	URL string `json:"url,omitempty"`
+}
+
+// maxListedEvents is the most events listed in a single message, the rest are counted
+const maxListedEvents = 20
+
+// Message is the data a payload is rendered from, and the data custom webhook templates are rendered with
+type Message struct {
+	Title  string    `json:"title"`
+	Events []Event   `json:"events"`
+	SentAt time.Time `json:"sent_at"`
+	// Truncated is the number of events left out of Events to keep the message readable
+	Truncated int `json:"truncated,omitempty"`
+	// Text is a plain text summary of the events, a line per event
+	Text string `json:"-"`
+}
+
+func newMessage(title string, events []Event, sentAt time.Time) Message {
+	m := Message{
+		Title:  title,
+		Events: events,
+		SentAt: sentAt.UTC(),
+	}
+	if len(m.Events) > maxListedEvents {
+		m.Truncated = len(m.Events) - maxListedEvents
+		m.Events = m.Events[:maxListedEvents]
+	}
+
+	var lines []string
+	for _, e := range m.Events {
+		lines = append(lines, e.line())
+	}
+	if m.Truncated > 0 {
+		lines = append(lines, fmt.Sprintf("and %d more", m.Truncated))
+	}
+	m.Text = strings.Join(lines, "\n")
+	return m
+}
+
+// line returns the event as a line of plain text
+func (e Event) line() string {
+	line := e.Title
+	if e.Severity != "" {
+		line = fmt.Sprintf("[%s] %s", e.Severity, line)
+	}
+	if e.Detail != "" {
+		line += " - " + e.Detail
+	}
+	return line
+}
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value, so strings can be placed in a JSON template safely
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// payload renders the message in the webhook's format, or with its template if it has one
+func (w Webhook) payload(m Message) ([]byte, error) {
+	if w.template != nil {
+		var buf bytes.Buffer
+		if err := w.template.Execute(&buf, m); err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	switch w.Format {
+	case FormatSlack:
+		return json.Marshal(slackPayload(m))
+	case FormatTeams:
+		return json.Marshal(teamsPayload(m))
+	default:
+		return json.Marshal(m)
+	}
+}
+
+// slackPayload returns a Slack incoming webhook payload, using mrkdwn for severities and links
+func slackPayload(m Message) map[string]any {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+
+	lines := []string{"*" + escape(m.Title) + "*"}
+	for _, e := range m.Events {
+		title := escape(e.Title)
+		if e.URL != "" {
+			title = fmt.Sprintf("<%s|%s>", e.URL, title)
+		}
+		line := "• " + title
+		if e.Severity != "" {
+			line = fmt.Sprintf("• *%s* %s", escape(e.Severity), title)
+		}
+		if e.Detail != "" {
+			line += " - " + escape(e.Detail)
+		}
+		lines = append(lines, line)
+	}
+	if m.Truncated > 0 {
+		lines = append(lines, fmt.Sprintf("_and %d more_", m.Truncated))
+	}
+
+	return map[string]any{
+		"text": strings.Join(lines, "\n"),
+	}
+}
+
+// teamsPayload returns a Microsoft Teams workflow webhook payload, an Adaptive Card with a line per event
+func teamsPayload(m Message) map[string]any {
+	body := []map[string]any{
+		{
+			"type":   "TextBlock",
+			"text":   m.Title,
+			"weight": "Bolder",
+			"size":   "Medium",
+			"wrap":   true,
+		},
+	}
+	for _, e := range m.Events {
+		title := e.Title
+		if e.URL != "" {
+			title = fmt.Sprintf("[%s](%s)", e.Title, e.URL)
+		}
+		if e.Severity != "" {
+			title = fmt.Sprintf("**%s** %s", e.Severity, title)
+		}
+		if e.Detail != "" {
+			title += " - " + e.Detail
+		}
+		body = append(body, map[string]any{
+			"type":    "TextBlock",
+			"text":    title,
+			"wrap":    true,
+			"spacing": "Small",
+		})
+	}
+	if m.Truncated > 0 {
+		body = append(body, map[string]any{
+			"type":     "TextBlock",
+			"text":     fmt.Sprintf("and %d more", m.Truncated),
+			"isSubtle": true,
+			"wrap":     true,
+		})
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			},
+		},
+	}
+}
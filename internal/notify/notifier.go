@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// ErrRateLimited is returned when a webhook has already been sent its limit of messages in the last hour
+var ErrRateLimited = errors.New("webhook rate limit reached")
+
+// rateLimitWindow is the window the rate limit counts messages over
+const rateLimitWindow = time.Hour
+
+// Notifier posts messages to webhooks, limiting how many messages each webhook is sent
+type Notifier struct {
+	// rateLimit is the most messages sent to each webhook per hour, 0 for no limit
+	rateLimit int
+	client    *http.Client
+
+	mu   sync.Mutex
+	sent map[string][]time.Time
+}
+
+// New returns a notifier that sends each webhook at most rateLimit messages an hour, or any number of messages if rateLimit is 0
+func New(rateLimit int, timeout time.Duration) *Notifier {
+	return &Notifier{
+		rateLimit: rateLimit,
+		client:    &http.Client{Timeout: timeout},
+		sent:      make(map[string][]time.Time),
+	}
+}
+
+// Send posts the events to the webhook in a single message
+// ErrRateLimited is returned without sending anything if the webhook has reached its rate limit
+func (n *Notifier) Send(ctx context.Context, w Webhook, title string, events []Event) error {
+	now := time.Now()
+	if !n.allow(w.Name, now) {
+		return ErrRateLimited
+	}
+
+	body, err := w.payload(newMessage(title, events, now))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "trivy-operator-explorer")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Logger.Error("Failed to close webhook response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Include the start of the response, webhook services usually explain what was wrong with the payload
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
+
+// allow records a message to the webhook and returns true if it's within the rate limit
+func (n *Notifier) allow(webhook string, now time.Time) bool {
+	if n.rateLimit <= 0 {
+		return true
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// Forget messages sent before the window
+	recent := n.sent[webhook][:0]
+	for _, t := range n.sent[webhook] {
+		if now.Sub(t) < rateLimitWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= n.rateLimit {
+		n.sent[webhook] = recent
+		return false
+	}
+	n.sent[webhook] = append(recent, now)
+	return true
+}
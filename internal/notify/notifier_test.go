@@ -0,0 +1,265 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	log.Init("error")
+	os.Exit(m.Run())
+}
+
+// webhookServer records the bodies posted to it
+type webhookServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	bodies [][]byte
+}
+
+func newWebhookServer(t *testing.T) *webhookServer {
+	s := &webhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s request with content type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		s.mu.Lock()
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) received() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bodies
+}
+
+func parseWebhook(t *testing.T, name, url, format string) Webhook {
+	webhooks, err := ParseWebhooks(fmt.Sprintf("- name: %s\n  url: %s\n  format: %s\n", name, url, format), "LOW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhooks[0]
+}
+
+var testEvents = []Event{
+	{
+		Key:      "vulnerability/nginx/CVE-2024-0001",
+		Kind:     KindVulnerability,
+		Severity: "CRITICAL",
+		Title:    "CVE-2024-0001 in nginx:1.25",
+		Detail:   "openssl 3.0.1",
+		URL:      "https://explorer.example.com/cve?id=CVE-2024-0001",
+	},
+	{
+		Key:   "ignore_expiring/nginx/CVE-2024-0002",
+		Kind:  KindIgnoreExpiring,
+		Title: "Ignore for CVE-2024-0002 <nginx> expires soon",
+	},
+}
+
+func TestSendPayloads(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, body []byte)
+	}{
+		{
+			format: FormatSlack,
+			check: func(t *testing.T, body []byte) {
+				var payload struct {
+					Text string `json:"text"`
+				}
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Fatal(err)
+				}
+				want := strings.Join([]string{
+					"*New findings*",
+					"• *CRITICAL* <https://explorer.example.com/cve?id=CVE-2024-0001|CVE-2024-0001 in nginx:1.25> - openssl 3.0.1",
+					"• Ignore for CVE-2024-0002 &lt;nginx&gt; expires soon",
+				}, "\n")
+				if payload.Text != want {
+					t.Errorf("text = %q, want %q", payload.Text, want)
+				}
+			},
+		},
+		{
+			format: FormatTeams,
+			check: func(t *testing.T, body []byte) {
+				var payload struct {
+					Type        string `json:"type"`
+					Attachments []struct {
+						ContentType string `json:"contentType"`
+						Content     struct {
+							Type string `json:"type"`
+							Body []struct {
+								Text string `json:"text"`
+							} `json:"body"`
+						} `json:"content"`
+					} `json:"attachments"`
+				}
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Fatal(err)
+				}
+				if payload.Type != "message" || len(payload.Attachments) != 1 {
+					t.Fatalf("got a %q payload with %d attachments, want a message with 1", payload.Type, len(payload.Attachments))
+				}
+				card := payload.Attachments[0]
+				if card.ContentType != "application/vnd.microsoft.card.adaptive" || card.Content.Type != "AdaptiveCard" {
+					t.Errorf("got a %q attachment of type %q, want an Adaptive Card", card.ContentType, card.Content.Type)
+				}
+				var texts []string
+				for _, block := range card.Content.Body {
+					texts = append(texts, block.Text)
+				}
+				want := []string{
+					"New findings",
+					"**CRITICAL** [CVE-2024-0001 in nginx:1.25](https://explorer.example.com/cve?id=CVE-2024-0001) - openssl 3.0.1",
+					"Ignore for CVE-2024-0002 <nginx> expires soon",
+				}
+				if strings.Join(texts, "\n") != strings.Join(want, "\n") {
+					t.Errorf("text blocks = %q, want %q", texts, want)
+				}
+			},
+		},
+		{
+			format: FormatJSON,
+			check: func(t *testing.T, body []byte) {
+				var m Message
+				if err := json.Unmarshal(body, &m); err != nil {
+					t.Fatal(err)
+				}
+				if m.Title != "New findings" || m.Truncated != 0 || m.SentAt.IsZero() {
+					t.Errorf("got title %q, truncated %d and sent at %s", m.Title, m.Truncated, m.SentAt)
+				}
+				if len(m.Events) != len(testEvents) {
+					t.Fatalf("got %d events, want %d", len(m.Events), len(testEvents))
+				}
+				for i := range testEvents {
+					if m.Events[i] != testEvents[i] {
+						t.Errorf("event %d = %+v, want %+v", i, m.Events[i], testEvents[i])
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			server := newWebhookServer(t)
+			n := New(0, time.Second)
+			if err := n.Send(context.Background(), parseWebhook(t, "test", server.URL, tt.format), "New findings", testEvents); err != nil {
+				t.Fatal(err)
+			}
+			bodies := server.received()
+			if len(bodies) != 1 {
+				t.Fatalf("webhook was sent %d messages, want 1", len(bodies))
+			}
+			tt.check(t, bodies[0])
+		})
+	}
+}
+
+func TestSendTruncatesEvents(t *testing.T) {
+	var events []Event
+	for i := 0; i < maxListedEvents+5; i++ {
+		events = append(events, Event{Key: fmt.Sprint(i), Kind: KindVulnerability, Severity: "HIGH", Title: fmt.Sprintf("CVE-2024-%04d", i)})
+	}
+
+	server := newWebhookServer(t)
+	n := New(0, time.Second)
+	if err := n.Send(context.Background(), parseWebhook(t, "json", server.URL, FormatJSON), "New findings", events); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), parseWebhook(t, "slack", server.URL, FormatSlack), "New findings", events); err != nil {
+		t.Fatal(err)
+	}
+	bodies := server.received()
+	if len(bodies) != 2 {
+		t.Fatalf("webhook was sent %d messages, want 2", len(bodies))
+	}
+
+	var m Message
+	if err := json.Unmarshal(bodies[0], &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Events) != maxListedEvents || m.Truncated != 5 {
+		t.Errorf("got %d events with %d truncated, want %d with 5 truncated", len(m.Events), m.Truncated, maxListedEvents)
+	}
+
+	var slack struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(bodies[1], &slack); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(slack.Text, "\n")
+	// The title, the listed events and the count of the rest
+	if len(lines) != maxListedEvents+2 || lines[len(lines)-1] != "_and 5 more_" {
+		t.Errorf("got %d lines ending with %q, want %d ending with %q", len(lines), lines[len(lines)-1], maxListedEvents+2, "_and 5 more_")
+	}
+}
+
+func TestSendRateLimit(t *testing.T) {
+	server := newWebhookServer(t)
+	n := New(2, time.Second)
+	limited := parseWebhook(t, "limited", server.URL, FormatJSON)
+
+	for i := 0; i < 2; i++ {
+		if err := n.Send(context.Background(), limited, "New findings", testEvents); err != nil {
+			t.Fatalf("message %d: %s", i+1, err)
+		}
+	}
+	if err := n.Send(context.Background(), limited, "New findings", testEvents); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got error %v, want ErrRateLimited", err)
+	}
+	if got := len(server.received()); got != 2 {
+		t.Errorf("webhook was sent %d messages, want 2", got)
+	}
+
+	// Each webhook has its own limit
+	if err := n.Send(context.Background(), parseWebhook(t, "other", server.URL, FormatJSON), "New findings", testEvents); err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages older than the window no longer count
+	n.mu.Lock()
+	for i := range n.sent[limited.Name] {
+		n.sent[limited.Name][i] = n.sent[limited.Name][i].Add(-rateLimitWindow)
+	}
+	n.mu.Unlock()
+	if err := n.Send(context.Background(), limited, "New findings", testEvents); err != nil {
+		t.Fatalf("got error %v after the window passed", err)
+	}
+}
+
+func TestSendErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := New(0, time.Second).Send(context.Background(), parseWebhook(t, "test", server.URL, FormatSlack), "New findings", testEvents)
+	if err == nil || !strings.Contains(err.Error(), "status 400: invalid_payload") {
+		t.Errorf("got error %v, want the status and response", err)
+	}
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Payload formats supported by webhooks
+const (
+	FormatSlack = "slack"
+	FormatTeams = "teams"
+	FormatJSON  = "json"
+)
+
+// Webhook is a destination for notifications
+type Webhook struct {
+	// Name identifies the webhook in logs and in the record of which events it has been sent, so it should not be changed once in use
+	Name string `json:"name"`
+	// URL is where payloads are posted to
+	URL string `json:"url"`
+	// Format is the payload format, one of slack, teams or json, and defaults to json
+	Format string `json:"format,omitempty"`
+	// MinSeverity is the lowest severity of finding sent to the webhook, and defaults to the notifier's minimum severity
+	MinSeverity string `json:"minSeverity,omitempty"`
+	// Kinds limits the webhook to some kinds of event, every kind is sent when empty
+	Kinds []Kind `json:"kinds,omitempty"`
+	// Template optionally replaces the format's payload with a Go template rendered with a Message
+	Template string `json:"template,omitempty"`
+
+	template *template.Template
+}
+
+// Webhooks is a list of webhooks
+type Webhooks []Webhook
+
+// ParseWebhooks parses a YAML or JSON list of webhooks, defaulting their minimum severity to minSeverity
+func ParseWebhooks(raw, minSeverity string) (Webhooks, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	if _, ok := severityRank[strings.ToUpper(minSeverity)]; !ok {
+		return nil, fmt.Errorf("minimum severity %q must be one of CRITICAL, HIGH, MEDIUM, LOW or UNKNOWN", minSeverity)
+	}
+
+	var webhooks Webhooks
+	if err := yaml.UnmarshalStrict([]byte(raw), &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(webhooks))
+	for i := range webhooks {
+		w := &webhooks[i]
+		if w.Name == "" {
+			return nil, fmt.Errorf("webhook %d has no name", i+1)
+		}
+		if _, ok := names[w.Name]; ok {
+			return nil, fmt.Errorf("webhook name %q is used more than once", w.Name)
+		}
+		names[w.Name] = struct{}{}
+
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook %s has an invalid URL, it must be an absolute http or https URL", w.Name)
+		}
+
+		if w.Format == "" {
+			w.Format = FormatJSON
+		}
+		if w.Format != FormatSlack && w.Format != FormatTeams && w.Format != FormatJSON {
+			return nil, fmt.Errorf("webhook %s has unknown format %q, must be one of slack, teams or json", w.Name, w.Format)
+		}
+
+		if w.MinSeverity == "" {
+			w.MinSeverity = minSeverity
+		}
+		w.MinSeverity = strings.ToUpper(w.MinSeverity)
+		if _, ok := severityRank[w.MinSeverity]; !ok {
+			return nil, fmt.Errorf("webhook %s has unknown minimum severity %q", w.Name, w.MinSeverity)
+		}
+
+		for _, kind := range w.Kinds {
+			if !kind.valid() {
+				return nil, fmt.Errorf("webhook %s has unknown event kind %q", w.Name, kind)
+			}
+		}
+
+		if w.Template != "" {
+			w.template, err = template.New(w.Name).Funcs(templateFuncs).Parse(w.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s has an invalid template: %w", w.Name, err)
+			}
+		}
+	}
+	return webhooks, nil
+}
+
+// Accepts returns true if the event should be sent to the webhook
+// Events without a severity, like expiring ignores, aren't held back by the minimum severity
+func (w Webhook) Accepts(e Event) bool {
+	if len(w.Kinds) > 0 {
+		found := false
+		for _, kind := range w.Kinds {
+			if kind == e.Kind {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if e.Severity == "" {
+		return true
+	}
+	return severityRank[strings.ToUpper(e.Severity)] >= severityRank[w.MinSeverity]
+}
+
+var severityRank = map[string]int{
+	"UNKNOWN":  0,
+	"LOW":      1,
+	"MEDIUM":   2,
+	"HIGH":     3,
+	"CRITICAL": 4,
+}
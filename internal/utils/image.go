@@ -33,3 +33,49 @@ func AssembleImageFullName(registry, repo, tag, digest string) string {
 	}
 	return fmt.Sprintf("%s/%s%s", registry, repo, imageSuffix)
 }
+
+// ParseImageReference splits an image reference like "ghcr.io/org/app:1.0" or "nginx@sha256:abc" into its pretty registry, repository, tag and digest
+// Docker Hub references may leave out the registry or the library/ prefix, they're returned as FormatPrettyImageRegistry and FormatPrettyImageRepo would
+func ParseImageReference(ref string) (registry, repo, tag, digest string) {
+	ref = strings.TrimSpace(ref)
+	if name, d, found := strings.Cut(ref, "@"); found {
+		ref = name
+		digest = d
+	}
+
+	// A colon after the last slash separates the tag, colons before it belong to a registry port
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		tag = ref[i+1:]
+		ref = ref[:i]
+	}
+
+	// The first path component is a registry if it looks like a host, otherwise the image is on Docker Hub
+	if first, rest, found := strings.Cut(ref, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		registry = first
+		ref = rest
+	}
+	if registry == "docker.io" {
+		registry = "index.docker.io"
+	}
+
+	return FormatPrettyImageRegistry(registry), FormatPrettyImageRepo(ref), tag, digest
+}
+
+// ImageReferenceMatches returns true if an image's pretty registry, repository, tag and digest are named by the image reference
+// A reference without a tag or digest names the latest tag, like a container runtime would
+func ImageReferenceMatches(ref, registry, repo, tag, digest string) bool {
+	refRegistry, refRepo, refTag, refDigest := ParseImageReference(ref)
+	if refTag == "" && refDigest == "" {
+		refTag = "latest"
+	}
+	if refRegistry != registry || refRepo != repo {
+		return false
+	}
+	if refTag != "" && refTag != tag {
+		return false
+	}
+	if refDigest != "" && refDigest != digest {
+		return false
+	}
+	return true
+}
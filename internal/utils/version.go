@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CompareVersions compares two package version strings, returning -1, 0 or 1 if a is less than, equal to, or greater than b
+// Package versions come from many ecosystems (deb, apk, rpm, npm, and so on) so this is a best-effort comparison
+// in the style of rpmvercmp: an optional numeric epoch ("1:"), then runs of digits compared numerically and runs of
+// letters compared lexically, where digits sort after letters and a tilde sorts before anything, even the end of the version
+// Common pre-release labels like "-rc1" also sort before the end of the version, so 1.0.0-rc1 is less than 1.0.0
+func CompareVersions(a, b string) int {
+	epochA, a := splitEpoch(strings.TrimSpace(a))
+	epochB, b := splitEpoch(strings.TrimSpace(b))
+	if epochA != epochB {
+		if epochA < epochB {
+			return -1
+		}
+		return 1
+	}
+
+	segmentsA := versionSegments(a)
+	segmentsB := versionSegments(b)
+	for i := 0; i < len(segmentsA) || i < len(segmentsB); i++ {
+		if i >= len(segmentsA) {
+			// a ran out first, it's only greater if b continues with a pre-release
+			if isPreRelease(segmentsB[i]) {
+				return 1
+			}
+			return -1
+		}
+		if i >= len(segmentsB) {
+			if isPreRelease(segmentsA[i]) {
+				return -1
+			}
+			return 1
+		}
+		if c := compareSegments(segmentsA[i], segmentsB[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// preReleaseLabels are the letter segments that mark a pre-release when a version continues past the end of another
+var preReleaseLabels = map[string]struct{}{
+	"alpha":    {},
+	"beta":     {},
+	"rc":       {},
+	"pre":      {},
+	"preview":  {},
+	"dev":      {},
+	"snapshot": {},
+}
+
+// isPreRelease returns true if a segment starts a pre-release, so a version continuing with it is less than one that ends
+func isPreRelease(segment string) bool {
+	if segment == "~" {
+		return true
+	}
+	_, ok := preReleaseLabels[strings.ToLower(segment)]
+	return ok
+}
+
+// splitEpoch splits a leading numeric epoch like "1:" off of a version
+func splitEpoch(v string) (int, string) {
+	before, after, found := strings.Cut(v, ":")
+	if !found {
+		return 0, v
+	}
+	epoch, err := strconv.Atoi(before)
+	if err != nil {
+		return 0, v
+	}
+	return epoch, after
+}
+
+// versionSegments splits a version into runs of digits, runs of letters, and tildes, dropping all other separators
+func versionSegments(v string) []string {
+	var segments []string
+	var current strings.Builder
+	var currentIsDigit bool
+
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range v {
+		switch {
+		case r == '~':
+			flush()
+			segments = append(segments, "~")
+		case unicode.IsDigit(r) || unicode.IsLetter(r):
+			isDigit := unicode.IsDigit(r)
+			if current.Len() > 0 && isDigit != currentIsDigit {
+				flush()
+			}
+			currentIsDigit = isDigit
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return segments
+}
+
+// compareSegments compares two segments of a version
+func compareSegments(a, b string) int {
+	if a == b {
+		return 0
+	}
+	// A tilde sorts before anything
+	if a == "~" {
+		return -1
+	}
+	if b == "~" {
+		return 1
+	}
+
+	aFirst, _ := utf8.DecodeRuneInString(a)
+	bFirst, _ := utf8.DecodeRuneInString(b)
+	aIsDigit := unicode.IsDigit(aFirst)
+	bIsDigit := unicode.IsDigit(bFirst)
+	switch {
+	case aIsDigit && bIsDigit:
+		// Compare numerically without parsing, so arbitrarily long numbers work
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	case aIsDigit:
+		return 1
+	case bIsDigit:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		// Equal versions
+		{"", "", 0},
+		{"1.2.3", "1.2.3", 0},
+		{" 1.2.3 ", "1.2.3", 0},
+		{"1.02", "1.2", 0},
+		{"1.2-3", "1.2.3", 0},
+
+		// Empty strings sort first
+		{"", "0", -1},
+		{"1.0", "", 1},
+
+		// Numeric rather than lexical order
+		{"1.10", "1.9", 1},
+		{"1.9", "1.10", -1},
+		{"2.0", "10.0", -1},
+		{"1.0.123456789012345678901234567890", "1.0.99", 1},
+
+		// Segments of different lengths
+		{"1.0", "1.0.0", -1},
+		{"1.2.3", "1.2", 1},
+		{"1.2.3-r1", "1.2.3", 1},
+		{"1.0-1ubuntu1", "1.0", 1},
+
+		// Epochs outrank the rest of the version
+		{"1:1.0", "2.0", 1},
+		{"2.0", "1:1.0", -1},
+		{"1:1.0", "2:0.1", -1},
+		{"0:1.0", "1.0", 0},
+
+		// Pre-releases sort before the release
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0", "1.0.0-rc1", 1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"1.0.0-rc1", "1.0.0-rc2", -1},
+		{"1.0.0~rc1", "1.0.0", -1},
+		{"1.0~", "1.0", -1},
+
+		// Letters sort before digits
+		{"1.0a", "1.0.1", -1},
+		{"1.0.a", "1.0.1", -1},
+
+		// Segments are classified by their first rune rather than byte, so a non-ASCII digit still sorts after letters
+		{"1.١", "1.ａ", 1},
+		{"1.ａ", "1.١", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_vs_"+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
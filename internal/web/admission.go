@@ -0,0 +1,107 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/admission"
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// AdmissionConfig contains the settings for the validating admission webhook
+type AdmissionConfig struct {
+	// Mode is one of off, audit or enforce
+	Mode string
+	// Port is the port the webhook is served on, the API server only calls webhooks over TLS
+	Port string
+	// TLSCertFile and TLSKeyFile are the webhook's serving certificate, the files are reloaded when they change
+	TLSCertFile string
+	TLSKeyFile  string
+	// ExemptNamespaces are namespaces whose objects are always admitted
+	ExemptNamespaces []string
+	// Interval is how often the vulnerability reports are reloaded
+	Interval time.Duration
+}
+
+// admissionPath is the path the webhook is served at
+const admissionPath = "/validate"
+
+// newAdmissionReviewer returns the reviewer for the webhook, or nil if the webhook is off
+func newAdmissionReviewer(config AdmissionConfig, externalURL string) (*admission.Reviewer, error) {
+	if config.Mode == admission.ModeOff {
+		return nil, nil
+	}
+	reviewer, err := admission.NewReviewer(admission.Config{
+		Mode:             config.Mode,
+		ExemptNamespaces: config.ExemptNamespaces,
+		ExternalURL:      externalURL,
+	}, ignoredCVEs)
+	if err != nil {
+		return nil, err
+	}
+	if config.Port == "" {
+		return nil, fmt.Errorf("admission webhook port must be set")
+	}
+	if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+		return nil, fmt.Errorf("both an admission webhook TLS certificate and key file are required, the API server only calls webhooks over TLS")
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("admission webhook interval must be greater than 0, got %s", config.Interval)
+	}
+	return reviewer, nil
+}
+
+// ignoredCVEs returns the CVEs ignored for an image, for the admission webhook
+func ignoredCVEs(registry, repository, tag string) (map[string]struct{}, error) {
+	ignores, err := db.GetIgnoredCVEsForImage(registry, repository, tag)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]struct{}, len(ignores))
+	for id := range ignores {
+		ids[id] = struct{}{}
+	}
+	return ids, nil
+}
+
+// admissionLoader periodically loads the vulnerability reports the webhook reviews objects against
+// Loading them ahead of time keeps reviews fast, the API server gives webhooks a few seconds at most
+type admissionLoader struct {
+	reviewer *admission.Reviewer
+	interval time.Duration
+}
+
+// run loads the reports on the configured interval until the context is done
+func (l *admissionLoader) run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.load(ctx); err != nil {
+			log.Logger.Error("error loading vulnerability reports for the admission webhook, the previously loaded reports are used until the next interval", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *admissionLoader) load(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting vulnerability reports: %w", err)
+	}
+	images := admission.NewImages(data)
+	l.reviewer.SetImages(images)
+	log.Logger.Debug("loaded vulnerability reports for the admission webhook", "images", len(images))
+	return nil
+}
@@ -0,0 +1,225 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+const (
+	// AuthModeNone disables authentication, every request is anonymous
+	AuthModeNone = "none"
+	// AuthModeProxy trusts identity headers set by a reverse proxy in front of the explorer
+	AuthModeProxy = "proxy"
+)
+
+// AuthConfig contains the authentication settings for the webserver
+type AuthConfig struct {
+	// Mode is one of "none" or "proxy"
+	Mode string
+	// ProxyUserHeader is the request header containing the username set by the reverse proxy
+	ProxyUserHeader string
+	// ProxyEmailHeader is the optional request header containing the user's email set by the reverse proxy
+	ProxyEmailHeader string
+	// ProxyTrustedCIDRs are the networks the reverse proxy connects from. Identity headers from any other address are rejected
+	ProxyTrustedCIDRs []string
+	// ProxyAdmins are the usernames granted the admin scope, which allows managing every user's API tokens
+	ProxyAdmins []string
+}
+
+// sessionScopes are the scopes granted to users signed in through the proxy, admins are granted db.ScopeAdmin as well
+var sessionScopes = []string{db.ScopeRead, db.ScopeIgnoreWrite}
+
+// User contains data about the user making a request
+type User struct {
+	Name  string
+	Email string
+	// Scopes granted by the API token used in this request, or the session scopes for users signed in through the proxy
+	Scopes []string
+	// APIToken is true if the request was authenticated with an API token
+	APIToken bool
+}
+
+// HasScope returns true if the user was granted the given scope
+func (u User) HasScope(scope string) bool {
+	for _, s := range u.Scopes {
+		if s == scope || s == db.ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// authenticator resolves the user making a request from the configured auth mode
+type authenticator struct {
+	config   AuthConfig
+	prefixes []netip.Prefix
+	admins   map[string]struct{}
+}
+
+func newAuthenticator(config AuthConfig) (*authenticator, error) {
+	a := &authenticator{config: config}
+
+	switch config.Mode {
+	case "", AuthModeNone:
+		a.config.Mode = AuthModeNone
+		if len(config.ProxyAdmins) > 0 {
+			return nil, fmt.Errorf("admins can only be configured in auth mode %q, anonymous users can't be told apart", AuthModeProxy)
+		}
+	case AuthModeProxy:
+		if config.ProxyUserHeader == "" {
+			return nil, fmt.Errorf("auth mode %q requires a proxy user header", AuthModeProxy)
+		}
+		if len(config.ProxyTrustedCIDRs) == 0 {
+			return nil, fmt.Errorf("auth mode %q requires at least one trusted proxy CIDR", AuthModeProxy)
+		}
+		for _, cidr := range config.ProxyTrustedCIDRs {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy CIDR %q: %w", cidr, err)
+			}
+			a.prefixes = append(a.prefixes, prefix.Masked())
+		}
+		a.admins = make(map[string]struct{}, len(config.ProxyAdmins))
+		for _, admin := range config.ProxyAdmins {
+			a.admins[strings.TrimSpace(admin)] = struct{}{}
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q, must be one of %s, %s", config.Mode, AuthModeNone, AuthModeProxy)
+	}
+
+	return a, nil
+}
+
+// middleware rejects unauthenticated requests and stores the requesting user in the request context
+// API tokens passed as a bearer token are accepted in every auth mode, from any address
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			t, found, err := db.AuthenticateAPIToken(token)
+			if err != nil {
+				log.Logger.Error("error authenticating api token", "error", err)
+				http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				log.Logger.Warn("rejecting request with invalid or revoked api token", "remote", r.RemoteAddr, "path", r.URL.Path)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			user := User{
+				Name:     fmt.Sprintf("token:%s", t.Name),
+				Scopes:   t.ScopeList(),
+				APIToken: true,
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+			return
+		}
+
+		if a.config.Mode == AuthModeNone {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !a.isTrustedProxy(r.RemoteAddr) {
+			log.Logger.Warn("rejecting request from untrusted proxy address", "remote", r.RemoteAddr, "path", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		name := strings.TrimSpace(r.Header.Get(a.config.ProxyUserHeader))
+		if name == "" {
+			log.Logger.Warn("rejecting request missing proxy user header", "header", a.config.ProxyUserHeader, "path", r.URL.Path)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user := User{Name: name, Scopes: sessionScopes}
+		if _, ok := a.admins[name]; ok {
+			user.Scopes = append(append([]string(nil), sessionScopes...), db.ScopeAdmin)
+		}
+		if a.config.ProxyEmailHeader != "" {
+			user.Email = strings.TrimSpace(r.Header.Get(a.config.ProxyEmailHeader))
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	})
+}
+
+func (a *authenticator) isTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range a.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// requireScope wraps a handler, rejecting requests made with an API token that was not granted the given scope
+func requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := userFromRequest(r); ok && !user.HasScope(scope) {
+			log.Logger.Warn("rejecting request missing required token scope", "user", user.Name, "scope", scope, "path", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireTokenManagement wraps the API token management handlers
+// Users signed in through the proxy manage their own tokens, while API tokens need the admin scope so a leaked token can't be used to mint more
+func requireTokenManagement(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := userFromRequest(r); ok && user.APIToken && !user.HasScope(db.ScopeAdmin) {
+			log.Logger.Warn("rejecting token management request from an api token without the admin scope", "user", user.Name, "path", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// bearerToken returns the token from the request's Authorization header, and false if there isn't one
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// userFromRequest returns the user that made the request, and false if the request is anonymous
+func userFromRequest(r *http.Request) (User, bool) {
+	user, ok := r.Context().Value(userContextKey).(User)
+	return user, ok
+}
+
+// usernameFromRequest returns the name of the user that made the request, or an empty string if anonymous
+func usernameFromRequest(r *http.Request) string {
+	user, _ := userFromRequest(r)
+	return user.Name
+}
@@ -1,11 +1,11 @@
 package content
 
-import "embed"
+import "io/fs"
 
-// Static contains an embedded filesystem
-var Static embed.FS
+// Static contains the web package content, an embedded filesystem in the binary
+var Static fs.FS
 
-// Init accepts an embedded filesystem for the web package content
-func Init(fs embed.FS) {
-	Static = fs
+// Init accepts a filesystem for the web package content
+func Init(static fs.FS) {
+	Static = static
 }
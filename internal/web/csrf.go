@@ -0,0 +1,166 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+const (
+	csrfCookieName = "trivy_explorer_csrf"
+	csrfHeaderName = "X-CSRF-Token"
+	csrfFormField  = "csrf_token"
+
+	csrfContextKey contextKey = "csrf"
+)
+
+// csrfProtection guards browser sessions against cross-site request forgery
+// It uses a double-submit token: a random token is stored in a SameSite cookie and must be echoed back
+// in a header or form field on every mutating request, which a cross-site page can not read or forge
+type csrfProtection struct {
+	// trustedOrigins are additional origins (scheme://host[:port]) allowed to make mutating requests
+	// Requests are always allowed from the same host they were sent to
+	trustedOrigins map[string]struct{}
+}
+
+func newCSRFProtection(trustedOrigins []string) *csrfProtection {
+	c := &csrfProtection{trustedOrigins: make(map[string]struct{}, len(trustedOrigins))}
+	for _, origin := range trustedOrigins {
+		c.trustedOrigins[strings.TrimSuffix(strings.ToLower(origin), "/")] = struct{}{}
+	}
+	return c
+}
+
+// middleware ensures every browser has a CSRF token cookie, and validates the token and request origin on mutating requests
+// Requests authenticated by API bearer tokens are not cookie based, so they're exempt
+func (c *csrfProtection) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerToken(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := ""
+		if cookie, err := r.Cookie(csrfCookieName); err == nil && len(cookie.Value) == 64 {
+			token = cookie.Value
+		}
+
+		if isMutatingMethod(r.Method) {
+			if !c.isAllowedOrigin(r) {
+				log.Logger.Warn("rejecting cross-origin request", "origin", r.Header.Get("Origin"), "referer", r.Header.Get("Referer"), "path", r.URL.Path)
+				http.Error(w, "Forbidden, cross-origin request", http.StatusForbidden)
+				return
+			}
+
+			submitted := r.Header.Get(csrfHeaderName)
+			if submitted == "" && isFormContentType(r.Header.Get("Content-Type")) {
+				submitted = r.PostFormValue(csrfFormField)
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(submitted)) != 1 {
+				log.Logger.Warn("rejecting request with missing or invalid CSRF token", "path", r.URL.Path)
+				http.Error(w, "Forbidden, invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+
+		if token == "" {
+			b := make([]byte, 32)
+			if _, err := rand.Read(b); err != nil {
+				log.Logger.Error("failed to generate CSRF token", "error", err)
+				http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+				return
+			}
+			token = hex.EncodeToString(b)
+			http.SetCookie(w, &http.Cookie{
+				Name:     csrfCookieName,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   isHTTPS(r),
+				SameSite: http.SameSiteStrictMode,
+			})
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfContextKey, token)))
+	})
+}
+
+// isAllowedOrigin checks the Origin header, falling back to the Referer header, against the request's host and the trusted origins
+// Requests with neither header are rejected, every browser we support sends at least one on mutating requests
+func (c *csrfProtection) isAllowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		referer := r.Header.Get("Referer")
+		if referer == "" {
+			return false
+		}
+		origin = referer
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	_, ok := c.trustedOrigins[strings.ToLower(u.Scheme+"://"+u.Host)]
+	return ok
+}
+
+// csrfTokenFromRequest returns the CSRF token for the request's browser session, or an empty string for API token requests
+func csrfTokenFromRequest(r *http.Request) string {
+	token, _ := r.Context().Value(csrfContextKey).(string)
+	return token
+}
+
+// requireContentType wraps a handler for a mutating route, rejecting mutating requests that aren't of the given media type
+func requireContentType(contentType string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isMutatingMethod(r.Method) {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != contentType {
+				http.Error(w, fmt.Sprintf("Unsupported Media Type, expected %s", contentType), http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// limitBody limits the size of mutating request bodies, reads past the limit return an *http.MaxBytesError
+func limitBody(maxBodyBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isMutatingMethod(r.Method) {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+func isFormContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
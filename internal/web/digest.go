@@ -0,0 +1,416 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/email"
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/web/content"
+	changesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/changes"
+	complianceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/compliance"
+	digestview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/digest"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+)
+
+// DigestConfig contains the settings for emailing scheduled digests
+type DigestConfig struct {
+	// Recipients is a YAML or JSON list of digest recipients, digests are disabled when it's empty
+	Recipients string
+	// Schedule is when digests are sent, like "Mon 08:00" or "daily 08:00" in the server's time zone
+	Schedule string
+	SMTP     email.Config
+}
+
+// digests sends the scheduled digests, it's set from the server config and nil when there are no recipients
+var digests *digester
+
+// digestCheckInterval is how often the schedule is checked for digests that are due
+const digestCheckInterval = time.Minute
+
+// digestRetryInterval is how long a digest that failed to send waits before it's retried, so a broken SMTP server isn't retried every check
+const digestRetryInterval = 15 * time.Minute
+
+// digestSchedule is a daily or weekly time of day
+type digestSchedule struct {
+	daily   bool
+	weekday time.Weekday
+	hour    int
+	minute  int
+}
+
+// parseDigestSchedule parses a schedule like "Mon 08:00", "monday 08:00" or "daily 08:00"
+func parseDigestSchedule(raw string) (digestSchedule, error) {
+	var s digestSchedule
+	fields := strings.Fields(raw)
+	if len(fields) != 2 {
+		return s, fmt.Errorf("digest schedule %q must be a day and a time, like \"Mon 08:00\" or \"daily 08:00\"", raw)
+	}
+
+	day := strings.ToLower(fields[0])
+	if day == "daily" {
+		s.daily = true
+	} else {
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			name := strings.ToLower(d.String())
+			if day == name || day == name[:3] {
+				s.weekday = d
+				found = true
+				break
+			}
+		}
+		if !found {
+			return s, fmt.Errorf("digest schedule day %q must be daily or a day of the week", fields[0])
+		}
+	}
+
+	clock, err := time.Parse("15:04", fields[1])
+	if err != nil {
+		return s, fmt.Errorf("digest schedule time %q must be in 24 hour HH:MM format", fields[1])
+	}
+	s.hour, s.minute = clock.Hour(), clock.Minute()
+	return s, nil
+}
+
+// previous returns the latest scheduled time at or before now
+func (s digestSchedule) previous(now time.Time) time.Time {
+	t := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, now.Location())
+	if s.daily {
+		if t.After(now) {
+			t = t.AddDate(0, 0, -1)
+		}
+		return t
+	}
+
+	t = t.AddDate(0, 0, -((int(t.Weekday()) - int(s.weekday) + 7) % 7))
+	if t.After(now) {
+		t = t.AddDate(0, 0, -7)
+	}
+	return t
+}
+
+// next returns the first scheduled time after now
+func (s digestSchedule) next(now time.Time) time.Time {
+	if s.daily {
+		return s.previous(now).AddDate(0, 0, 1)
+	}
+	return s.previous(now).AddDate(0, 0, 7)
+}
+
+// String formats the schedule for logs
+func (s digestSchedule) String() string {
+	day := "daily"
+	if !s.daily {
+		day = s.weekday.String()
+	}
+	return fmt.Sprintf("%s %02d:%02d", day, s.hour, s.minute)
+}
+
+// digester sends each recipient a digest when one is due on the schedule
+type digester struct {
+	recipients digestview.Recipients
+	schedule   digestSchedule
+	sender     *email.Sender
+	baseURL    string
+
+	mu     sync.Mutex
+	failed map[string]time.Time
+}
+
+func newDigester(config DigestConfig, baseURL string) (*digester, error) {
+	recipients, err := digestview.ParseRecipients(config.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+
+	schedule, err := parseDigestSchedule(config.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := email.New(config.SMTP)
+	if err != nil {
+		return nil, err
+	}
+
+	return &digester{
+		recipients: recipients,
+		schedule:   schedule,
+		sender:     sender,
+		baseURL:    baseURL,
+		failed:     make(map[string]time.Time),
+	}, nil
+}
+
+// run checks for due digests until the context is done
+func (d *digester) run(ctx context.Context) {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		d.check(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check sends the digests that are due
+// A digest is due when the last one was sent before the latest scheduled time, so a digest missed while the server was down is sent once it's back
+// New recipients are recorded without being sent a digest, and get their first one on the schedule
+func (d *digester) check(ctx context.Context, now time.Time) {
+	due := d.schedule.previous(now)
+
+	for _, recipient := range d.recipients {
+		sentAt, found, err := db.GetDigestSentAt(recipient.Name)
+		if err != nil {
+			log.Logger.Error("error getting when the last digest was sent", "recipient", recipient.Name, "error", err)
+			continue
+		}
+		if !found {
+			if err := db.SetDigestSentAt(recipient.Name, now); err != nil {
+				log.Logger.Error("error recording new digest recipient", "recipient", recipient.Name, "error", err)
+				continue
+			}
+			log.Logger.Info("new digest recipient, its first digest will be sent on the schedule", "recipient", recipient.Name, "next", d.schedule.next(now))
+			continue
+		}
+		if !sentAt.Before(due) {
+			continue
+		}
+
+		d.mu.Lock()
+		failedAt, failed := d.failed[recipient.Name]
+		d.mu.Unlock()
+		if failed && now.Sub(failedAt) < digestRetryInterval {
+			continue
+		}
+
+		if err := d.send(ctx, recipient, sentAt, now); err != nil {
+			log.Logger.Error("error sending digest, it will be retried", "recipient", recipient.Name, "retry_in", digestRetryInterval, "error", err)
+			d.mu.Lock()
+			d.failed[recipient.Name] = now
+			d.mu.Unlock()
+			continue
+		}
+
+		d.mu.Lock()
+		delete(d.failed, recipient.Name)
+		d.mu.Unlock()
+		if err := db.SetDigestSentAt(recipient.Name, now); err != nil {
+			log.Logger.Error("error recording when the digest was sent, the next digest may repeat its changes", "recipient", recipient.Name, "error", err)
+		}
+		log.Logger.Info("sent digest", "recipient", recipient.Name, "to", len(recipient.To), "next", d.schedule.next(now))
+	}
+}
+
+// send builds a recipient's digest of the changes since the given time and emails it
+func (d *digester) send(ctx context.Context, recipient digestview.Recipient, since, now time.Time) error {
+	view, err := d.build(ctx, recipient, since, now)
+	if err != nil {
+		return err
+	}
+	message, err := renderDigest(view)
+	if err != nil {
+		return err
+	}
+	message.To = recipient.To
+	return d.sender.Send(ctx, message)
+}
+
+// build gets a recipient's digest from the reports
+// The digest is skipped if the vulnerability reports can't be read, but the rest of its data is best effort and named in the digest when missing
+func (d *digester) build(ctx context.Context, recipient digestview.Recipient, since, now time.Time) (digestview.View, error) {
+	r := backgroundRequest(ctx, "digest")
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+
+	vulnerabilityData, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		return digestview.View{}, fmt.Errorf("error getting vulnerability reports: %w", err)
+	}
+
+	complianceData, err := kube.GetComplianceReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "ClusterComplianceReports", "compliance report summaries are missing", err)
+		complianceData = &v1alpha1.ClusterComplianceReportList{}
+	}
+
+	slaView := getSLAView(ctx, r, vulnerabilityData)
+
+	changes, found, err := getChangesView(ctx, r, vulnerabilityData, since)
+	if err != nil {
+		recordPartialFailure(r, "Snapshots", "changes since the last digest are missing", err)
+		changes, found = changesview.View{Since: since}, false
+	}
+
+	view := digestview.GetView(
+		recipient,
+		imagesview.GetView(vulnerabilityData, nil, imagesview.Filters{}),
+		complianceview.GetView(complianceData),
+		slaView,
+		len(slaConfig.Policies) > 0,
+		changes,
+		found,
+		now,
+	)
+	view.BaseURL = d.baseURL
+	for _, f := range partialFailuresFromRequest(r) {
+		view.Missing = append(view.Missing, fmt.Sprintf("%s: %s", f.Kind, f.Impact))
+	}
+	return view, nil
+}
+
+// renderDigest renders a digest as an email with HTML and plain text bodies
+func renderDigest(view digestview.View) (email.Message, error) {
+	var m email.Message
+
+	htmlTmpl, err := htmltemplate.New("digest.html").ParseFS(content.Static, "static/digest.html")
+	if err != nil {
+		return m, fmt.Errorf("failed to parse digest html template: %w", err)
+	}
+	textTmpl, err := texttemplate.New("digest.txt").ParseFS(content.Static, "static/digest.txt")
+	if err != nil {
+		return m, fmt.Errorf("failed to parse digest text template: %w", err)
+	}
+
+	var html, text bytes.Buffer
+	if err := htmlTmpl.Execute(&html, view); err != nil {
+		return m, fmt.Errorf("failed to render digest html template: %w", err)
+	}
+	if err := textTmpl.Execute(&text, view); err != nil {
+		return m, fmt.Errorf("failed to render digest text template: %w", err)
+	}
+
+	m.Subject = fmt.Sprintf("Trivy Operator Explorer digest for %s: %d critical, %d high vulnerabilities", view.Scope(), view.Summary.CriticalVulnerabilities, view.Summary.HighVulnerabilities)
+	if view.SLABreached > 0 {
+		m.Subject += fmt.Sprintf(", %d SLA breaches", view.SLABreached)
+	}
+	m.HTML = html.String()
+	m.Text = text.String()
+	return m, nil
+}
+
+// recipient returns the configured recipient with the given name
+func (d *digester) recipient(name string) (digestview.Recipient, bool) {
+	for _, r := range d.recipients {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return digestview.Recipient{}, false
+}
+
+// since returns when the recipient's last digest was sent, or a schedule period ago if it hasn't been sent one
+func (d *digester) since(recipient string, now time.Time) time.Time {
+	sentAt, found, err := db.GetDigestSentAt(recipient)
+	if err != nil {
+		log.Logger.Error("error getting when the last digest was sent", "recipient", recipient, "error", err)
+	}
+	if err != nil || !found {
+		return now.Add(-d.schedule.next(now).Sub(d.schedule.previous(now)))
+	}
+	return sentAt
+}
+
+// digestPreviewHandler renders a recipient's next digest as it would be emailed, in HTML or with format=text in plain text
+func digestPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if digests == nil {
+		http.Error(w, "No digest recipients are configured", http.StatusNotFound)
+		return
+	}
+	recipient, ok := digests.recipient(r.URL.Query().Get("recipient"))
+	if !ok {
+		http.Error(w, "Unknown digest recipient", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	view, err := digests.build(r.Context(), recipient, digests.since(recipient.Name, now), now)
+	if err != nil {
+		log.Logger.Error("error building digest preview", "recipient", recipient.Name, "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+	message, err := renderDigest(view)
+	if err != nil {
+		log.Logger.Error("error rendering digest preview", "recipient", recipient.Name, "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err = w.Write([]byte(message.Text))
+	} else {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, err = w.Write([]byte(message.HTML))
+	}
+	if err != nil {
+		log.Logger.Error("encountered error writing digest preview", "error", err)
+	}
+}
+
+// DigestSendResult is the outcome of sending a digest to a recipient on demand
+type DigestSendResult struct {
+	Recipient string `json:"recipient"`
+	Error     string `json:"error,omitempty"`
+}
+
+// digestSendHandler sends the digests now, to one recipient with the recipient query parameter or to every recipient
+// Digests sent on demand don't affect the schedule, so the next scheduled digest still lists the changes since the last scheduled one
+func digestSendHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if digests == nil {
+		http.Error(w, "No digest recipients are configured", http.StatusNotFound)
+		return
+	}
+
+	recipients := digests.recipients
+	if name := r.URL.Query().Get("recipient"); name != "" {
+		recipient, ok := digests.recipient(name)
+		if !ok {
+			http.Error(w, "Unknown digest recipient", http.StatusNotFound)
+			return
+		}
+		recipients = digestview.Recipients{recipient}
+	}
+
+	now := time.Now()
+	var results []DigestSendResult
+	for _, recipient := range recipients {
+		result := DigestSendResult{Recipient: recipient.Name}
+		if err := digests.send(r.Context(), recipient, digests.since(recipient.Name, now), now); err != nil {
+			log.Logger.Error("error sending digest", "recipient", recipient.Name, "error", err)
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Logger.Error("encountered error encoding digest send json response", "error", err)
+		return
+	}
+}
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDigestSchedule(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "daily 08:00", want: "daily 08:00"},
+		{raw: "Mon 08:30", want: "Monday 08:30"},
+		{raw: "saturday 23:59", want: "Saturday 23:59"},
+		{raw: "  SUN   0:05 ", want: "Sunday 00:05"},
+		{raw: "Mon", wantErr: true},
+		{raw: "Mon 08:00 UTC", wantErr: true},
+		{raw: "weekly 08:00", wantErr: true},
+		{raw: "Mo 08:00", wantErr: true},
+		{raw: "Mon 8am", wantErr: true},
+		{raw: "Mon 24:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			s, err := parseDigestSchedule(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got schedule %s, want an error", s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.String() != tt.want {
+				t.Errorf("got schedule %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestSchedulePreviousAndNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %s", err)
+	}
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	ny := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, newYork)
+	}
+
+	// 2026-10-19 is a Monday
+	tests := []struct {
+		name     string
+		schedule string
+		now      time.Time
+		previous time.Time
+		next     time.Time
+	}{
+		{
+			name:     "daily before the time",
+			schedule: "daily 08:00",
+			now:      utc(time.October, 19, 7, 59),
+			previous: utc(time.October, 18, 8, 0),
+			next:     utc(time.October, 19, 8, 0),
+		},
+		{
+			name:     "daily at the time",
+			schedule: "daily 08:00",
+			now:      utc(time.October, 19, 8, 0),
+			previous: utc(time.October, 19, 8, 0),
+			next:     utc(time.October, 20, 8, 0),
+		},
+		{
+			name:     "daily across a month",
+			schedule: "daily 08:00",
+			now:      utc(time.October, 31, 23, 0),
+			previous: utc(time.October, 31, 8, 0),
+			next:     utc(time.November, 1, 8, 0),
+		},
+		{
+			name:     "weekly on the day before the time",
+			schedule: "Mon 08:00",
+			now:      utc(time.October, 19, 7, 0),
+			previous: utc(time.October, 12, 8, 0),
+			next:     utc(time.October, 19, 8, 0),
+		},
+		{
+			name:     "weekly on the day after the time",
+			schedule: "Mon 08:00",
+			now:      utc(time.October, 19, 9, 0),
+			previous: utc(time.October, 19, 8, 0),
+			next:     utc(time.October, 26, 8, 0),
+		},
+		{
+			name:     "weekly later in the week",
+			schedule: "Fri 17:30",
+			now:      utc(time.October, 19, 9, 0),
+			previous: utc(time.October, 16, 17, 30),
+			next:     utc(time.October, 23, 17, 30),
+		},
+		{
+			name:     "weekly on sunday",
+			schedule: "Sun 08:00",
+			now:      utc(time.October, 24, 8, 0),
+			previous: utc(time.October, 18, 8, 0),
+			next:     utc(time.October, 25, 8, 0),
+		},
+		{
+			name:     "daily keeps the local time when daylight saving time starts",
+			schedule: "daily 08:00",
+			now:      ny(time.March, 8, 7, 0),
+			previous: ny(time.March, 7, 8, 0),
+			next:     ny(time.March, 8, 8, 0),
+		},
+		{
+			name:     "weekly keeps the local time when daylight saving time ends",
+			schedule: "Mon 08:00",
+			now:      ny(time.October, 30, 12, 0),
+			previous: ny(time.October, 26, 8, 0),
+			next:     ny(time.November, 2, 8, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseDigestSchedule(tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.previous(tt.now); !got.Equal(tt.previous) {
+				t.Errorf("previous(%s) = %s, want %s", tt.now, got, tt.previous)
+			}
+			if got := s.next(tt.now); !got.Equal(tt.next) {
+				t.Errorf("next(%s) = %s, want %s", tt.now, got, tt.next)
+			}
+		})
+	}
+}
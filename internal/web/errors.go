@@ -0,0 +1,132 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+const partialFailuresContextKey contextKey = "partialFailures"
+
+// kubeRequestTimeout bounds the Kubernetes API calls made while serving a single request, it's set from the server config
+var kubeRequestTimeout = 30 * time.Second
+
+// kubeContext returns a context for Kubernetes API calls made while serving the request
+// It's cancelled when the client goes away or the kube request timeout passes, whichever is first
+func kubeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), kubeRequestTimeout)
+}
+
+// PartialFailure describes a data source that could not be loaded while rendering a page
+type PartialFailure struct {
+	// Kind is the kind of data that failed to load, like "VulnerabilityReports"
+	Kind string `json:"kind"`
+	// Impact describes what is missing from the page as a result
+	Impact string `json:"impact"`
+}
+
+// partialFailures collects the data sources that failed while serving a request
+type partialFailures struct {
+	mu       sync.Mutex
+	failures []PartialFailure
+}
+
+// partialFailuresMiddleware adds a partial failure collector to every request context
+func partialFailuresMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), partialFailuresContextKey, &partialFailures{})))
+	})
+}
+
+// recordPartialFailure logs a failure to load optional page data, and records it so the page can render a banner naming it
+func recordPartialFailure(r *http.Request, kind, impact string, err error) {
+	log.Logger.Error("error getting data, rendering partial page", "kind", kind, "path", r.URL.Path, "error", err.Error())
+
+	p, ok := r.Context().Value(partialFailuresContextKey).(*partialFailures)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures = append(p.failures, PartialFailure{Kind: kind, Impact: impact})
+}
+
+// backgroundRequest returns a request for background work, like building a digest, so it can reuse the page data helpers and collect the partial failures they record
+func backgroundRequest(ctx context.Context, name string) *http.Request {
+	r := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: name}, Header: make(http.Header)}
+	return r.WithContext(context.WithValue(ctx, partialFailuresContextKey, &partialFailures{}))
+}
+
+// partialFailuresFromRequest returns the partial failures recorded while serving the request
+func partialFailuresFromRequest(r *http.Request) []PartialFailure {
+	p, ok := r.Context().Value(partialFailuresContextKey).(*partialFailures)
+	if !ok {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PartialFailure(nil), p.failures...)
+}
+
+// errorTemplateData contains the data for the error page
+type errorTemplateData struct {
+	PageRoute string
+	Status    int
+	Title     string
+	Detail    string
+}
+
+// renderKubeError logs an error getting required data from the Kubernetes API, and renders the error page naming the report kind
+func renderKubeError(w http.ResponseWriter, r *http.Request, kind string, err error) {
+	log.Logger.Error("error getting data from the kubernetes api", "kind", kind, "path", r.URL.Path, "error", err.Error())
+
+	status := kubeErrorStatus(err)
+	detail := "The Kubernetes API could not be reached or returned an error. Check the explorer's logs, then try again."
+	if status == http.StatusGatewayTimeout {
+		detail = "The Kubernetes API took too long to respond. Large clusters may need a longer kube request timeout."
+	}
+
+	renderError(w, r, status, fmt.Sprintf("Could not load %s", kind), detail)
+}
+
+// writeKubeAPIError logs an error getting required data from the Kubernetes API, and responds to an API request with a plain text error
+func writeKubeAPIError(w http.ResponseWriter, r *http.Request, kind string, err error) {
+	log.Logger.Error("error getting data from the kubernetes api", "kind", kind, "path", r.URL.Path, "error", err.Error())
+	http.Error(w, fmt.Sprintf("Could not load %s", kind), kubeErrorStatus(err))
+}
+
+// kubeErrorStatus returns the status code for a failed Kubernetes API call
+func kubeErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusServiceUnavailable
+}
+
+// renderError renders the error page with the given status code
+func renderError(w http.ResponseWriter, r *http.Request, status int, title, detail string) {
+	tmpl := newTemplate(r, "error.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing error html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(status)
+	err := tmpl.Execute(w, errorTemplateData{
+		PageRoute: "error",
+		Status:    status,
+		Title:     title,
+		Detail:    detail,
+	})
+	if err != nil {
+		log.Logger.Error("encountered error executing error html template", "error", err)
+		return
+	}
+}
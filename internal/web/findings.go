@@ -0,0 +1,113 @@
+package web
+
+import (
+	"sort"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+)
+
+// newFindingObservations lists every finding in the vulnerability reports for the findings ledger
+// Ignored vulnerabilities are included, since ignoring a finding doesn't remediate it
+func newFindingObservations(data *v1alpha1.VulnerabilityReportList) []db.FindingObservation {
+	observations := make(map[db.FindingKey]*db.FindingObservation)
+	namespaces := make(map[db.FindingKey]map[string]struct{})
+
+	for _, item := range data.Items {
+		registry := utils.FormatPrettyImageRegistry(item.Report.Registry.Server)
+		repository := utils.FormatPrettyImageRepo(item.Report.Artifact.Repository)
+		namespace := item.ObjectMeta.Labels["trivy-operator.resource.namespace"]
+
+		for _, v := range item.Report.Vulnerabilities {
+			key := db.FindingKey{
+				Registry:   registry,
+				Repository: repository,
+				CVEID:      v.VulnerabilityID,
+				Package:    v.Resource,
+			}
+			if _, ok := observations[key]; !ok {
+				observations[key] = &db.FindingObservation{
+					FindingKey: key,
+					Severity:   string(v.Severity),
+				}
+				namespaces[key] = make(map[string]struct{})
+			}
+			if namespace != "" {
+				namespaces[key][namespace] = struct{}{}
+			}
+		}
+	}
+
+	list := make([]db.FindingObservation, 0, len(observations))
+	for key, o := range observations {
+		for ns := range namespaces[key] {
+			o.Namespaces = append(o.Namespaces, ns)
+		}
+		sort.Strings(o.Namespaces)
+		list = append(list, *o)
+	}
+	return list
+}
+
+// newConfigAuditObservations lists every failed check in the config audit reports for the config audit ledger
+func newConfigAuditObservations(data *v1alpha1.ConfigAuditReportList) []db.ConfigAuditFinding {
+	var list []db.ConfigAuditFinding
+	seen := make(map[db.ConfigAuditFinding]struct{})
+
+	for _, item := range data.Items {
+		// Long resource names are truncated in labels, so the annotation is preferred when present
+		name := item.ObjectMeta.Labels["trivy-operator.resource.name"]
+		if val, ok := item.ObjectMeta.Annotations["trivy-operator.resource.name"]; ok {
+			name = val
+		} else if name == "" {
+			name = item.Name
+		}
+
+		for _, check := range item.Report.Checks {
+			if check.Success {
+				continue
+			}
+			finding := db.ConfigAuditFinding{
+				Namespace: item.ObjectMeta.Labels["trivy-operator.resource.namespace"],
+				Kind:      item.ObjectMeta.Labels["trivy-operator.resource.kind"],
+				Name:      name,
+				CheckID:   check.ID,
+				Title:     check.Title,
+				Severity:  string(check.Severity),
+			}
+			if _, ok := seen[finding]; ok {
+				continue
+			}
+			seen[finding] = struct{}{}
+			list = append(list, finding)
+		}
+	}
+	return list
+}
+
+// newExposedSecretObservations lists every secret in the exposed secret reports for the exposed secret ledger
+// Like the findings ledger, secrets are tracked per repository rather than per tag
+func newExposedSecretObservations(data *v1alpha1.ExposedSecretReportList) []db.ExposedSecretFinding {
+	var list []db.ExposedSecretFinding
+	seen := make(map[db.ExposedSecretFinding]struct{})
+
+	for _, item := range data.Items {
+		for _, secret := range item.Report.Secrets {
+			finding := db.ExposedSecretFinding{
+				Registry:   utils.FormatPrettyImageRegistry(item.Report.Registry.Server),
+				Repository: utils.FormatPrettyImageRepo(item.Report.Artifact.Repository),
+				RuleID:     secret.RuleID,
+				Target:     secret.Target,
+				Title:      secret.Title,
+				Severity:   string(secret.Severity),
+			}
+			if _, ok := seen[finding]; ok {
+				continue
+			}
+			seen[finding] = struct{}{}
+			list = append(list, finding)
+		}
+	}
+	return list
+}
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// healthCheckTimeout bounds each health check so a hung dependency can't hang the probe
+const healthCheckTimeout = 3 * time.Second
+
+// HealthResponse is the JSON body returned by the health endpoints
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// HealthCheck is the result of a single health check
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+const (
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// livenessHandler reports whether the process is healthy
+// It only checks local dependencies, so a Kubernetes API outage doesn't cause the Pod to be restarted
+func livenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthResponse(w, []HealthCheck{
+		checkSQLite(r.Context()),
+	})
+}
+
+// readinessHandler reports whether the explorer can serve report data
+func readinessHandler(w http.ResponseWriter, r *http.Request) {
+	checks := []HealthCheck{
+		checkSQLite(r.Context()),
+		checkKubeAPI(r.Context()),
+	}
+	checks = append(checks, checkReportCRDs(r.Context())...)
+	writeHealthResponse(w, checks)
+}
+
+func checkSQLite(ctx context.Context) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	return newHealthCheck("sqlite", db.Client.PingContext(ctx))
+}
+
+func checkKubeAPI(ctx context.Context) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	return newHealthCheck("kube-api", kube.CheckAPIServer(ctx))
+}
+
+// checkReportCRDs returns a check for each report resource, failing the ones whose CRD is not installed
+func checkReportCRDs(ctx context.Context) []HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	served, err := kube.GetServedReportResources(ctx)
+
+	checks := make([]HealthCheck, 0, len(kube.ReportResources))
+	for _, resource := range kube.ReportResources {
+		name := fmt.Sprintf("crd:%s", resource)
+		if err != nil {
+			checks = append(checks, newHealthCheck(name, err))
+			continue
+		}
+		if _, ok := served[resource]; !ok {
+			checks = append(checks, newHealthCheck(name, fmt.Errorf("resource %s is not served by the api server, is trivy-operator installed?", resource)))
+			continue
+		}
+		checks = append(checks, newHealthCheck(name, nil))
+	}
+	return checks
+}
+
+func newHealthCheck(name string, err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{Name: name, Status: healthStatusFail, Error: err.Error()}
+	}
+	return HealthCheck{Name: name, Status: healthStatusOK}
+}
+
+// writeHealthResponse writes the checks as JSON, with a 503 status code if any check failed
+func writeHealthResponse(w http.ResponseWriter, checks []HealthCheck) {
+	resp := HealthResponse{Status: healthStatusOK, Checks: checks}
+	code := http.StatusOK
+	for _, check := range checks {
+		if check.Status != healthStatusOK {
+			resp.Status = healthStatusFail
+			code = http.StatusServiceUnavailable
+			log.Logger.Warn("health check failed", "check", check.Name, "error", check.Error)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Logger.Error("error writing health response", "error", err)
+	}
+}
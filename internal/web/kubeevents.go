@@ -0,0 +1,268 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// KubeEventsConfig contains the settings for emitting Kubernetes Events on workloads with new high severity findings
+type KubeEventsConfig struct {
+	// Enabled turns on emitting events
+	Enabled bool
+	// Interval is how often the reports are checked for new findings
+	Interval time.Duration
+}
+
+// Reasons of the events emitted for each kind of finding
+const (
+	kubeEventReasonVulnerability     = "CriticalVulnerability"
+	kubeEventReasonExposedSecret     = "ExposedSecret"
+	kubeEventReasonConfigAuditFailed = "CriticalConfigAuditFailed"
+)
+
+// maxKubeEventsPerCheck limits how many events are emitted each interval, so a newly deployed vulnerable workload can't flood the API server
+// Findings left over have their events emitted on the following intervals
+const maxKubeEventsPerCheck = 50
+
+// workloadFinding is a finding on a Kubernetes object that an event can be emitted for
+type workloadFinding struct {
+	// Key identifies the finding for deduplication, an event is only emitted once per key
+	Key     string
+	Object  kube.ResourceMetadata
+	Reason  string
+	Message string
+}
+
+// kubeEventEmitter periodically checks the reports for new high severity findings and emits a Warning event on each affected object
+type kubeEventEmitter struct {
+	config KubeEventsConfig
+}
+
+// run checks for new findings on the configured interval until the context is done
+// The first check happens right away, so enabling events baselines the current findings without waiting an interval
+func (e *kubeEventEmitter) run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.check(ctx); err != nil {
+			log.Logger.Error("error checking for findings to emit kubernetes events for, they'll be checked again on the next interval", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check emits an event for each finding that hasn't had one emitted yet
+// When events are first enabled the current findings are recorded without emitting anything, so only findings that appear afterwards have events
+func (e *kubeEventEmitter) check(ctx context.Context) error {
+	findings, err := e.findings(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	emitted, baselined, err := db.GetEmittedKubeEventKeys()
+	if err != nil {
+		return err
+	}
+	if !baselined {
+		keys := make([]string, 0, len(findings))
+		for _, f := range findings {
+			keys = append(keys, f.Key)
+		}
+		if err := db.RecordEmittedKubeEvents(keys, now); err != nil {
+			return err
+		}
+		log.Logger.Info("baselined kubernetes events, events will be emitted for findings that appear from now on", "existing_findings", len(findings))
+		return nil
+	}
+
+	var present []string
+	var count int
+	var limited bool
+	objects := make(map[kube.ResourceMetadata]corev1.ObjectReference)
+	for _, f := range findings {
+		if _, ok := emitted[f.Key]; ok {
+			present = append(present, f.Key)
+			continue
+		}
+		if count >= maxKubeEventsPerCheck {
+			// Emitting stops here, but the loop keeps going so every already emitted finding is still recorded as present
+			limited = true
+			continue
+		}
+
+		object, ok := objects[f.Object]
+		if !ok {
+			object, err = e.resolve(ctx, f.Object)
+			if errors.Is(err, kube.ErrUnsupportedKind) {
+				// There's nothing to emit the event on, so the finding is recorded rather than looked up every interval
+				log.Logger.Debug("skipping kubernetes event for an object of an unsupported kind", "kind", f.Object.Kind, "namespace", f.Object.Namespace, "name", f.Object.Name)
+				present = append(present, f.Key)
+				continue
+			}
+			if err != nil {
+				log.Logger.Warn("error resolving object to emit a kubernetes event on, it'll be retried on the next interval", "kind", f.Object.Kind, "namespace", f.Object.Namespace, "name", f.Object.Name, "error", err)
+				continue
+			}
+			objects[f.Object] = object
+		}
+
+		if err := kube.CreateWarningEvent(ctx, object, f.Reason, f.Message); err != nil {
+			log.Logger.Error("error emitting kubernetes event, it'll be retried on the next interval", "kind", f.Object.Kind, "namespace", f.Object.Namespace, "name", f.Object.Name, "reason", f.Reason, "error", err)
+			continue
+		}
+		count++
+		present = append(present, f.Key)
+	}
+	if count > 0 {
+		log.Logger.Info("emitted kubernetes events for new findings", "events", count)
+	}
+	if limited {
+		log.Logger.Warn("more new findings than events can be emitted for at once, the rest will be emitted on the following intervals", "limit", maxKubeEventsPerCheck)
+	}
+
+	// Findings that are still present are kept from being forgotten
+	if err := db.RecordEmittedKubeEvents(present, now); err != nil {
+		log.Logger.Error("error recording emitted kubernetes events", "error", err)
+	}
+
+	deleted, err := db.DeleteEmittedKubeEventsNotSeenSince(now.Add(-notifiedEventRetention))
+	if err != nil {
+		log.Logger.Error("error deleting emitted kubernetes events", "error", err)
+	} else if deleted > 0 {
+		log.Logger.Debug("forgot kubernetes events for findings that have disappeared", "count", deleted)
+	}
+	return nil
+}
+
+func (e *kubeEventEmitter) resolve(ctx context.Context, object kube.ResourceMetadata) (corev1.ObjectReference, error) {
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+	return kube.ResolveObject(ctx, object.Kind, object.Namespace, object.Name)
+}
+
+// findings gets every finding that could have an event emitted from the reports, sorted so the same findings are emitted first each check
+// The check is skipped if the vulnerability reports can't be read, but the other report types are best effort
+func (e *kubeEventEmitter) findings(ctx context.Context) ([]workloadFinding, error) {
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+
+	vulnerabilityData, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting vulnerability reports: %w", err)
+	}
+	findings := vulnerabilityWorkloadFindings(imagesview.GetView(vulnerabilityData, nil, imagesview.Filters{}))
+
+	exposedSecretData, err := kube.GetExposedSecretReportList(ctx)
+	if err != nil {
+		log.Logger.Warn("error getting exposed secret reports, new exposed secrets won't have events emitted until they can be read", "error", err)
+	} else {
+		for _, item := range exposedSecretData.Items {
+			object := kube.ResourceMetadata{
+				Kind:      item.ObjectMeta.Labels["trivy-operator.resource.kind"],
+				Name:      item.ObjectMeta.Labels["trivy-operator.resource.name"],
+				Namespace: item.ObjectMeta.Labels["trivy-operator.resource.namespace"],
+			}
+			image := utils.AssembleImageFullName(utils.FormatPrettyImageRegistry(item.Report.Registry.Server), utils.FormatPrettyImageRepo(item.Report.Artifact.Repository), item.Report.Artifact.Tag, "")
+			for _, s := range item.Report.Secrets {
+				findings = append(findings, workloadFinding{
+					Key:     strings.Join([]string{kubeEventReasonExposedSecret, object.Namespace, object.Kind, object.Name, image, s.RuleID, s.Target}, "/"),
+					Object:  object,
+					Reason:  kubeEventReasonExposedSecret,
+					Message: fmt.Sprintf("Trivy Operator found a %s exposed secret in image %s: %s in %s", s.Severity, image, s.Title, s.Target),
+				})
+			}
+		}
+	}
+
+	configAuditData, err := kube.GetConfigAuditReportList(ctx)
+	if err != nil {
+		log.Logger.Warn("error getting config audit reports, new config audit failures won't have events emitted until they can be read", "error", err)
+	} else {
+		// Config audit failures are read through the ledger's observations, which resolve truncated resource names
+		for _, f := range newConfigAuditObservations(configAuditData) {
+			if f.Severity != "CRITICAL" {
+				continue
+			}
+			object := kube.ResourceMetadata{Kind: f.Kind, Name: f.Name, Namespace: f.Namespace}
+			findings = append(findings, workloadFinding{
+				Key:     strings.Join([]string{kubeEventReasonConfigAuditFailed, f.Namespace, f.Kind, f.Name, f.CheckID}, "/"),
+				Object:  object,
+				Reason:  kubeEventReasonConfigAuditFailed,
+				Message: fmt.Sprintf("Trivy Operator config audit check %s failed: %s", f.CheckID, f.Title),
+			})
+		}
+	}
+
+	// Deduplicate findings reported more than once, like secrets in several containers running the same image
+	seen := make(map[string]struct{}, len(findings))
+	unique := findings[:0]
+	for _, f := range findings {
+		if _, ok := seen[f.Key]; ok {
+			continue
+		}
+		seen[f.Key] = struct{}{}
+		unique = append(unique, f)
+	}
+	sort.SliceStable(unique, func(j, k int) bool {
+		return unique[j].Key < unique[k].Key
+	})
+	return unique, nil
+}
+
+// vulnerabilityWorkloadFindings returns a finding per critical CVE in each object running a vulnerable image
+// The images view leaves out ignored vulnerabilities, so an approved ignore never has an event emitted
+func vulnerabilityWorkloadFindings(images imagesview.View) []workloadFinding {
+	var findings []workloadFinding
+	for _, image := range images {
+		if image.Unscanned || len(image.CriticalVulnerabilities) == 0 {
+			continue
+		}
+		name := utils.AssembleImageFullName(image.Registry, image.Name, image.Tag, "")
+
+		// An image can ship the same CVE in several packages, so they're listed in a single event
+		var ids []string
+		packages := make(map[string][]string)
+		for _, v := range image.CriticalVulnerabilities {
+			if _, ok := packages[v.ID]; !ok {
+				ids = append(ids, v.ID)
+			}
+			p := fmt.Sprintf("%s %s", v.Resource, v.VulnerableVersion)
+			if v.FixedVersion != "" {
+				p += " (fixed in " + v.FixedVersion + ")"
+			}
+			packages[v.ID] = append(packages[v.ID], p)
+		}
+
+		for resource := range image.Resources {
+			object := kube.ResourceMetadata{Kind: resource.Kind, Name: resource.Name, Namespace: resource.Namespace}
+			for _, id := range ids {
+				findings = append(findings, workloadFinding{
+					Key:     strings.Join([]string{kubeEventReasonVulnerability, object.Namespace, object.Kind, object.Name, image.Registry, image.Name, image.Tag, id}, "/"),
+					Object:  object,
+					Reason:  kubeEventReasonVulnerability,
+					Message: fmt.Sprintf("Trivy Operator found critical vulnerability %s in image %s: %s", id, name, strings.Join(packages[id], ", ")),
+				})
+			}
+		}
+	}
+	return findings
+}
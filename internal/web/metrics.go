@@ -0,0 +1,89 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	slaview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/sla"
+)
+
+// slaMetrics is the last SLA view evaluated without errors, served when an evaluation fails so a failed scrape doesn't look like every breach was fixed
+var slaMetrics struct {
+	mu        sync.Mutex
+	view      slaview.View
+	evaluated time.Time
+}
+
+// metricsHandler serves SLA breach counts in the Prometheus text exposition format
+// The counts are evaluated on each scrape, so the scrape interval should be longer than a page load on large clusters
+// When the evaluation fails, the last successful one is served and the scrape error gauge is set
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), kubeRequestTimeout)
+	defer cancel()
+	// The ops server doesn't collect partial failures for banners, but they're needed here to tell a complete evaluation from a partial one
+	r = r.WithContext(context.WithValue(ctx, partialFailuresContextKey, &partialFailures{}))
+
+	scrapeError := 0
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		log.Logger.Error("error getting vulnerability reports, serving the last SLA evaluation", "error", err)
+		scrapeError = 1
+	} else {
+		view := getSLAView(ctx, r, data)
+		if len(partialFailuresFromRequest(r)) > 0 {
+			log.Logger.Error("error evaluating SLAs, serving the last SLA evaluation")
+			scrapeError = 1
+		} else {
+			slaMetrics.mu.Lock()
+			slaMetrics.view, slaMetrics.evaluated = view, time.Now()
+			slaMetrics.mu.Unlock()
+		}
+	}
+
+	slaMetrics.mu.Lock()
+	view, evaluated := slaMetrics.view, slaMetrics.evaluated
+	slaMetrics.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP trivy_operator_explorer_sla_scrape_error Whether the SLAs couldn't be evaluated on this scrape, the last successful evaluation is served when set.\n")
+	b.WriteString("# TYPE trivy_operator_explorer_sla_scrape_error gauge\n")
+	fmt.Fprintf(&b, "trivy_operator_explorer_sla_scrape_error %d\n", scrapeError)
+	// Nothing is known about the findings until an evaluation has succeeded, so they're left out rather than reported as zero
+	if !evaluated.IsZero() {
+		b.WriteString("# HELP trivy_operator_explorer_sla_breached_findings Findings past their SLA deadline.\n")
+		b.WriteString("# TYPE trivy_operator_explorer_sla_breached_findings gauge\n")
+		for _, c := range view.Counts {
+			fmt.Fprintf(&b, "trivy_operator_explorer_sla_breached_findings{namespace=\"%s\",severity=\"%s\"} %d\n", escapeLabelValue(c.Namespace), escapeLabelValue(c.Severity), c.Breached)
+		}
+		b.WriteString("# HELP trivy_operator_explorer_sla_due_soon_findings Findings within the warning period of their SLA deadline.\n")
+		b.WriteString("# TYPE trivy_operator_explorer_sla_due_soon_findings gauge\n")
+		for _, c := range view.Counts {
+			fmt.Fprintf(&b, "trivy_operator_explorer_sla_due_soon_findings{namespace=\"%s\",severity=\"%s\"} %d\n", escapeLabelValue(c.Namespace), escapeLabelValue(c.Severity), c.DueSoon)
+		}
+		b.WriteString("# HELP trivy_operator_explorer_sla_untracked_findings Findings with an SLA that the findings ledger hasn't recorded yet.\n")
+		b.WriteString("# TYPE trivy_operator_explorer_sla_untracked_findings gauge\n")
+		fmt.Fprintf(&b, "trivy_operator_explorer_sla_untracked_findings %d\n", view.Untracked)
+		b.WriteString("# HELP trivy_operator_explorer_sla_last_evaluation_timestamp_seconds When the served SLA counts were evaluated.\n")
+		b.WriteString("# TYPE trivy_operator_explorer_sla_last_evaluation_timestamp_seconds gauge\n")
+		fmt.Fprintf(&b, "trivy_operator_explorer_sla_last_evaluation_timestamp_seconds %d\n", evaluated.Unix())
+	}
+	b.WriteString("# HELP trivy_operator_explorer_sla_policies Configured SLA policies.\n")
+	b.WriteString("# TYPE trivy_operator_explorer_sla_policies gauge\n")
+	fmt.Fprintf(&b, "trivy_operator_explorer_sla_policies %d\n", len(slaConfig.Policies))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := w.Write([]byte(b.String())); err != nil {
+		log.Logger.Error("encountered error writing metrics response", "error", err)
+	}
+}
+
+// escapeLabelValue escapes a Prometheus label value
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,165 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	slaview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/sla"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testVulnerabilityReportsPath = "/apis/aquasecurity.github.io/v1alpha1/vulnerabilityreports"
+	testNamespacesPath           = "/api/v1/namespaces"
+	testDigest                   = "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+)
+
+// testVulnerabilityReports has one report for nginx:1.25 in the default namespace, with a critical vulnerability
+var testVulnerabilityReports = v1alpha1.VulnerabilityReportList{
+	TypeMeta: metav1.TypeMeta{Kind: "VulnerabilityReportList", APIVersion: "aquasecurity.github.io/v1alpha1"},
+	Items: []v1alpha1.VulnerabilityReport{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "replicaset-nginx-6d4cf56db6-nginx",
+			Namespace: "default",
+			Labels: map[string]string{
+				"trivy-operator.resource.kind":      "ReplicaSet",
+				"trivy-operator.resource.name":      "nginx-6d4cf56db6",
+				"trivy-operator.resource.namespace": "default",
+				"trivy-operator.container.name":     "nginx",
+			},
+		},
+		Report: v1alpha1.VulnerabilityReportData{
+			Registry: v1alpha1.Registry{Server: "index.docker.io"},
+			Artifact: v1alpha1.Artifact{Repository: "library/nginx", Tag: "1.25", Digest: testDigest},
+			Vulnerabilities: []v1alpha1.Vulnerability{{
+				VulnerabilityID:  "CVE-2024-0001",
+				Resource:         "openssl",
+				InstalledVersion: "3.0.0",
+				FixedVersion:     "3.0.1",
+				Severity:         v1alpha1.SeverityCritical,
+			}},
+		},
+	}},
+}
+
+var testNamespaces = corev1.NamespaceList{
+	TypeMeta: metav1.TypeMeta{Kind: "NamespaceList", APIVersion: "v1"},
+	Items:    []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "default"}}},
+}
+
+// newTestKubeAPI points the kube package at a fake API server serving the given objects by path
+// Paths in failing are answered with 403 Forbidden, which isn't retried
+func newTestKubeAPI(t *testing.T, objects map[string]any, failing *sync.Map) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, fail := failing.Load(r.URL.Path); fail {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`)
+			return
+		}
+		object, ok := objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(object); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	err := os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test
+current-context: test
+`, server.URL)), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kube.InitClient(false, kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scrapeMetrics returns the metrics handler's response body
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metricsHandler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestMetricsServeLastEvaluationOnError(t *testing.T) {
+	initTestDB(t)
+	slaConfig = slaview.Config{Policies: slaview.Policies{{Name: "default", Critical: 7, High: 30, Medium: 90, Low: 180}}}
+	t.Cleanup(func() {
+		slaConfig = slaview.Config{}
+		slaMetrics.view, slaMetrics.evaluated = slaview.View{}, time.Time{}
+	})
+
+	var failing sync.Map
+	newTestKubeAPI(t, map[string]any{
+		testVulnerabilityReportsPath: testVulnerabilityReports,
+		testNamespacesPath:           testNamespaces,
+	}, &failing)
+
+	// The vulnerability was first seen long enough ago to have breached its SLA
+	if err := db.RecordFindingObservations(time.Now().Add(-30*24*time.Hour), newFindingObservations(&testVulnerabilityReports)); err != nil {
+		t.Fatal(err)
+	}
+	const breached = `trivy_operator_explorer_sla_breached_findings{namespace="default",severity="CRITICAL"} 1`
+
+	// Before any evaluation has succeeded, a failed one doesn't export breach counts at all
+	failing.Store(testVulnerabilityReportsPath, true)
+	body := scrapeMetrics(t)
+	if !strings.Contains(body, "trivy_operator_explorer_sla_scrape_error 1\n") {
+		t.Errorf("scrape error isn't set after failing to list vulnerability reports:\n%s", body)
+	}
+	if strings.Contains(body, "trivy_operator_explorer_sla_breached_findings{") || strings.Contains(body, "trivy_operator_explorer_sla_untracked_findings ") {
+		t.Errorf("finding counts were exported before an evaluation succeeded:\n%s", body)
+	}
+
+	failing.Delete(testVulnerabilityReportsPath)
+	body = scrapeMetrics(t)
+	if !strings.Contains(body, "trivy_operator_explorer_sla_scrape_error 0\n") || !strings.Contains(body, breached) {
+		t.Fatalf("want the breach exported without a scrape error:\n%s", body)
+	}
+
+	// Failing to list the reports, or to get anything the evaluation depends on, serves the last evaluation
+	for _, path := range []string{testVulnerabilityReportsPath, testNamespacesPath} {
+		failing.Store(path, true)
+		body = scrapeMetrics(t)
+		if !strings.Contains(body, "trivy_operator_explorer_sla_scrape_error 1\n") {
+			t.Errorf("scrape error isn't set when %s fails:\n%s", path, body)
+		}
+		if !strings.Contains(body, breached) {
+			t.Errorf("the last evaluation's breach isn't served when %s fails:\n%s", path, body)
+		}
+		failing.Delete(path)
+	}
+}
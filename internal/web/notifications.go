@@ -0,0 +1,403 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/notify"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+	complianceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/compliance"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+)
+
+// NotificationConfig contains the settings for notifying webhooks about newly appearing findings
+type NotificationConfig struct {
+	// Webhooks is a YAML or JSON list of webhooks, notifications are disabled when it's empty
+	Webhooks string
+	// Interval is how often the reports are checked for new findings, 0 disables notifications
+	Interval time.Duration
+	// MinSeverity is the lowest severity of finding notified about, webhooks can override it
+	MinSeverity string
+	// RateLimit is the most messages sent to each webhook per hour, 0 for no limit
+	RateLimit int
+	// IgnoreExpiryWarning is how long before an ignore expires that webhooks are notified about it
+	IgnoreExpiryWarning time.Duration
+}
+
+// notifications sends notifications to the configured webhooks, it's set from the server config and nil when there are no webhooks
+var notifications *notificationWatcher
+
+// notifiedEventRetention is how long an event that has disappeared is remembered as sent
+// A finding that flickers while its workload is rescanned isn't sent again, but one that's fixed and later reintroduced is
+const notifiedEventRetention = 7 * 24 * time.Hour
+
+// webhookTimeout bounds each post to a webhook
+const webhookTimeout = 10 * time.Second
+
+// notificationWatcher periodically checks the reports for events that haven't been sent to each webhook yet
+type notificationWatcher struct {
+	config   NotificationConfig
+	webhooks notify.Webhooks
+	notifier *notify.Notifier
+	// baseURL is the explorer's external URL for linking to findings, links are left out when it's empty
+	baseURL string
+}
+
+func newNotificationWatcher(config NotificationConfig, baseURL string) (*notificationWatcher, error) {
+	if config.Interval < 0 || config.RateLimit < 0 || config.IgnoreExpiryWarning < 0 {
+		return nil, fmt.Errorf("notification interval, rate limit and ignore expiry warning must not be negative")
+	}
+
+	webhooks, err := notify.ParseWebhooks(config.Webhooks, config.MinSeverity)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, nil
+	}
+
+	return &notificationWatcher{
+		config:   config,
+		webhooks: webhooks,
+		notifier: notify.New(config.RateLimit, webhookTimeout),
+		baseURL:  baseURL,
+	}, nil
+}
+
+// run checks for new events on the configured interval until the context is done
+// The first check happens right away, so newly configured webhooks are baselined without waiting an interval
+func (n *notificationWatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(n.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.check(ctx); err != nil {
+			log.Logger.Error("error checking for new notification events, they'll be checked again on the next interval", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check gets the current events and sends the webhooks the ones they haven't been sent yet
+func (n *notificationWatcher) check(ctx context.Context) error {
+	events, err := n.events(ctx)
+	if err != nil {
+		return err
+	}
+	n.send(ctx, events, time.Now())
+	return nil
+}
+
+// send sends each webhook the events it accepts that it hasn't been sent yet
+// When a webhook is first configured its current events are recorded without being sent, so it's only notified about what appears afterwards
+func (n *notificationWatcher) send(ctx context.Context, events []notify.Event, now time.Time) {
+	for _, webhook := range n.webhooks {
+		var accepted []notify.Event
+		for _, e := range events {
+			if webhook.Accepts(e) {
+				accepted = append(accepted, e)
+			}
+		}
+
+		notified, baselined, err := db.GetNotifiedEventKeys(webhook.Name)
+		if err != nil {
+			log.Logger.Error("error getting notified events", "webhook", webhook.Name, "error", err)
+			continue
+		}
+		if !baselined {
+			if err := db.RecordNotifiedEvents(webhook.Name, eventKeys(accepted), now); err != nil {
+				log.Logger.Error("error baselining webhook", "webhook", webhook.Name, "error", err)
+				continue
+			}
+			log.Logger.Info("baselined new webhook, it will be notified about events from now on", "webhook", webhook.Name, "existing_events", len(accepted))
+			continue
+		}
+
+		var pending []notify.Event
+		var present []string
+		for _, e := range accepted {
+			if _, ok := notified[e.Key]; ok {
+				present = append(present, e.Key)
+				continue
+			}
+			pending = append(pending, e)
+		}
+
+		if len(pending) > 0 {
+			err := n.notifier.Send(ctx, webhook, notificationTitle(pending), pending)
+			if errors.Is(err, notify.ErrRateLimited) {
+				log.Logger.Warn("webhook rate limit reached, events will be sent once it allows", "webhook", webhook.Name, "pending_events", len(pending))
+			} else if err != nil {
+				log.Logger.Error("error notifying webhook, events will be sent on the next interval", "webhook", webhook.Name, "pending_events", len(pending), "error", err)
+			} else {
+				log.Logger.Info("notified webhook", "webhook", webhook.Name, "events", len(pending))
+				present = append(present, eventKeys(pending)...)
+			}
+		}
+
+		// Events that are still present are kept from being forgotten
+		if err := db.RecordNotifiedEvents(webhook.Name, present, now); err != nil {
+			log.Logger.Error("error recording notified events", "webhook", webhook.Name, "error", err)
+		}
+	}
+
+	deleted, err := db.DeleteNotifiedEventsNotSeenSince(now.Add(-notifiedEventRetention))
+	if err != nil {
+		log.Logger.Error("error deleting notified events", "error", err)
+	} else if deleted > 0 {
+		log.Logger.Debug("forgot notified events that have disappeared", "count", deleted)
+	}
+}
+
+// events gets every event that could be notified about from the reports
+// The check is skipped if the vulnerability reports can't be read, but the other report types are best effort
+func (n *notificationWatcher) events(ctx context.Context) ([]notify.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+
+	vulnerabilityData, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting vulnerability reports: %w", err)
+	}
+	events := n.vulnerabilityEvents(imagesview.GetView(vulnerabilityData, nil, imagesview.Filters{}))
+
+	exposedSecretData, err := kube.GetExposedSecretReportList(ctx)
+	if err != nil {
+		log.Logger.Warn("error getting exposed secret reports, new exposed secrets won't be notified about until they can be read", "error", err)
+	} else {
+		for _, s := range newExposedSecretObservations(exposedSecretData) {
+			events = append(events, notify.Event{
+				Key:      strings.Join([]string{string(notify.KindExposedSecret), s.Registry, s.Repository, s.RuleID, s.Target}, "/"),
+				Kind:     notify.KindExposedSecret,
+				Severity: s.Severity,
+				Title:    fmt.Sprintf("%s in %s", s.Title, utils.AssembleImageFullName(s.Registry, s.Repository, "", "")),
+				Detail:   s.Target,
+				URL:      n.link("/exposedsecrets"),
+			})
+		}
+	}
+
+	complianceData, err := kube.GetComplianceReportList(ctx)
+	if err != nil {
+		log.Logger.Warn("error getting compliance reports, new compliance failures won't be notified about until they can be read", "error", err)
+	} else {
+		for _, report := range complianceview.GetView(complianceData) {
+			for _, check := range report.Checks {
+				if check.TotalFailed == nil || *check.TotalFailed == 0 {
+					continue
+				}
+				events = append(events, notify.Event{
+					Key:      strings.Join([]string{string(notify.KindComplianceFailure), report.ID, check.IDNumber}, "/"),
+					Kind:     notify.KindComplianceFailure,
+					Severity: check.Severity,
+					Title:    fmt.Sprintf("%s %s failing in %s", check.IDNumber, check.Name, report.Title),
+					Detail:   fmt.Sprintf("%d failed", *check.TotalFailed),
+					URL:      n.link("/compliancereport?id=" + url.QueryEscape(report.ID)),
+				})
+			}
+		}
+	}
+
+	if n.config.IgnoreExpiryWarning > 0 {
+		ignores, err := db.GetIgnoresExpiringBefore(time.Now().Add(n.config.IgnoreExpiryWarning))
+		if err != nil {
+			log.Logger.Error("error getting expiring ignores", "error", err)
+		}
+		for _, ignore := range ignores {
+			image := utils.AssembleImageFullName(utils.FormatPrettyImageRegistry(ignore.Registry), utils.FormatPrettyImageRepo(ignore.Repository), ignore.Tag, "")
+			detail := ignore.Reason
+			if ignore.IgnoredBy != "" {
+				detail += fmt.Sprintf(" (ignored by %s)", ignore.IgnoredBy)
+			}
+			events = append(events, notify.Event{
+				// The expiry time is part of the key, so extending an ignore that's expiring again is notified again
+				Key:    strings.Join([]string{string(notify.KindIgnoreExpiring), ignore.Registry, ignore.Repository, ignore.Tag, ignore.CVEID, fmt.Sprint(ignore.ExpiresAt.Unix())}, "/"),
+				Kind:   notify.KindIgnoreExpiring,
+				Title:  fmt.Sprintf("Ignore of %s in %s expires %s", ignore.CVEID, image, ignore.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")),
+				Detail: detail,
+				URL:    n.link("/cve?id=" + url.QueryEscape(ignore.CVEID)),
+			})
+		}
+	}
+
+	sortEvents(events)
+	return events, nil
+}
+
+// vulnerabilityEvents returns an event per CVE and package in each image repository, like the findings ledger
+// The images view leaves out ignored vulnerabilities, so an approved ignore is never notified about
+func (n *notificationWatcher) vulnerabilityEvents(images imagesview.View) []notify.Event {
+	var events []notify.Event
+	seen := make(map[string]struct{})
+
+	for _, image := range images {
+		if image.Unscanned {
+			continue
+		}
+		repository := utils.AssembleImageFullName(image.Registry, image.Name, "", "")
+
+		namespaceSet := make(map[string]struct{})
+		for resource := range image.Resources {
+			namespaceSet[resource.Namespace] = struct{}{}
+		}
+		var namespaces []string
+		for namespace := range namespaceSet {
+			namespaces = append(namespaces, namespace)
+		}
+		sort.Strings(namespaces)
+
+		for _, vulns := range [][]imagesview.Vulnerability{image.CriticalVulnerabilities, image.HighVulnerabilities, image.MediumVulnerabilities, image.LowVulnerabilities} {
+			for _, v := range vulns {
+				key := strings.Join([]string{string(notify.KindVulnerability), image.Registry, image.Name, v.ID, v.Resource}, "/")
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+
+				detail := fmt.Sprintf("%s %s", v.Resource, v.VulnerableVersion)
+				if v.FixedVersion != "" {
+					detail += ", fixed in " + v.FixedVersion
+				}
+				if len(namespaces) > 0 {
+					detail += ", running in " + strings.Join(namespaces, ", ")
+				}
+				events = append(events, notify.Event{
+					Key:      key,
+					Kind:     notify.KindVulnerability,
+					Severity: v.Severity,
+					Title:    fmt.Sprintf("%s in %s", v.ID, repository),
+					Detail:   detail,
+					URL:      n.link("/cve?id=" + url.QueryEscape(v.ID)),
+				})
+			}
+		}
+	}
+	return events
+}
+
+// link returns an absolute link to a page of the explorer, or an empty string without a base URL
+func (n *notificationWatcher) link(path string) string {
+	if n.baseURL == "" {
+		return ""
+	}
+	return n.baseURL + path
+}
+
+var notificationSeverityOrder = map[string]int{
+	"CRITICAL": 0,
+	"HIGH":     1,
+	"MEDIUM":   2,
+	"LOW":      3,
+	"UNKNOWN":  4,
+	// Events without a severity, like expiring ignores, go last
+	"": 5,
+}
+
+// sortEvents sorts the events by severity then title, so the most important are listed first in a message
+func sortEvents(events []notify.Event) {
+	sort.SliceStable(events, func(j, k int) bool {
+		if notificationSeverityOrder[events[j].Severity] != notificationSeverityOrder[events[k].Severity] {
+			return notificationSeverityOrder[events[j].Severity] < notificationSeverityOrder[events[k].Severity]
+		}
+		return events[j].Title < events[k].Title
+	})
+}
+
+func eventKeys(events []notify.Event) []string {
+	keys := make([]string, 0, len(events))
+	for _, e := range events {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+// notificationTitle summarizes the kinds of event in a message, like "2 new vulnerabilities, 1 expiring ignore"
+func notificationTitle(events []notify.Event) string {
+	labels := []struct {
+		kind             notify.Kind
+		singular, plural string
+	}{
+		{notify.KindVulnerability, "new vulnerability", "new vulnerabilities"},
+		{notify.KindExposedSecret, "new exposed secret", "new exposed secrets"},
+		{notify.KindComplianceFailure, "new compliance failure", "new compliance failures"},
+		{notify.KindIgnoreExpiring, "expiring ignore", "expiring ignores"},
+		{notify.KindTest, "test notification", "test notifications"},
+	}
+
+	counts := make(map[notify.Kind]int)
+	for _, e := range events {
+		counts[e.Kind]++
+	}
+
+	var parts []string
+	for _, l := range labels {
+		switch counts[l.kind] {
+		case 0:
+		case 1:
+			parts = append(parts, "1 "+l.singular)
+		default:
+			parts = append(parts, fmt.Sprintf("%d %s", counts[l.kind], l.plural))
+		}
+	}
+	return "Trivy Operator Explorer: " + strings.Join(parts, ", ")
+}
+
+// WebhookTestResult is the outcome of sending a test notification to a webhook
+type WebhookTestResult struct {
+	Webhook string `json:"webhook"`
+	Error   string `json:"error,omitempty"`
+}
+
+// notificationTestHandler sends a test notification to every webhook, so their URLs and formats can be checked
+// Test notifications bypass deduplication but count towards the rate limit
+func notificationTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if notifications == nil {
+		http.Error(w, "No notification webhooks are configured", http.StatusNotFound)
+		return
+	}
+
+	event := notify.Event{
+		Key:   "test",
+		Kind:  notify.KindTest,
+		Title: "Test notification",
+		URL:   notifications.link("/"),
+	}
+	if username := usernameFromRequest(r); username != "" {
+		event.Detail = "sent by " + username
+	}
+
+	var results []WebhookTestResult
+	for _, webhook := range notifications.webhooks {
+		result := WebhookTestResult{Webhook: webhook.Name}
+		if err := notifications.notifier.Send(r.Context(), webhook, notificationTitle([]notify.Event{event}), []notify.Event{event}); err != nil {
+			log.Logger.Error("error sending test notification", "webhook", webhook.Name, "error", err)
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Logger.Error("encountered error encoding notification test json response", "error", err)
+		return
+	}
+}
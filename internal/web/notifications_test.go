@@ -0,0 +1,92 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/notify"
+	"github.com/starttoaster/trivy-operator-explorer/internal/web/content"
+)
+
+func TestMain(m *testing.M) {
+	log.Init("error")
+	// Pages are rendered from the templates in the repository, which the binary embeds
+	content.Init(os.DirFS(filepath.Join("..", "..")))
+	os.Exit(m.Run())
+}
+
+// initTestDB points the db package at a new database for the test
+func initTestDB(t *testing.T) {
+	if err := db.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestNotificationsDeduplicate(t *testing.T) {
+	initTestDB(t)
+
+	var mu sync.Mutex
+	var messages []notify.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m notify.Message
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		messages = append(messages, m)
+		mu.Unlock()
+	}))
+	defer server.Close()
+	sent := func() []notify.Message {
+		mu.Lock()
+		defer mu.Unlock()
+		return messages
+	}
+
+	n, err := newNotificationWatcher(NotificationConfig{
+		Webhooks:    fmt.Sprintf("- name: test\n  url: %s\n", server.URL),
+		Interval:    time.Minute,
+		MinSeverity: "HIGH",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	existing := notify.Event{Key: "existing", Kind: notify.KindVulnerability, Severity: "CRITICAL", Title: "CVE-2024-0001 in nginx:1.25"}
+	added := notify.Event{Key: "added", Kind: notify.KindVulnerability, Severity: "HIGH", Title: "CVE-2024-0002 in nginx:1.25"}
+	low := notify.Event{Key: "low", Kind: notify.KindVulnerability, Severity: "LOW", Title: "CVE-2024-0003 in nginx:1.25"}
+	now := time.Now()
+
+	// A new webhook is baselined with the current events rather than sent them
+	n.send(context.Background(), []notify.Event{existing}, now)
+	if got := len(sent()); got != 0 {
+		t.Fatalf("baselining sent %d messages, want 0", got)
+	}
+
+	// Only events that haven't been sent and meet the minimum severity are sent
+	n.send(context.Background(), []notify.Event{existing, added, low}, now.Add(time.Minute))
+	if got := sent(); len(got) != 1 || len(got[0].Events) != 1 || got[0].Events[0].Key != added.Key {
+		t.Fatalf("got messages %+v, want one with only the added event", got)
+	}
+
+	// Events that were already sent aren't sent again
+	n.send(context.Background(), []notify.Event{existing, added, low}, now.Add(2*time.Minute))
+	if got := len(sent()); got != 1 {
+		t.Errorf("webhook was sent %d messages, want 1", got)
+	}
+}
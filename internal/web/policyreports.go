@@ -0,0 +1,422 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyReportsConfig contains the settings for publishing findings as wgpolicyk8s.io PolicyReports
+type PolicyReportsConfig struct {
+	// Enabled turns on publishing policy reports
+	Enabled bool
+	// Interval is how often the policy reports are reconciled with the Trivy Operator reports
+	Interval time.Duration
+}
+
+// policyReportName is the name of the PolicyReport in each namespace, and of the ClusterPolicyReport
+const policyReportName = "trivy-operator-explorer"
+
+// policyReportManagedByLabel marks the policy reports the explorer publishes, so reports left for namespaces without findings can be found and deleted
+const policyReportManagedByLabel = "app.kubernetes.io/managed-by"
+
+// policyReportSource is the source of every result, dashboards like Policy Reporter group results by it
+const policyReportSource = "Trivy Operator Explorer"
+
+// Categories of the results for each kind of finding
+const (
+	policyReportCategoryVulnerability = "Vulnerability"
+	policyReportCategoryConfigAudit   = "Config Audit"
+	policyReportCategoryRbac          = "RBAC Assessment"
+	policyReportCategoryExposedSecret = "Exposed Secret"
+)
+
+// maxPolicyReportResults keeps each report well under the API server's object size limit
+// The most severe results are kept when a namespace has more findings than this
+const maxPolicyReportResults = 2000
+
+// policyReportExporter periodically publishes the findings in the Trivy Operator reports as a PolicyReport per namespace and a ClusterPolicyReport
+type policyReportExporter struct {
+	config PolicyReportsConfig
+}
+
+// run reconciles the policy reports on the configured interval until the context is done
+func (e *policyReportExporter) run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.reconcile(ctx); err != nil {
+			log.Logger.Error("error publishing policy reports, they'll be reconciled again on the next interval", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile creates, updates and deletes the published policy reports so they match the current findings
+// Reports are only written when their results change, so an unchanged cluster doesn't write to the API server every interval
+func (e *policyReportExporter) reconcile(ctx context.Context) error {
+	desired, err := e.reports(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+
+	existing, err := kube.GetPolicyReports(ctx, policyReportManagedByLabel+"="+policyReportName)
+	if err != nil {
+		return fmt.Errorf("error getting published policy reports: %w", err)
+	}
+
+	now := time.Now()
+	var created, updated, deleted int
+	for _, report := range existing {
+		results, ok := desired[report.Namespace]
+		if !ok || report.Name != policyReportName {
+			if err := kube.DeletePolicyReport(ctx, report); err != nil {
+				log.Logger.Error("error deleting policy report", "namespace", report.Namespace, "name", report.Name, "error", err)
+				continue
+			}
+			deleted++
+			continue
+		}
+		delete(desired, report.Namespace)
+
+		results = withPolicyReportTimestamps(results, report.Results, now)
+		if policyReportResultsEqual(results, report.Results) {
+			continue
+		}
+		next := newPolicyReport(report.Namespace, results)
+		next.ResourceVersion = report.ResourceVersion
+		if err := kube.UpdatePolicyReport(ctx, next); err != nil {
+			log.Logger.Error("error updating policy report", "namespace", report.Namespace, "error", err)
+			continue
+		}
+		updated++
+	}
+
+	for namespace, results := range desired {
+		report := newPolicyReport(namespace, withPolicyReportTimestamps(results, nil, now))
+		if err := kube.CreatePolicyReport(ctx, report); err != nil {
+			log.Logger.Error("error creating policy report", "namespace", namespace, "error", err)
+			continue
+		}
+		created++
+	}
+
+	if created+updated+deleted > 0 {
+		log.Logger.Info("published policy reports", "created", created, "updated", updated, "deleted", deleted)
+	}
+	return nil
+}
+
+// reports gets the results of each policy report from the Trivy Operator reports, keyed by namespace with cluster scoped results under ""
+// Nothing is published if any report type can't be read, rather than removing its results from the policy reports until it can be read again
+func (e *policyReportExporter) reports(ctx context.Context) (map[string][]kube.PolicyReportResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+
+	vulnerabilityData, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting vulnerability reports: %w", err)
+	}
+	configAuditData, err := kube.GetConfigAuditReportList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting config audit reports: %w", err)
+	}
+	rbacData, err := kube.GetRbacAssessmentReportList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting rbac assessment reports: %w", err)
+	}
+	clusterRbacData, err := kube.GetClusterRbacAssessmentReportList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting cluster rbac assessment reports: %w", err)
+	}
+	exposedSecretData, err := kube.GetExposedSecretReportList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting exposed secret reports: %w", err)
+	}
+
+	reports := make(map[string][]kube.PolicyReportResult)
+	add := func(namespace string, result kube.PolicyReportResult) {
+		reports[namespace] = append(reports[namespace], result)
+	}
+
+	// The images view leaves out ignored vulnerabilities, so approved ignores aren't published
+	for _, result := range vulnerabilityPolicyResults(imagesview.GetView(vulnerabilityData, nil, imagesview.Filters{})) {
+		add(result.Resources[0].Namespace, result)
+	}
+
+	for _, item := range configAuditData.Items {
+		object := reportObject(item.ObjectMeta)
+		for _, check := range item.Report.Checks {
+			if !check.Success {
+				add(object.Namespace, checkPolicyResult(policyReportCategoryConfigAudit, check, object))
+			}
+		}
+	}
+
+	for _, item := range rbacData.Items {
+		object := reportObject(item.ObjectMeta)
+		for _, check := range item.Report.Checks {
+			if !check.Success {
+				add(object.Namespace, checkPolicyResult(policyReportCategoryRbac, check, object))
+			}
+		}
+	}
+
+	for _, item := range clusterRbacData.Items {
+		object := reportObject(item.ObjectMeta)
+		object.APIVersion = "rbac.authorization.k8s.io/v1"
+		for _, check := range item.Report.Checks {
+			if !check.Success {
+				add("", checkPolicyResult(policyReportCategoryRbac, check, object))
+			}
+		}
+	}
+
+	for _, item := range exposedSecretData.Items {
+		object := reportObject(item.ObjectMeta)
+		image := utils.AssembleImageFullName(utils.FormatPrettyImageRegistry(item.Report.Registry.Server), utils.FormatPrettyImageRepo(item.Report.Artifact.Repository), item.Report.Artifact.Tag, "")
+		for _, s := range item.Report.Secrets {
+			// The secret's match is left out, so publishing a report doesn't spread the secret further
+			add(object.Namespace, kube.PolicyReportResult{
+				Source:   policyReportSource,
+				Policy:   s.RuleID,
+				Rule:     s.Title,
+				Category: policyReportCategoryExposedSecret,
+				Severity: policyReportSeverity(string(s.Severity)),
+				Result:   "fail",
+				Message:  fmt.Sprintf("%s found in %s of image %s", s.Title, s.Target, image),
+				Properties: map[string]string{
+					"image":  image,
+					"target": s.Target,
+				},
+				Resources: []corev1.ObjectReference{object},
+			})
+		}
+	}
+
+	for namespace, results := range reports {
+		reports[namespace] = sortPolicyReportResults(namespace, results)
+	}
+	return reports, nil
+}
+
+// vulnerabilityPolicyResults returns a result per vulnerable package in each image, per namespace running the image
+func vulnerabilityPolicyResults(images imagesview.View) []kube.PolicyReportResult {
+	var results []kube.PolicyReportResult
+	for _, image := range images {
+		if image.Unscanned {
+			continue
+		}
+		name := utils.AssembleImageFullName(image.Registry, image.Name, image.Tag, "")
+
+		// A result lists every workload in its namespace running the image
+		resources := make(map[string][]corev1.ObjectReference)
+		for r := range image.Resources {
+			resources[r.Namespace] = append(resources[r.Namespace], kube.ObjectReference(r.Kind, r.Namespace, r.Name))
+		}
+
+		var vulnerabilities []imagesview.Vulnerability
+		vulnerabilities = append(vulnerabilities, image.CriticalVulnerabilities...)
+		vulnerabilities = append(vulnerabilities, image.HighVulnerabilities...)
+		vulnerabilities = append(vulnerabilities, image.MediumVulnerabilities...)
+		vulnerabilities = append(vulnerabilities, image.LowVulnerabilities...)
+
+		for _, objects := range resources {
+			sort.SliceStable(objects, func(j, k int) bool {
+				return objects[j].Kind+"/"+objects[j].Name < objects[k].Kind+"/"+objects[k].Name
+			})
+			for _, v := range vulnerabilities {
+				properties := map[string]string{
+					"image":            name,
+					"package":          v.Resource,
+					"installedVersion": v.VulnerableVersion,
+				}
+				if v.FixedVersion != "" {
+					properties["fixedVersion"] = v.FixedVersion
+				}
+				if v.Score > 0 {
+					properties["score"] = strconv.FormatFloat(v.Score, 'f', 1, 64)
+				}
+				if v.URL != "" {
+					properties["url"] = v.URL
+				}
+				message := v.Title
+				if message == "" {
+					message = fmt.Sprintf("%s in %s %s", v.ID, v.Resource, v.VulnerableVersion)
+				}
+
+				results = append(results, kube.PolicyReportResult{
+					Source:     policyReportSource,
+					Policy:     v.ID,
+					Rule:       v.Resource,
+					Category:   policyReportCategoryVulnerability,
+					Severity:   policyReportSeverity(v.Severity),
+					Result:     "fail",
+					Message:    message,
+					Properties: properties,
+					Resources:  append([]corev1.ObjectReference(nil), objects...),
+				})
+			}
+		}
+	}
+	return results
+}
+
+// checkPolicyResult returns the result of a failed config audit or RBAC assessment check on an object
+func checkPolicyResult(category string, check v1alpha1.Check, object corev1.ObjectReference) kube.PolicyReportResult {
+	message := strings.Join(check.Messages, "; ")
+	if message == "" {
+		message = check.Description
+	}
+	result := kube.PolicyReportResult{
+		Source:    policyReportSource,
+		Policy:    check.ID,
+		Rule:      check.Title,
+		Category:  category,
+		Severity:  policyReportSeverity(string(check.Severity)),
+		Result:    "fail",
+		Message:   message,
+		Resources: []corev1.ObjectReference{object},
+	}
+	if check.Remediation != "" {
+		result.Properties = map[string]string{"remediation": check.Remediation}
+	}
+	return result
+}
+
+// reportObject returns a reference to the object a Trivy Operator report is about, from its labels
+// Long resource names are truncated in labels, so the annotation is preferred when present
+func reportObject(meta metav1.ObjectMeta) corev1.ObjectReference {
+	name := meta.Labels["trivy-operator.resource.name"]
+	if val, ok := meta.Annotations["trivy-operator.resource.name"]; ok {
+		name = val
+	} else if name == "" {
+		name = meta.Name
+	}
+	return kube.ObjectReference(meta.Labels["trivy-operator.resource.kind"], meta.Labels["trivy-operator.resource.namespace"], name)
+}
+
+// policyReportSeverity converts a Trivy severity to a Policy Reports severity, which are lowercase and have no unknown severity
+func policyReportSeverity(severity string) string {
+	switch s := strings.ToLower(severity); s {
+	case "critical", "high", "medium", "low":
+		return s
+	default:
+		return "info"
+	}
+}
+
+var policyReportSeverityRank = map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3, "info": 4}
+
+// sortPolicyReportResults sorts a report's results by severity so the same results are published each interval, keeping the most severe when there are too many
+func sortPolicyReportResults(namespace string, results []kube.PolicyReportResult) []kube.PolicyReportResult {
+	keys := make([]string, len(results))
+	for i, r := range results {
+		keys[i] = policyReportResultKey(r)
+	}
+	sort.Sort(policyReportResultSorter{results: results, keys: keys})
+
+	if len(results) > maxPolicyReportResults {
+		log.Logger.Warn("policy report has more results than can be published, the least severe are left out", "namespace", namespace, "results", len(results), "limit", maxPolicyReportResults)
+		results = results[:maxPolicyReportResults]
+	}
+	return results
+}
+
+// policyReportResultSorter sorts results by severity, then by their keys
+type policyReportResultSorter struct {
+	results []kube.PolicyReportResult
+	keys    []string
+}
+
+func (s policyReportResultSorter) Len() int { return len(s.results) }
+
+func (s policyReportResultSorter) Less(j, k int) bool {
+	rj, rk := policyReportSeverityRank[s.results[j].Severity], policyReportSeverityRank[s.results[k].Severity]
+	if rj != rk {
+		return rj < rk
+	}
+	return s.keys[j] < s.keys[k]
+}
+
+func (s policyReportResultSorter) Swap(j, k int) {
+	s.results[j], s.results[k] = s.results[k], s.results[j]
+	s.keys[j], s.keys[k] = s.keys[k], s.keys[j]
+}
+
+// policyReportResultKey identifies a result by everything but its timestamp
+func policyReportResultKey(r kube.PolicyReportResult) string {
+	r.Timestamp = metav1.Timestamp{}
+	b, err := json.Marshal(r)
+	if err != nil {
+		// Results are plain strings and maps, so this doesn't happen in practice
+		return r.Category + "/" + r.Policy + "/" + r.Rule
+	}
+	return string(b)
+}
+
+// withPolicyReportTimestamps timestamps the results, results that were already published keep their timestamp so it shows when the finding was first published
+func withPolicyReportTimestamps(results, published []kube.PolicyReportResult, now time.Time) []kube.PolicyReportResult {
+	timestamps := make(map[string]metav1.Timestamp, len(published))
+	for _, r := range published {
+		timestamps[policyReportResultKey(r)] = r.Timestamp
+	}
+
+	stamped := make([]kube.PolicyReportResult, len(results))
+	for i, r := range results {
+		if ts, ok := timestamps[policyReportResultKey(r)]; ok {
+			r.Timestamp = ts
+		} else {
+			r.Timestamp = metav1.Timestamp{Seconds: now.Unix()}
+		}
+		stamped[i] = r
+	}
+	return stamped
+}
+
+// policyReportResultsEqual returns true if two lists of results would publish the same report
+func policyReportResultsEqual(a, b []kube.PolicyReportResult) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+// newPolicyReport returns the report published in a namespace, or the ClusterPolicyReport when the namespace is empty
+func newPolicyReport(namespace string, results []kube.PolicyReportResult) kube.PolicyReport {
+	report := kube.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      policyReportName,
+			Namespace: namespace,
+			Labels:    map[string]string{policyReportManagedByLabel: policyReportName},
+		},
+		Results: results,
+	}
+	// Every result is a failed finding, passing checks aren't published
+	report.Summary.Fail = len(results)
+	return report
+}
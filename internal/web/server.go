@@ -1,276 +1,1852 @@
 package web
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
 	"github.com/starttoaster/trivy-operator-explorer/internal/db"
 	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
 	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
 	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
 	"github.com/starttoaster/trivy-operator-explorer/internal/web/content"
+	changesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/changes"
 	clusterauditview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/clusteraudit"
 	clusterauditsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/clusteraudits"
 	clusterroleview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/clusterrole"
 	clusterrolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/clusterroles"
+	compareview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/compare"
 	complianceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/compliance"
 	configauditview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/configaudit"
 	configauditsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/configaudits"
+	cveview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/cve"
 	exposedsecretview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/exposedsecret"
 	exposedsecretsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/exposedsecrets"
 	imageview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/image"
 	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
 	indexview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/index"
+	mttrview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/mttr"
+	namespaceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/namespace"
+	namespacesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/namespaces"
+	packagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/packages"
+	remediationview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/remediation"
 	roleview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/role"
 	rolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/roles"
+	searchview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/search"
+	slaview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/sla"
+	workloadview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/workload"
 )
 
-// Start starts the webserver
-func Start(port string) error {
+// Config contains the settings for the webserver
+type Config struct {
+	Port string
+	// OpsPort is the port for the operational endpoints like health checks, served on a separate listener without auth
+	OpsPort string
+	Auth    AuthConfig
+	// TrustedOrigins are origins other than the server's own host allowed to make mutating requests from a browser
+	TrustedOrigins []string
+	// MaxBodyBytes limits the request body size of mutating requests
+	MaxBodyBytes int64
+	Timeouts     TimeoutConfig
+	// KubeRequestTimeout bounds the Kubernetes API calls made while serving a single page, including retries
+	KubeRequestTimeout time.Duration
+	// TLSCertFile and TLSKeyFile enable TLS on the UI listener when both are set, the files are reloaded when they change
+	TLSCertFile   string
+	TLSKeyFile    string
+	Snapshots     SnapshotConfig
+	SLA           SLAConfig
+	Notifications NotificationConfig
+	Digest        DigestConfig
+	Tickets       TicketConfig
+	KubeEvents    KubeEventsConfig
+	PolicyReports PolicyReportsConfig
+	Admission     AdmissionConfig
+	// ExternalURL is the URL users reach the explorer at, used to link to it from notifications, digests and tickets
+	ExternalURL string
+}
+
+// slaConfig contains the parsed SLA policies, it's set from the server config
+var slaConfig slaview.Config
+
+// SLAConfig contains the remediation SLA settings
+type SLAConfig struct {
+	// Policies is a YAML or JSON list of SLA policies, SLAs aren't evaluated when it's empty
+	Policies string
+	// Warning is how long before its deadline a finding is listed as due soon
+	Warning time.Duration
+	// OwnerLabel is the namespace label naming the team that owns the namespace's findings
+	OwnerLabel string
+}
+
+// TimeoutConfig contains the http server timeouts
+type TimeoutConfig struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	// Shutdown is how long in-flight requests are given to finish after a shutdown signal
+	Shutdown time.Duration
+}
+
+// Start starts the webserver, and blocks until the context is done and in-flight requests are drained
+func Start(ctx context.Context, config Config) error {
+	auth, err := newAuthenticator(config.Auth)
+	if err != nil {
+		return err
+	}
+	if config.MaxBodyBytes <= 0 {
+		return fmt.Errorf("max request body size must be greater than 0, got %d", config.MaxBodyBytes)
+	}
+	if config.KubeRequestTimeout <= 0 {
+		return fmt.Errorf("kube request timeout must be greater than 0, got %s", config.KubeRequestTimeout)
+	}
+	kubeRequestTimeout = config.KubeRequestTimeout
+	if config.OpsPort == config.Port {
+		return fmt.Errorf("the ops port must be different from the server port, both are %s", config.Port)
+	}
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return fmt.Errorf("both a TLS certificate and key file are required to enable TLS")
+	}
+	policies, err := slaview.ParsePolicies(config.SLA.Policies)
+	if err != nil {
+		return err
+	}
+	if config.SLA.Warning < 0 {
+		return fmt.Errorf("SLA warning period must not be negative, got %s", config.SLA.Warning)
+	}
+	slaConfig = slaview.Config{
+		Policies:   policies,
+		Warning:    config.SLA.Warning,
+		OwnerLabel: config.SLA.OwnerLabel,
+	}
+	if config.Snapshots.Interval < 0 || config.Snapshots.Retention < 0 {
+		return fmt.Errorf("snapshot interval and retention must not be negative, got %s and %s", config.Snapshots.Interval, config.Snapshots.Retention)
+	}
+	if config.Snapshots.LedgerInterval < 0 {
+		return fmt.Errorf("ledger interval must not be negative, got %s", config.Snapshots.LedgerInterval)
+	}
+	if config.ExternalURL != "" {
+		u, err := url.Parse(config.ExternalURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("external URL must be an absolute http or https URL, got %q", config.ExternalURL)
+		}
+		config.ExternalURL = strings.TrimSuffix(config.ExternalURL, "/")
+	}
+	notifications, err = newNotificationWatcher(config.Notifications, config.ExternalURL)
+	if err != nil {
+		return err
+	}
+	digests, err = newDigester(config.Digest, config.ExternalURL)
+	if err != nil {
+		return err
+	}
+	ticketing, err = newTicketer(config.Tickets, config.ExternalURL)
+	if err != nil {
+		return err
+	}
+	if ticketing != nil && config.Tickets.Automatic && config.Snapshots.Interval <= 0 {
+		return fmt.Errorf("automatic tickets need snapshots enabled, new vulnerabilities are found through the findings ledger they update")
+	}
+	if config.KubeEvents.Enabled && config.KubeEvents.Interval <= 0 {
+		return fmt.Errorf("kubernetes events interval must be greater than 0, got %s", config.KubeEvents.Interval)
+	}
+	if config.PolicyReports.Enabled && config.PolicyReports.Interval <= 0 {
+		return fmt.Errorf("policy reports interval must be greater than 0, got %s", config.PolicyReports.Interval)
+	}
+	reviewer, err := newAdmissionReviewer(config.Admission, config.ExternalURL)
+	if err != nil {
+		return err
+	}
+	if reviewer != nil && (config.Admission.Port == config.Port || config.Admission.Port == config.OpsPort) {
+		return fmt.Errorf("the admission webhook port must be different from the server and ops ports, got %s", config.Admission.Port)
+	}
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", indexHandler)
-	mux.HandleFunc("/images", imagesHandler)
-	mux.HandleFunc("/image", imageHandler)
-	mux.HandleFunc("/ignore", ignoreHandler)
-	mux.HandleFunc("/ignore/bulk", bulkIgnoreHandler)
-	mux.HandleFunc("/configaudits", configauditsHandler)
-	mux.HandleFunc("/configaudit", configauditHandler)
-	mux.HandleFunc("/clusteraudits", clusterauditsHandler)
-	mux.HandleFunc("/clusteraudit", clusterauditHandler)
-	mux.HandleFunc("/clusterroles", clusterrolesHandler)
-	mux.HandleFunc("/clusterrole", clusterroleHandler)
-	mux.HandleFunc("/exposedsecrets", exposedsecretsHandler)
-	mux.HandleFunc("/exposedsecret", exposedsecretHandler)
-	mux.HandleFunc("/roles", rolesHandler)
-	mux.HandleFunc("/role", roleHandler)
-	mux.HandleFunc("/compliancereports", complianceReportsHandler)
-	mux.HandleFunc("/compliancereport", complianceReportHandler)
+	mux.HandleFunc("/", requireScope(db.ScopeRead, indexHandler))
+	mux.HandleFunc("/images", requireScope(db.ScopeRead, imagesHandler))
+	mux.HandleFunc("/image", requireScope(db.ScopeRead, imageHandler))
+	mux.HandleFunc("/api/image", requireScope(db.ScopeRead, imageAPIHandler))
+	mux.HandleFunc("/packages", requireScope(db.ScopeRead, packagesHandler))
+	mux.HandleFunc("/remediation", requireScope(db.ScopeRead, remediationHandler))
+	mux.HandleFunc("/mttr", requireScope(db.ScopeRead, mttrHandler))
+	mux.HandleFunc("/sla", requireScope(db.ScopeRead, slaHandler))
+	mux.HandleFunc("/api/sla", requireScope(db.ScopeRead, slaAPIHandler))
+	mux.HandleFunc("/changes", requireScope(db.ScopeRead, changesHandler))
+	mux.HandleFunc("/api/changes", requireScope(db.ScopeRead, changesAPIHandler))
+	mux.HandleFunc("/namespaces", requireScope(db.ScopeRead, namespacesHandler))
+	mux.HandleFunc("/namespace", requireScope(db.ScopeRead, namespaceHandler))
+	mux.HandleFunc("/workload", requireScope(db.ScopeRead, workloadHandler))
+	mux.HandleFunc("/compare", requireScope(db.ScopeRead, compareHandler))
+	mux.HandleFunc("/api/compare", requireScope(db.ScopeRead, compareAPIHandler))
+	mux.HandleFunc("/search", requireScope(db.ScopeRead, searchHandler))
+	mux.HandleFunc("/api/search", requireScope(db.ScopeRead, searchAPIHandler))
+	mux.HandleFunc("/ignore", requireScope(db.ScopeIgnoreWrite, requireContentType("application/json", ignoreHandler)))
+	mux.HandleFunc("/ignore/bulk", requireScope(db.ScopeIgnoreWrite, requireContentType("application/json", bulkIgnoreHandler)))
+	mux.HandleFunc("/ignore/cve", requireScope(db.ScopeIgnoreWrite, requireContentType("application/json", cveIgnoreHandler)))
+	mux.HandleFunc("/cve", requireScope(db.ScopeRead, cveHandler))
+	mux.HandleFunc("/api/cve", requireScope(db.ScopeRead, cveAPIHandler))
+	mux.HandleFunc("/configaudits", requireScope(db.ScopeRead, configauditsHandler))
+	mux.HandleFunc("/configaudit", requireScope(db.ScopeRead, configauditHandler))
+	mux.HandleFunc("/clusteraudits", requireScope(db.ScopeRead, clusterauditsHandler))
+	mux.HandleFunc("/clusteraudit", requireScope(db.ScopeRead, clusterauditHandler))
+	mux.HandleFunc("/clusterroles", requireScope(db.ScopeRead, clusterrolesHandler))
+	mux.HandleFunc("/clusterrole", requireScope(db.ScopeRead, clusterroleHandler))
+	mux.HandleFunc("/exposedsecrets", requireScope(db.ScopeRead, exposedsecretsHandler))
+	mux.HandleFunc("/exposedsecret", requireScope(db.ScopeRead, exposedsecretHandler))
+	mux.HandleFunc("/roles", requireScope(db.ScopeRead, rolesHandler))
+	mux.HandleFunc("/role", requireScope(db.ScopeRead, roleHandler))
+	mux.HandleFunc("/compliancereports", requireScope(db.ScopeRead, complianceReportsHandler))
+	mux.HandleFunc("/compliancereport", requireScope(db.ScopeRead, complianceReportHandler))
+	mux.HandleFunc("/tokens", requireTokenManagement(requireContentType("application/x-www-form-urlencoded", tokensHandler)))
+	mux.HandleFunc("/tokens/revoke", requireTokenManagement(requireContentType("application/x-www-form-urlencoded", revokeTokenHandler)))
+	mux.HandleFunc("/api/notifications/test", requireScope(db.ScopeAdmin, notificationTestHandler))
+	mux.HandleFunc("/digest/preview", requireScope(db.ScopeAdmin, digestPreviewHandler))
+	mux.HandleFunc("/api/digest/send", requireScope(db.ScopeAdmin, digestSendHandler))
+	mux.HandleFunc("/ticket", requireScope(db.ScopeIgnoreWrite, requireContentType("application/json", ticketHandler)))
 	// TODO just serve the js and css directories in static
 	// this serves the html templates for no reason
 	mux.Handle("/static/", http.FileServer(http.FS(content.Static)))
-	return http.ListenAndServe(fmt.Sprintf(":%s", port), mux)
+
+	// Middleware runs outermost first: authenticate the user, limit the body size, check CSRF tokens for browser sessions,
+	// then collect partial failures so pages can name the data they're missing
+	csrf := newCSRFProtection(config.TrustedOrigins)
+	handler := auth.middleware(limitBody(config.MaxBodyBytes, csrf.middleware(partialFailuresMiddleware(mux))))
+
+	// Operational endpoints are served on their own listener so they're reachable by probes without auth
+	opsMux := http.NewServeMux()
+	opsMux.HandleFunc("/healthz", livenessHandler)
+	opsMux.HandleFunc("/readyz", readinessHandler)
+	opsMux.HandleFunc("/metrics", metricsHandler)
+
+	uiServer := newServer(config.Port, handler, config.Timeouts)
+	opsServer := newServer(config.OpsPort, opsMux, config.Timeouts)
+
+	// The admission webhook is served on its own TLS listener, it's called by the API server rather than users so it skips the UI's auth
+	var admissionServer *http.Server
+	if reviewer != nil {
+		admissionMux := http.NewServeMux()
+		admissionMux.Handle(admissionPath, reviewer)
+		admissionServer = newServer(config.Admission.Port, admissionMux, config.Timeouts)
+	}
+
+	// Context for background work tied to the server's lifetime, such as watching TLS certificates, taking snapshots, notifying webhooks and sending digests
+	serverCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Workers are waited on before returning, so the database isn't closed while they're still writing to it
+	var workers sync.WaitGroup
+	startWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(serverCtx)
+		}()
+	}
+
+	var certs *certReloader
+	if config.TLSCertFile != "" {
+		certs, err = newCertReloader(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		uiServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+		startWorker(certs.watch)
+	}
+
+	if admissionServer != nil {
+		admissionCerts, err := newCertReloader(config.Admission.TLSCertFile, config.Admission.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		admissionServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: admissionCerts.GetCertificate,
+		}
+		startWorker(admissionCerts.watch)
+	}
+
+	if config.Snapshots.Interval > 0 {
+		s := &snapshotter{config: config.Snapshots}
+		startWorker(s.run)
+	} else if config.Snapshots.LedgerInterval > 0 {
+		l := &ledgerUpdater{interval: config.Snapshots.LedgerInterval}
+		startWorker(l.run)
+		log.Logger.Info("snapshots disabled, trend charts will not be updated, the findings ledgers are updated on their own interval", "interval", config.Snapshots.LedgerInterval)
+	} else {
+		log.Logger.Warn("snapshots and ledger updates disabled, trend charts, first seen times, ages, MTTR and SLAs will not be updated")
+	}
+
+	if notifications != nil && config.Notifications.Interval > 0 {
+		startWorker(notifications.run)
+		log.Logger.Info("notifying webhooks about new findings", "webhooks", len(notifications.webhooks), "interval", config.Notifications.Interval)
+	} else {
+		log.Logger.Info("notifications disabled, no webhooks will be notified about new findings")
+	}
+
+	if digests != nil {
+		startWorker(digests.run)
+		log.Logger.Info("emailing scheduled digests", "recipients", len(digests.recipients), "schedule", digests.schedule.String(), "time_zone", time.Local.String())
+	} else {
+		log.Logger.Info("digests disabled, no digest emails will be sent")
+	}
+
+	if ticketing != nil {
+		startWorker(ticketing.run)
+		log.Logger.Info("creating tickets in jira", "project", ticketing.client.Project(), "automatic", config.Tickets.Automatic, "interval", config.Tickets.Interval)
+	} else {
+		log.Logger.Info("tickets disabled, no jira issues will be created")
+	}
+
+	if config.KubeEvents.Enabled {
+		e := &kubeEventEmitter{config: config.KubeEvents}
+		startWorker(e.run)
+		log.Logger.Info("emitting kubernetes events for new findings", "interval", config.KubeEvents.Interval)
+	} else {
+		log.Logger.Info("kubernetes events disabled, no events will be emitted for new findings")
+	}
+
+	if config.PolicyReports.Enabled {
+		e := &policyReportExporter{config: config.PolicyReports}
+		startWorker(e.run)
+		log.Logger.Info("publishing findings as policy reports", "api_version", kube.PolicyReportAPIVersion, "interval", config.PolicyReports.Interval)
+	} else {
+		log.Logger.Info("policy reports disabled, findings won't be published as policy reports")
+	}
+
+	if reviewer != nil {
+		l := &admissionLoader{reviewer: reviewer, interval: config.Admission.Interval}
+		startWorker(l.run)
+	}
+
+	errCh := make(chan error, 3)
+	go func() {
+		log.Logger.Info("starting ui server", "port", config.Port, "tls", certs != nil)
+		if certs != nil {
+			// Certificates come from the TLS config's GetCertificate callback
+			errCh <- uiServer.ListenAndServeTLS("", "")
+		} else {
+			errCh <- uiServer.ListenAndServe()
+		}
+	}()
+	go func() {
+		log.Logger.Info("starting ops server", "port", config.OpsPort)
+		errCh <- opsServer.ListenAndServe()
+	}()
+	if admissionServer != nil {
+		go func() {
+			log.Logger.Info("starting admission webhook server", "port", config.Admission.Port, "path", admissionPath, "mode", config.Admission.Mode, "exempt_namespaces", config.Admission.ExemptNamespaces)
+			errCh <- admissionServer.ListenAndServeTLS("", "")
+		}()
+	} else {
+		log.Logger.Info("admission webhook disabled, objects won't be reviewed")
+	}
+
+	// Wait for a shutdown signal, or for any server to fail
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		log.Logger.Info("shutdown signal received, draining in-flight requests", "timeout", config.Timeouts.Shutdown)
+	case serveErr = <-errCh:
+		log.Logger.Error("server stopped unexpectedly, shutting down", "error", serveErr)
+	}
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), config.Timeouts.Shutdown)
+	defer shutdownCancel()
+	shutdownErr := errors.Join(uiServer.Shutdown(shutdownCtx), opsServer.Shutdown(shutdownCtx))
+	if admissionServer != nil {
+		shutdownErr = errors.Join(shutdownErr, admissionServer.Shutdown(shutdownCtx))
+	}
+	if shutdownErr != nil {
+		log.Logger.Error("error shutting down servers", "error", shutdownErr)
+	}
+
+	// Background workers share the shutdown timeout with the servers
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		log.Logger.Error("timed out waiting for background workers to stop", "timeout", config.Timeouts.Shutdown)
+		shutdownErr = errors.Join(shutdownErr, fmt.Errorf("timed out waiting for background workers to stop after %s", config.Timeouts.Shutdown))
+	}
+
+	if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+		return serveErr
+	}
+	return shutdownErr
+}
+
+// newServer creates an http server listening on the given port with the configured timeouts
+func newServer(port string, handler http.Handler, timeouts TimeoutConfig) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%s", port),
+		Handler:           handler,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+	}
+}
+
+// newTemplate parses a page template from the static directory along with the sidebar, and registers the template helper functions for this request
+func newTemplate(r *http.Request, page string) *template.Template {
+	funcMap := template.FuncMap{
+		"sanitizeID": func(s string) string {
+			replacer := strings.NewReplacer("/", "_", ":", "_", " ", "_", "-", "_", ".", "_")
+			return replacer.Replace(s)
+		},
+		"csrfToken": func() string {
+			return csrfTokenFromRequest(r)
+		},
+		"currentUser": func() *User {
+			if user, ok := userFromRequest(r); ok {
+				return &user
+			}
+			return nil
+		},
+		"partialFailures": func() []PartialFailure {
+			return partialFailuresFromRequest(r)
+		},
+		"inc": func(i int) int {
+			return i + 1
+		},
+		"age": func(t time.Time) string {
+			return utils.FormatDuration(time.Since(t))
+		},
+	}
+
+	return template.Must(template.New(page).Funcs(funcMap).ParseFS(content.Static, fmt.Sprintf("static/%s", page), "static/sidebar.html", "static/banner.html"))
+}
+
+func indexHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "index.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing index html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	// The dashboard renders whatever data it can get, and names the report kinds that failed in a banner
+	vulnerabilityData, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "VulnerabilityReports", "image vulnerability totals are missing", err)
+		vulnerabilityData = &v1alpha1.VulnerabilityReportList{}
+	}
+	imagesView := imagesview.GetView(vulnerabilityData, nil, imagesview.Filters{})
+
+	// Get compliance reports
+	complianceData, err := kube.GetComplianceReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "ClusterComplianceReports", "compliance report summaries are missing", err)
+		complianceData = &v1alpha1.ClusterComplianceReportList{}
+	}
+	complianceView := complianceview.GetView(complianceData)
+
+	// Get index view
+	indexData := indexview.GetView(imagesView, complianceView)
+
+	// Get trends from snapshots
+	indexData.Trend, err = db.GetClusterTrend()
+	if err != nil {
+		recordPartialFailure(r, "Snapshots", "the vulnerability trend chart is missing", err)
+	}
+	indexData.ComplianceTrends, err = db.GetComplianceTrends()
+	if err != nil {
+		recordPartialFailure(r, "Snapshots", "the compliance trend charts are missing", err)
+	}
+
+	err = tmpl.Execute(w, indexData)
+	if err != nil {
+		log.Logger.Error("encountered error executing index html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+func imagesHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "images.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing images html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Parse URL query params
+	q := r.URL.Query()
+
+	// Check query params
+	hasFix := q.Get("hasfix")
+	var hasFixBool bool
+	if hasFix != "" {
+		var err error
+		hasFixBool, err = strconv.ParseBool(hasFix)
+		if err != nil {
+			log.Logger.Warn("could not parse hasfix query parameter to bool type, ignoring filter", "raw", hasFix, "error", err.Error())
+		}
+	}
+
+	showIgnored := q.Get("showignored")
+	var showIgnoredBool bool
+	if showIgnored != "" {
+		var err error
+		showIgnoredBool, err = strconv.ParseBool(showIgnored)
+		if err != nil {
+			log.Logger.Warn("could not parse showignored query parameter to bool type, ignoring filter", "raw", showIgnored, "error", err.Error())
+		}
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		renderKubeError(w, r, "VulnerabilityReports", err)
+		return
+	}
+	// Get total images map -- we don't return here if we get an error because it's for optional helpful data
+	imagesMap, err := kube.GetContainerImagesMap(ctx)
+	if err != nil {
+		recordPartialFailure(r, "Pods", "running images without a vulnerability report are hidden", err)
+	}
+
+	imageData := imagesview.GetView(data, imagesMap, imagesview.Filters{
+		HasFix:      hasFixBool,
+		ShowIgnored: showIgnoredBool,
+	})
+	firstSeen, err := db.GetOpenFindingsFirstSeen("", "")
+	if err != nil {
+		recordPartialFailure(r, "Findings", "the age of each image's oldest finding is missing", err)
+	}
+	imagesview.SetFirstSeen(imageData, firstSeen)
+	page := imagesview.GetPage(imageData, parseImagesQuery(q))
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute   string
+		Data        imagesview.Page
+		HasFix      bool
+		ShowIgnored bool
+		Severities  []string
+		SortOptions []string
+		PrevPageURL string
+		NextPageURL string
+	}{
+		PageRoute:   "images",
+		Data:        page,
+		HasFix:      hasFixBool,
+		ShowIgnored: showIgnoredBool,
+		Severities:  imagesview.Severities,
+		SortOptions: imagesview.SortOptions,
+		PrevPageURL: pageURL(r, page.Query.Page-1),
+		NextPageURL: pageURL(r, page.Query.Page+1),
+	}
+
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing images html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseImagesQuery parses the filter, sort and paging query parameters of the images page
+// Values that can't be parsed are logged and ignored, like the page's other filters
+func parseImagesQuery(q url.Values) imagesview.Query {
+	query := imagesview.Query{
+		Namespace:   q.Get("namespace"),
+		Registry:    q.Get("registry"),
+		MinSeverity: q.Get("minseverity"),
+		Sort:        q.Get("sort"),
+	}
+
+	if raw := q.Get("minscore"); raw != "" {
+		minScore, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			log.Logger.Warn("could not parse minscore query parameter to float type, ignoring filter", "raw", raw, "error", err.Error())
+		} else {
+			query.MinScore = minScore
+		}
+	}
+
+	for param, dest := range map[string]*bool{"eosl": &query.EOSLOnly, "unscanned": &query.UnscannedOnly} {
+		raw := q.Get(param)
+		if raw == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			log.Logger.Warn("could not parse "+param+" query parameter to bool type, ignoring filter", "raw", raw, "error", err.Error())
+			continue
+		}
+		*dest = b
+	}
+
+	for param, dest := range map[string]*int{"page": &query.Page, "pagesize": &query.PageSize} {
+		raw := q.Get(param)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			log.Logger.Warn("could not parse "+param+" query parameter to int type, using the default", "raw", raw, "error", err.Error())
+			continue
+		}
+		*dest = n
+	}
+
+	return query
+}
+
+// pageURL returns the request's URL with its page query parameter replaced
+func pageURL(r *http.Request, page int) string {
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	return r.URL.Path + "?" + q.Encode()
+}
+
+func packagesHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "packages.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing packages html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Parse URL query params
+	q := r.URL.Query()
+
+	// Check query params
+	showIgnored := q.Get("showignored")
+	var showIgnoredBool bool
+	if showIgnored != "" {
+		var err error
+		showIgnoredBool, err = strconv.ParseBool(showIgnored)
+		if err != nil {
+			log.Logger.Warn("could not parse showignored query parameter to bool type, ignoring filter", "raw", showIgnored, "error", err.Error())
+		}
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		renderKubeError(w, r, "VulnerabilityReports", err)
+		return
+	}
+
+	packagesData := packagesview.GetView(data, packagesview.Filters{
+		ShowIgnored: showIgnoredBool,
+		Name:        q.Get("name"),
+	})
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute   string
+		ShowIgnored bool
+		Name        string
+		Data        packagesview.View
+	}{
+		PageRoute:   "packages",
+		ShowIgnored: showIgnoredBool,
+		Name:        q.Get("name"),
+		Data:        packagesData,
+	}
+
+	// Execute html template
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing packages html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+func remediationHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse URL query params
+	q := r.URL.Query()
+	image := strings.TrimSpace(q.Get("image"))
+	format := q.Get("format")
+	if format != "" && format != "markdown" && format != "csv" {
+		http.Error(w, "Invalid format, must be one of markdown, csv", http.StatusBadRequest)
+		return
+	}
+
+	// Check query params
+	showIgnored := q.Get("showignored")
+	var showIgnoredBool bool
+	if showIgnored != "" {
+		var err error
+		showIgnoredBool, err = strconv.ParseBool(showIgnored)
+		if err != nil {
+			log.Logger.Warn("could not parse showignored query parameter to bool type, ignoring filter", "raw", showIgnored, "error", err.Error())
+		}
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		if format != "" {
+			writeKubeAPIError(w, r, "VulnerabilityReports", err)
+			return
+		}
+		renderKubeError(w, r, "VulnerabilityReports", err)
+		return
+	}
+
+	view := remediationview.GetView(packagesview.GetView(data, packagesview.Filters{
+		ShowIgnored: showIgnoredBool,
+		Image:       image,
+	}), image)
+
+	switch format {
+	case "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="remediation-plan.md"`)
+		if err := view.WriteMarkdown(w); err != nil {
+			log.Logger.Error("encountered error writing remediation markdown", "error", err)
+		}
+		return
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="remediation-plan.csv"`)
+		if err := view.WriteCSV(w); err != nil {
+			log.Logger.Error("encountered error writing remediation csv", "error", err)
+		}
+		return
+	}
+
+	tmpl := newTemplate(r, "remediation.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing remediation html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute   string
+		ShowIgnored bool
+		Images      []string
+		Data        remediationview.View
+	}{
+		PageRoute:   "remediation",
+		ShowIgnored: showIgnoredBool,
+		Images:      reportImageNames(data),
+		Data:        view,
+	}
+
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing remediation html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+func mttrHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "mttr.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing mttr html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Get findings ledger
+	findings, err := db.GetFindings()
+	if err != nil {
+		log.Logger.Error("error getting findings", "error", err.Error())
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute string
+		Data      mttrview.View
+	}{
+		PageRoute: "mttr",
+		Data:      mttrview.GetView(findings, time.Now()),
+	}
+
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing mttr html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+func slaHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "sla.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing sla html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		renderKubeError(w, r, "VulnerabilityReports", err)
+		return
+	}
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute  string
+		Warning    string
+		OwnerLabel string
+		Data       slaview.View
+	}{
+		PageRoute:  "sla",
+		Warning:    utils.FormatDuration(slaConfig.Warning),
+		OwnerLabel: slaConfig.OwnerLabel,
+		Data:       getSLAView(ctx, r, data),
+	}
+
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing sla html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+func slaAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		writeKubeAPIError(w, r, "VulnerabilityReports", err)
+		return
+	}
+	view := getSLAView(ctx, r, data)
+
+	// Name the data that failed to load, since findings may be missing or evaluated against the wrong policy
+	response := struct {
+		slaview.View
+		Breached        int              `json:"breached"`
+		DueSoon         int              `json:"due_soon"`
+		PartialFailures []PartialFailure `json:"partial_failures,omitempty"`
+	}{
+		View:            view,
+		Breached:        view.Breached(),
+		DueSoon:         view.DueSoon(),
+		PartialFailures: partialFailuresFromRequest(r),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Logger.Error("encountered error encoding sla json response", "error", err)
+		return
+	}
+}
+
+// getSLAView evaluates the SLA policies against the vulnerability reports
+// Ignored vulnerabilities are left out, since an approved ignore exempts a finding from its SLA
+func getSLAView(ctx context.Context, r *http.Request, data *v1alpha1.VulnerabilityReportList) slaview.View {
+	if len(slaConfig.Policies) == 0 {
+		return slaview.View{}
+	}
+
+	firstSeen, err := db.GetOpenFindingsFirstSeen("", "")
+	if err != nil {
+		recordPartialFailure(r, "Findings", "findings can't be evaluated without their first seen times", err)
+	}
+
+	namespaceLabels, err := kube.GetNamespaceLabels(ctx)
+	if err != nil {
+		recordPartialFailure(r, "Namespaces", "namespace specific policies and owners are missing", err)
+	}
+
+	images := imagesview.GetView(data, nil, imagesview.Filters{})
+	return slaview.GetView(images, firstSeen, namespaceLabels, slaConfig, time.Now())
+}
+
+func changesHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "changes.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing changes html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Parse URL query params
+	now := time.Now()
+	rawSince := r.URL.Query().Get("since")
+	since := now.Add(-changesview.DefaultSince)
+	if rawSince != "" {
+		parsed, err := changesview.ParseSince(rawSince, now)
+		if err != nil {
+			log.Logger.Warn("could not parse since query parameter, showing changes since the default", "raw", rawSince, "error", err.Error())
+		} else {
+			since = parsed
+		}
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		renderKubeError(w, r, "VulnerabilityReports", err)
+		return
+	}
+
+	view, found, err := getChangesView(ctx, r, data, since)
+	if err != nil {
+		log.Logger.Error("error getting changes", "error", err.Error())
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Without a snapshot to diff against, point at the oldest one so a later time can be picked
+	var oldest *time.Time
+	if !found {
+		takenAt, ok, err := db.GetOldestDetailedSnapshotTime()
+		if err != nil {
+			log.Logger.Error("error getting oldest snapshot time", "error", err.Error())
+		} else if ok {
+			oldest = &takenAt
+		}
+	}
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute string
+		Since     string
+		Found     bool
+		Oldest    *time.Time
+		Data      changesview.View
+	}{
+		PageRoute: "changes",
+		Since:     since.Local().Format("2006-01-02T15:04"),
+		Found:     found,
+		Oldest:    oldest,
+		Data:      view,
+	}
+
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing changes html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+func changesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse URL query params
+	now := time.Now()
+	since := now.Add(-changesview.DefaultSince)
+	if rawSince := r.URL.Query().Get("since"); rawSince != "" {
+		var err error
+		since, err = changesview.ParseSince(rawSince, now)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid since query parameter: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	data, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		writeKubeAPIError(w, r, "VulnerabilityReports", err)
+		return
+	}
+
+	view, found, err := getChangesView(ctx, r, data, since)
+	if err != nil {
+		log.Logger.Error("error getting changes", "error", err.Error())
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("No snapshot found at or before %s", since.Format(time.RFC3339)), http.StatusNotFound)
+		return
+	}
+
+	// Name the reports that failed to load, since their changes are missing
+	response := struct {
+		changesview.View
+		PartialFailures []PartialFailure `json:"partial_failures,omitempty"`
+	}{
+		View:            view,
+		PartialFailures: partialFailuresFromRequest(r),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Logger.Error("encountered error encoding changes json response", "error", err)
+		return
+	}
+}
+
+// getChangesView diffs the current reports against the latest detailed snapshot taken at or before since
+// The returned bool is false if there's no such snapshot
+func getChangesView(ctx context.Context, r *http.Request, data *v1alpha1.VulnerabilityReportList, since time.Time) (changesview.View, bool, error) {
+	snapshot, found, err := db.GetDetailedSnapshotAtOrBefore(since)
+	if err != nil || !found {
+		return changesview.View{Since: since}, false, err
+	}
+
+	// The ledgers are updated with each detailed snapshot, so their state when it was taken is the baseline
+	baseline := changesview.Baseline{Snapshot: snapshot}
+	baseline.Images, err = db.GetSnapshotImages(snapshot.ID)
+	if err != nil {
+		return changesview.View{}, false, err
+	}
+	baseline.Findings, err = db.GetFindingsOpenAt(snapshot.TakenAt)
+	if err != nil {
+		return changesview.View{}, false, err
+	}
+	baseline.ConfigAudits, err = db.GetConfigAuditFindingsOpenAt(snapshot.TakenAt)
+	if err != nil {
+		return changesview.View{}, false, err
+	}
+	baseline.ExposedSecrets, err = db.GetExposedSecretFindingsOpenAt(snapshot.TakenAt)
+	if err != nil {
+		return changesview.View{}, false, err
+	}
+
+	// The current state is put in the same form as the snapshots and ledgers, so both sides are keyed the same way
+	current := changesview.Current{
+		Images:   newSnapshot(time.Now(), imagesview.GetView(data, nil, imagesview.Filters{})).Images,
+		Findings: newFindingObservations(data),
+	}
+	configAuditData, err := kube.GetConfigAuditReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "ConfigAuditReports", "new config audit failures are missing", err)
+	} else {
+		current.ConfigAudits = newConfigAuditObservations(configAuditData)
+	}
+	exposedSecretData, err := kube.GetExposedSecretReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "ExposedSecretReports", "new exposed secrets are missing", err)
+	} else {
+		current.ExposedSecrets = newExposedSecretObservations(exposedSecretData)
+	}
+
+	return changesview.GetView(since, baseline, current), true, nil
+}
+
+// resourceReports contains the cluster-wide views of every report type that's aggregated by namespace or workload
+type resourceReports struct {
+	images         imagesview.View
+	configAudits   configauditsview.View
+	roles          rolesview.View
+	exposedSecrets exposedsecretsview.View
+}
+
+// getResourceReports gets every report type that's aggregated by namespace or workload
+// A report type that fails to load is recorded as a partial failure and left empty, so the rest of the page still renders
+func getResourceReports(ctx context.Context, r *http.Request, imageFilters imagesview.Filters) resourceReports {
+	var reports resourceReports
+
+	vulnerabilityData, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "VulnerabilityReports", "image vulnerabilities are missing", err)
+	} else {
+		reports.images = imagesview.GetView(vulnerabilityData, nil, imageFilters)
+	}
+
+	configAuditData, err := kube.GetConfigAuditReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "ConfigAuditReports", "resource audits are missing", err)
+	} else {
+		reports.configAudits = configauditsview.GetView(configAuditData, configauditsview.Filters{})
+	}
+
+	roleData, err := kube.GetRbacAssessmentReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "RbacAssessmentReports", "role assessments are missing", err)
+	} else {
+		reports.roles = rolesview.GetView(roleData, rolesview.Filters{})
+	}
+
+	exposedSecretData, err := kube.GetExposedSecretReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "ExposedSecretReports", "exposed secrets are missing", err)
+	} else {
+		reports.exposedSecrets = exposedsecretsview.GetView(exposedSecretData)
+	}
+
+	return reports
+}
+
+func namespacesHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "namespaces.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing namespaces html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	reports := getResourceReports(ctx, r, imagesview.Filters{})
+	view := namespacesview.GetView(reports.images, reports.configAudits, reports.roles, reports.exposedSecrets)
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute string
+		Data      namespacesview.View
+	}{
+		PageRoute: "namespaces",
+		Data:      view,
+	}
+
+	// Execute html template
+	err := tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing namespaces html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+func namespaceHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "namespace.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing namespace html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Check query params -- 404 if required params not passed
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		log.Logger.Error("namespace name query param missing from request")
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	reports := getResourceReports(ctx, r, imagesview.Filters{})
+	view := namespaceview.GetView(name, reports.images, reports.configAudits, reports.roles, reports.exposedSecrets)
+
+	trend, err := db.GetNamespaceTrend(name)
+	if err != nil {
+		recordPartialFailure(r, "Snapshots", "the vulnerability trend chart is missing", err)
+	}
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute string
+		Data      namespaceview.View
+		Trend     []db.TrendPoint
+	}{
+		PageRoute: "namespace",
+		Data:      view,
+		Trend:     trend,
+	}
+
+	// Execute html template
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing namespace html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+func workloadHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "workload.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing workload html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Parse URL query params
+	q := r.URL.Query()
+
+	// Check query params -- 404 if required params not passed
+	namespace := q.Get("namespace")
+	kind := q.Get("kind")
+	name := q.Get("name")
+	if namespace == "" || kind == "" || name == "" {
+		log.Logger.Error("workload namespace, kind or name query param missing from request")
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	reports := getResourceReports(ctx, r, imagesview.Filters{})
+
+	// Trivy Operator reports on a Deployment's ReplicaSets, so find them to match their reports to the Deployment
+	owners := []kube.ResourceMetadata{{Kind: kind, Name: name, Namespace: namespace}}
+	var replicaSetNames []string
+	if kind == "Deployment" {
+		replicaSets, err := kube.GetOwnedReplicaSets(ctx, namespace, name)
+		if err != nil {
+			recordPartialFailure(r, "ReplicaSets", "reports for the Deployment's ReplicaSets are missing", err)
+		}
+		for _, rs := range replicaSets {
+			owners = append(owners, rs)
+			replicaSetNames = append(replicaSetNames, rs.Name)
+		}
+	}
+
+	// Find the Roles bound to the workload's ServiceAccounts
+	serviceAccounts, err := kube.GetServiceAccountsForOwners(ctx, namespace, owners)
+	if err != nil {
+		recordPartialFailure(r, "Pods", "the workload's service accounts and their roles are missing", err)
+	}
+	var roleNames []string
+	for _, serviceAccount := range serviceAccounts {
+		roles, err := kube.GetRolesForServiceAccount(ctx, namespace, serviceAccount)
+		if err != nil {
+			recordPartialFailure(r, "RoleBindings", "roles bound to the workload's service accounts are missing", err)
+			break
+		}
+		roleNames = append(roleNames, roles...)
+	}
+
+	view, found := workloadview.GetView(workloadview.Filters{
+		Namespace:       namespace,
+		Kind:            kind,
+		Name:            name,
+		ReplicaSets:     replicaSetNames,
+		ServiceAccounts: serviceAccounts,
+		Roles:           roleNames,
+	}, reports.images, reports.configAudits, reports.roles, reports.exposedSecrets)
+
+	// If the workload has no reports and no running Pods, 404, unless that might be because some data failed to load
+	if !found && len(serviceAccounts) == 0 && len(partialFailuresFromRequest(r)) == 0 {
+		log.Logger.Error("workload query params did not produce a valid result from scraped data", "namespace", namespace, "kind", kind, "name", name)
+		http.NotFound(w, r)
+		return
+	}
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute string
+		Data      workloadview.View
+	}{
+		PageRoute: "workload",
+		Data:      view,
+	}
+
+	// Execute html template
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing workload html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+// imageRequest contains the image and filters requested from the image page or API
+type imageRequest struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+	filters    imageview.Filters
+}
+
+// parseImageRequest parses the image page's query parameters
+// Returns an error naming the missing parameter if the image isn't fully specified, optional filters that can't be parsed are logged and ignored
+func parseImageRequest(q url.Values) (imageRequest, error) {
+	req := imageRequest{
+		registry:   q.Get("registry"),
+		repository: q.Get("repository"),
+		tag:        q.Get("tag"),
+		digest:     q.Get("digest"),
+	}
+	if req.repository == "" {
+		return req, fmt.Errorf("missing required query parameter: repository")
+	}
+	if req.digest == "" {
+		return req, fmt.Errorf("missing required query parameter: digest")
+	}
+	if req.registry == "" {
+		req.registry = "index.docker.io"
+	}
+
+	req.filters = imageview.Filters{
+		Name: utils.AssembleImageFullName(
+			utils.FormatPrettyImageRegistry(req.registry),
+			utils.FormatPrettyImageRepo(req.repository),
+			req.tag,
+			req.digest,
+		),
+		Digest:    req.digest,
+		Severity:  q.Get("severity"),
+		Resources: strings.Split(q.Get("resources"), ","),
+		Target:    q.Get("target"),
+		Class:     q.Get("class"),
+	}
+
+	hasFix := q.Get("hasfix")
+	if hasFix != "" {
+		var err error
+		req.filters.HasFix, err = strconv.ParseBool(hasFix)
+		if err != nil {
+			log.Logger.Warn("could not parse hasfix query parameter to bool type, ignoring filter", "raw", hasFix, "error", err.Error())
+		}
+	}
+
+	showIgnored := q.Get("showignored")
+	if showIgnored != "" {
+		var err error
+		req.filters.ShowIgnored, err = strconv.ParseBool(showIgnored)
+		if err != nil {
+			log.Logger.Warn("could not parse showignored query parameter to bool type, ignoring filter", "raw", showIgnored, "error", err.Error())
+		}
+	}
+
+	for param, dest := range map[string]*time.Time{"publishedafter": &req.filters.PublishedAfter, "publishedbefore": &req.filters.PublishedBefore} {
+		raw := q.Get(param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			log.Logger.Warn("could not parse "+param+" query parameter to a YYYY-MM-DD date, ignoring filter", "raw", raw, "error", err.Error())
+			continue
+		}
+		*dest = t
+	}
+
+	return req, nil
+}
+
+// getImageView gets the image view for a request from the vulnerability reports, along with the image's ignored CVEs
+// Returns false if the image wasn't found in the reports
+func getImageView(reports *v1alpha1.VulnerabilityReportList, req imageRequest) (imageview.View, bool) {
+	// Get ignored CVEs from database
+	ignoredCVEs, err := db.GetIgnoredCVEsForImage(req.registry, req.repository, req.tag)
+	if err != nil {
+		log.Logger.Error("error getting ignored CVEs", "error", err.Error())
+		// Continue without ignored CVEs rather than failing the request
+		ignoredCVEs = nil
+	}
+
+	view, found := imageview.GetView(reports, req.filters, ignoredCVEs)
+	if !found {
+		log.Logger.Error("image name and digest query params did not produce a valid result from scraped data", "image", req.filters.Name, "digest", req.digest)
+		return view, found
+	}
+
+	// Get finding ages from the findings ledger
+	firstSeen, err := db.GetOpenFindingsFirstSeen(view.Registry, view.Repository)
+	if err != nil {
+		log.Logger.Error("error getting finding first seen times", "error", err.Error())
+		// Continue without finding ages rather than failing the request
+	}
+	imageview.SetFirstSeen(view, firstSeen)
+
+	// Get the tickets created for the image repository's vulnerabilities
+	tickets, err := db.GetTickets(db.TicketKey{Registry: view.Registry, Repository: view.Repository})
+	if err != nil {
+		log.Logger.Error("error getting tickets", "error", err.Error())
+		// Continue without tickets rather than failing the request
+	}
+	imageview.SetTickets(view, tickets)
+	return view, found
+}
+
+func getCVEView(reports *v1alpha1.VulnerabilityReportList, cveID string) (cveview.View, bool) {
+	view, found := cveview.GetView(reports, cveview.Filters{
+		ID: cveID,
+	})
+	if !found {
+		return view, found
+	}
+
+	// Get the tickets created for the CVE in each image repository
+	tickets, err := db.GetTickets(db.TicketKey{CVEID: view.ID})
+	if err != nil {
+		log.Logger.Error("error getting tickets", "error", err.Error())
+		// Continue without tickets rather than failing the request
+	}
+	cveview.SetTickets(view, tickets)
+	return view, found
+}
+
+func imageHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "image.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing image html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Check query params -- 404 if required params not passed
+	req, err := parseImageRequest(r.URL.Query())
+	if err != nil {
+		log.Logger.Error("image query params missing from request", "error", err.Error())
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	reports, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		renderKubeError(w, r, "VulnerabilityReports", err)
+		return
+	}
+
+	// Get image view from reports
+	view, found := getImageView(reports, req)
+
+	// If the selected image from query params was not found, 404
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	trend, err := db.GetImageTrend(view.Registry, view.Repository, view.Tag, view.Digest)
+	if err != nil {
+		recordPartialFailure(r, "Snapshots", "the vulnerability trend chart is missing", err)
+	}
+
+	// Add page type to template data
+	templateData := struct {
+		PageRoute      string
+		Filters        imageview.Filters
+		Classes        []string
+		Data           imageview.View
+		Trend          []db.TrendPoint
+		TicketsEnabled bool
+	}{
+		PageRoute:      "image",
+		Filters:        req.filters,
+		Classes:        []string{imageview.ClassOSPackages, imageview.ClassLanguagePackages},
+		Data:           view,
+		Trend:          trend,
+		TicketsEnabled: ticketing != nil,
+	}
+
+	// Execute html template
+	err = tmpl.Execute(w, templateData)
+	if err != nil {
+		log.Logger.Error("encountered error executing image html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
 }
 
-func indexHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/index.html", "static/sidebar.html"))
+func imageAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, err := parseImageRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	reports, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		writeKubeAPIError(w, r, "VulnerabilityReports", err)
+		return
+	}
+
+	view, found := getImageView(reports, req)
+	if !found {
+		http.Error(w, "Image not found in any vulnerability report", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(view); err != nil {
+		log.Logger.Error("encountered error encoding image json response", "error", err)
+		return
+	}
+}
+
+func cveHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "cve.html")
 	if tmpl == nil {
-		log.Logger.Error("encountered error parsing index html template")
+		log.Logger.Error("encountered error parsing cve html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 
+	// Check query params -- 404 if required params not passed
+	cveID := strings.TrimSpace(r.URL.Query().Get("id"))
+	if cveID == "" {
+		log.Logger.Error("cve id query param missing from request")
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get vulnerability reports
-	vulnerabilityData, err := kube.GetVulnerabilityReportList()
+	reports, err := kube.GetVulnerabilityReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting VulnerabilityReports", "error", err.Error())
+		renderKubeError(w, r, "VulnerabilityReports", err)
 		return
 	}
-	imagesView := imagesview.GetView(vulnerabilityData, nil, imagesview.Filters{})
 
-	// Get compliance reports
-	complianceData, err := kube.GetComplianceReportList()
-	if err != nil {
-		log.Logger.Error("error getting ComplianceReports", "error", err.Error())
+	// Get cve view from reports
+	view, found := getCVEView(reports, cveID)
+
+	// If the CVE from query params was not found in any image, 404
+	if !found {
+		log.Logger.Error("cve id query param did not produce a valid result from scraped data", "id", cveID)
+		http.NotFound(w, r)
 		return
 	}
-	complianceView := complianceview.GetView(complianceData)
 
-	// Get index view
-	indexData := indexview.GetView(imagesView, complianceView)
+	// Add page type to template data
+	templateData := struct {
+		PageRoute      string
+		Data           cveview.View
+		TicketsEnabled bool
+	}{
+		PageRoute:      "cve",
+		Data:           view,
+		TicketsEnabled: ticketing != nil,
+	}
 
-	err = tmpl.Execute(w, indexData)
+	// Execute html template
+	err = tmpl.Execute(w, templateData)
 	if err != nil {
-		log.Logger.Error("encountered error executing index html template", "error", err)
+		log.Logger.Error("encountered error executing cve html template", "error", err)
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 }
 
-func imagesHandler(w http.ResponseWriter, r *http.Request) {
-	funcMap := template.FuncMap{
-		"sanitizeID": func(s string) string {
-			replacer := strings.NewReplacer("/", "_", ":", "_", " ", "_", "-", "_", ".", "_")
-			return replacer.Replace(s)
-		},
+func cveAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cveID := strings.TrimSpace(r.URL.Query().Get("id"))
+	if cveID == "" {
+		http.Error(w, "Missing required query parameter: id", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// Get vulnerability reports
+	reports, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		writeKubeAPIError(w, r, "VulnerabilityReports", err)
+		return
 	}
 
-	tmpl := template.Must(template.New("images.html").Funcs(funcMap).ParseFS(content.Static, "static/images.html", "static/sidebar.html"))
+	view, found := getCVEView(reports, cveID)
+	if !found {
+		http.Error(w, "CVE not found in any image", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(view); err != nil {
+		log.Logger.Error("encountered error encoding cve json response", "error", err)
+		return
+	}
+}
+
+func compareHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "compare.html")
 	if tmpl == nil {
-		log.Logger.Error("encountered error parsing images html template")
+		log.Logger.Error("encountered error parsing compare html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 
 	// Parse URL query params
 	q := r.URL.Query()
-
-	// Check query params
-	hasFix := q.Get("hasfix")
-	var hasFixBool bool
-	if hasFix != "" {
-		var err error
-		hasFixBool, err = strconv.ParseBool(hasFix)
-		if err != nil {
-			log.Logger.Warn("could not parse hasfix query parameter to bool type, ignoring filter", "raw", hasFix, "error", err.Error())
-		}
+	filters := compareview.Filters{
+		A: strings.TrimSpace(q.Get("a")),
+		B: strings.TrimSpace(q.Get("b")),
 	}
 
+	// Check query params
 	showIgnored := q.Get("showignored")
-	var showIgnoredBool bool
 	if showIgnored != "" {
 		var err error
-		showIgnoredBool, err = strconv.ParseBool(showIgnored)
+		filters.ShowIgnored, err = strconv.ParseBool(showIgnored)
 		if err != nil {
 			log.Logger.Warn("could not parse showignored query parameter to bool type, ignoring filter", "raw", showIgnored, "error", err.Error())
 		}
 	}
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get vulnerability reports
-	data, err := kube.GetVulnerabilityReportList()
+	data, err := kube.GetVulnerabilityReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting VulnerabilityReports", "error", err.Error())
+		renderKubeError(w, r, "VulnerabilityReports", err)
 		return
 	}
-	// Get total images map -- we don't return here if we get an error because it's for optional helpful data
-	imagesMap, err := kube.GetContainerImagesMap()
-	if err != nil {
-		log.Logger.Error("error getting a list of running images", "error", err.Error())
-	}
 
-	imageData := imagesview.GetView(data, imagesMap, imagesview.Filters{
-		HasFix:      hasFixBool,
-		ShowIgnored: showIgnoredBool,
-	})
+	// Only compare once both images are picked, the form is shown on its own until then
+	compared := filters.A != "" && filters.B != ""
+	var view compareview.View
+	if compared {
+		view = compareview.GetView(data, filters)
+		if !view.A.Found || !view.B.Found {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	} else {
+		view.A.Reference = filters.A
+		view.B.Reference = filters.B
+	}
 
 	// Add page type to template data
 	templateData := struct {
-		PageRoute string
-		Data      imagesview.View
+		PageRoute   string
+		Compared    bool
+		ShowIgnored bool
+		Images      []string
+		Data        compareview.View
 	}{
-		PageRoute: "images",
-		Data:      imageData,
+		PageRoute:   "compare",
+		Compared:    compared,
+		ShowIgnored: filters.ShowIgnored,
+		Images:      reportImageNames(data),
+		Data:        view,
 	}
 
 	err = tmpl.Execute(w, templateData)
 	if err != nil {
-		log.Logger.Error("encountered error executing images html template", "error", err)
+		log.Logger.Error("encountered error executing compare html template", "error", err)
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 }
 
-func imageHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/image.html", "static/sidebar.html"))
-	if tmpl == nil {
-		log.Logger.Error("encountered error parsing image html template")
-		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+func compareAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+
 	// Parse URL query params
 	q := r.URL.Query()
-
-	// Check query params -- 404 if required params not passed
-	imageRepository := q.Get("repository")
-	if imageRepository == "" {
-		log.Logger.Error("image repository query param missing from request")
-		http.NotFound(w, r)
-		return
+	filters := compareview.Filters{
+		A: strings.TrimSpace(q.Get("a")),
+		B: strings.TrimSpace(q.Get("b")),
 	}
-	imageTag := q.Get("tag")
-	imageDigest := q.Get("digest")
-	if imageDigest == "" {
-		log.Logger.Error("image digest query param missing from request")
-		http.NotFound(w, r)
+	if filters.A == "" || filters.B == "" {
+		http.Error(w, "Missing required query parameters: a, b", http.StatusBadRequest)
 		return
 	}
-	imageRegistry := q.Get("registry")
-	if imageRegistry == "" {
-		imageRegistry = "index.docker.io"
-	}
-	severity := q.Get("severity")
-	resources := q.Get("resources")
-
-	hasFix := q.Get("hasfix")
-	var hasFixBool bool
-	if hasFix != "" {
-		var err error
-		hasFixBool, err = strconv.ParseBool(hasFix)
-		if err != nil {
-			log.Logger.Warn("could not parse hasfix query parameter to bool type, ignoring filter", "raw", hasFix, "error", err.Error())
-		}
-	}
 
+	// Check query params
 	showIgnored := q.Get("showignored")
-	var showIgnoredBool bool
 	if showIgnored != "" {
 		var err error
-		showIgnoredBool, err = strconv.ParseBool(showIgnored)
+		filters.ShowIgnored, err = strconv.ParseBool(showIgnored)
 		if err != nil {
 			log.Logger.Warn("could not parse showignored query parameter to bool type, ignoring filter", "raw", showIgnored, "error", err.Error())
 		}
 	}
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get vulnerability reports
-	reports, err := kube.GetVulnerabilityReportList()
+	data, err := kube.GetVulnerabilityReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting VulnerabilityReports", "error", err.Error())
+		writeKubeAPIError(w, r, "VulnerabilityReports", err)
 		return
 	}
 
-	// Get ignored CVEs from database
-	ignoredCVEs, err := db.GetIgnoredCVEsForImage(imageRegistry, imageRepository, imageTag)
-	if err != nil {
-		log.Logger.Error("error getting ignored CVEs", "error", err.Error())
-		// Continue without ignored CVEs rather than failing the request
-		ignoredCVEs = nil
+	view := compareview.GetView(data, filters)
+	if !view.A.Found {
+		http.Error(w, fmt.Sprintf("No vulnerability report found for image %s", filters.A), http.StatusNotFound)
+		return
+	}
+	if !view.B.Found {
+		http.Error(w, fmt.Sprintf("No vulnerability report found for image %s", filters.B), http.StatusNotFound)
+		return
 	}
 
-	imageName := utils.AssembleImageFullName(
-		utils.FormatPrettyImageRegistry(imageRegistry),
-		utils.FormatPrettyImageRepo(imageRepository),
-		imageTag,
-		imageDigest,
-	)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(view); err != nil {
+		log.Logger.Error("encountered error encoding compare json response", "error", err)
+		return
+	}
+}
 
-	// Get image view from reports
-	view, found := imageview.GetView(reports, imageview.Filters{
-		Name:        imageName,
-		Digest:      imageDigest,
-		Severity:    severity,
-		HasFix:      hasFixBool,
-		ShowIgnored: showIgnoredBool,
-		Resources:   strings.Split(resources, ","),
-	}, ignoredCVEs)
+// reportImageNames returns the sorted names of the images with a vulnerability report, for suggesting images to compare
+func reportImageNames(data *v1alpha1.VulnerabilityReportList) []string {
+	set := make(map[string]struct{})
+	for _, item := range data.Items {
+		set[utils.AssembleImageFullName(
+			utils.FormatPrettyImageRegistry(item.Report.Registry.Server),
+			utils.FormatPrettyImageRepo(item.Report.Artifact.Repository),
+			item.Report.Artifact.Tag,
+			item.Report.Artifact.Digest,
+		)] = struct{}{}
+	}
 
-	// If the selected image from query params was not found, 404
-	if !found {
-		log.Logger.Error("image name and digest query params did not produce a valid result from scraped data", "image", imageName, "digest", imageDigest)
-		http.NotFound(w, r)
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getSearchSources gets every report type that's searched
+// Ignored CVEs are included so they can still be found, and a report type that fails to load is recorded as a partial failure
+func getSearchSources(ctx context.Context, r *http.Request) searchview.Sources {
+	reports := getResourceReports(ctx, r, imagesview.Filters{ShowIgnored: true})
+	sources := searchview.Sources{
+		Images:         reports.images,
+		ConfigAudits:   reports.configAudits,
+		Roles:          reports.roles,
+		ExposedSecrets: reports.exposedSecrets,
+	}
+
+	clusterRoleData, err := kube.GetClusterRbacAssessmentReportList(ctx)
+	if err != nil {
+		recordPartialFailure(r, "ClusterRbacAssessmentReports", "cluster role assessments are missing", err)
+	} else {
+		sources.ClusterRoles = clusterrolesview.GetView(clusterRoleData)
+	}
+
+	return sources
+}
+
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := newTemplate(r, "search.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing search html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	// Don't load every report for a query too short to search
+	var view searchview.View
+	if len(query) >= searchview.MinQueryLength {
+		ctx, cancel := kubeContext(r)
+		defer cancel()
+		view = searchview.GetView(query, getSearchSources(ctx, r))
+	} else {
+		view = searchview.View{Query: query}
+	}
+
 	// Add page type to template data
 	templateData := struct {
-		PageRoute string
-		Data      imageview.View
+		PageRoute      string
+		MinQueryLength int
+		Data           searchview.View
 	}{
-		PageRoute: "image",
-		Data:      view,
+		PageRoute:      "search",
+		MinQueryLength: searchview.MinQueryLength,
+		Data:           view,
 	}
 
-	// Execute html template
-	err = tmpl.Execute(w, templateData)
+	err := tmpl.Execute(w, templateData)
 	if err != nil {
-		log.Logger.Error("encountered error executing image html template", "error", err)
+		log.Logger.Error("encountered error executing search html template", "error", err)
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 }
 
+func searchAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(query) < searchview.MinQueryLength {
+		http.Error(w, fmt.Sprintf("Query parameter q must be at least %d characters", searchview.MinQueryLength), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	view := searchview.GetView(query, getSearchSources(ctx, r))
+
+	// Name the report types that failed to load, since the results may be missing matches from them
+	response := struct {
+		searchview.View
+		PartialFailures []PartialFailure `json:"partial_failures,omitempty"`
+	}{
+		View:            view,
+		PartialFailures: partialFailuresFromRequest(r),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Logger.Error("encountered error encoding search json response", "error", err)
+		return
+	}
+}
+
 func ignoreHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -281,7 +1857,7 @@ func ignoreHandler(w http.ResponseWriter, r *http.Request) {
 	var requestData db.IgnoredImageVulnerability
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		log.Logger.Error("Failed to decode unignore request", "error", err)
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeBodyError(w, err, "Invalid JSON")
 		return
 	}
 
@@ -296,6 +1872,9 @@ func ignoreHandler(w http.ResponseWriter, r *http.Request) {
 		requestData.Registry = "index.docker.io"
 	}
 
+	// The requesting user is always taken from the authenticated request, never the request body
+	requestData.IgnoredBy = usernameFromRequest(r)
+
 	// Handle both POST (ignore) and DELETE (unignore) requests
 	if r.Method == http.MethodPost {
 		// Validate additional required fields
@@ -303,6 +1882,10 @@ func ignoreHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if expiresInPast(requestData.ExpiresAt) {
+			http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+			return
+		}
 
 		// Insert into database
 		if err := db.InsertIgnoredImageVulnerability(requestData); err != nil {
@@ -313,7 +1896,7 @@ func ignoreHandler(w http.ResponseWriter, r *http.Request) {
 
 	} else if r.Method == http.MethodDelete {
 		// Delete from database
-		if err := db.DeleteIgnoredImageVulnerability(requestData.Registry, requestData.Repository, requestData.Tag, requestData.CVEID); err != nil {
+		if err := db.DeleteIgnoredImageVulnerability(requestData.Registry, requestData.Repository, requestData.Tag, requestData.CVEID, requestData.IgnoredBy); err != nil {
 			log.Logger.Error("Failed to delete ignored vulnerability", "error", err)
 			http.Error(w, "Failed to unignore CVE", http.StatusInternalServerError)
 			return
@@ -330,6 +1913,8 @@ type BulkIgnoreRequest struct {
 	Tag        string   `json:"tag"`
 	CVEIDs     []string `json:"cve_ids"`
 	Reason     string   `json:"reason"`
+	// ExpiresAt is optional, ignores without it never expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 func bulkIgnoreHandler(w http.ResponseWriter, r *http.Request) {
@@ -342,7 +1927,7 @@ func bulkIgnoreHandler(w http.ResponseWriter, r *http.Request) {
 	var requestData BulkIgnoreRequest
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		log.Logger.Error("Failed to decode bulk ignore request", "error", err)
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		writeBodyError(w, err, "Invalid JSON")
 		return
 	}
 
@@ -351,6 +1936,10 @@ func bulkIgnoreHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
+	if expiresInPast(requestData.ExpiresAt) {
+		http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+		return
+	}
 
 	// Set default registry for Docker Hub if empty
 	registry := requestData.Registry
@@ -359,7 +1948,7 @@ func bulkIgnoreHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Insert into database using bulk insert
-	if err := db.BulkInsertIgnoredImageVulnerabilities(registry, requestData.Repository, requestData.Tag, requestData.Reason, requestData.CVEIDs); err != nil {
+	if err := db.BulkInsertIgnoredImageVulnerabilities(registry, requestData.Repository, requestData.Tag, requestData.Reason, usernameFromRequest(r), requestData.ExpiresAt, requestData.CVEIDs); err != nil {
 		log.Logger.Error("Failed to bulk insert ignored vulnerabilities", "error", err)
 		http.Error(w, "Failed to save bulk ignore request", http.StatusInternalServerError)
 		return
@@ -368,8 +1957,105 @@ func bulkIgnoreHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// CVEIgnoreRequest represents a request to ignore a CVE in every image it affects
+type CVEIgnoreRequest struct {
+	CVEID  string `json:"cve_id"`
+	Reason string `json:"reason"`
+	// ExpiresAt is optional, ignores without it never expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func cveIgnoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse JSON request body
+	var requestData CVEIgnoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		log.Logger.Error("Failed to decode cve ignore request", "error", err)
+		writeBodyError(w, err, "Invalid JSON")
+		return
+	}
+
+	// Validate required fields
+	if requestData.CVEID == "" || requestData.Reason == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+	if expiresInPast(requestData.ExpiresAt) {
+		http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	// The affected images are looked up from the reports, so images that started running since the page loaded are ignored too
+	reports, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		writeKubeAPIError(w, r, "VulnerabilityReports", err)
+		return
+	}
+	view, found := cveview.GetView(reports, cveview.Filters{
+		ID: requestData.CVEID,
+	})
+	if !found {
+		http.Error(w, "CVE not found in any image", http.StatusNotFound)
+		return
+	}
+
+	var images []db.IgnoredImageVulnerability
+	for _, image := range view.Images {
+		if image.IsIgnored {
+			continue
+		}
+
+		// Set default registry for Docker Hub if empty
+		registry := image.Registry
+		if registry == "" {
+			registry = "index.docker.io"
+		}
+		images = append(images, db.IgnoredImageVulnerability{
+			Registry:   registry,
+			Repository: image.Repository,
+			Tag:        image.Tag,
+		})
+	}
+
+	// Nothing to do if the CVE is already ignored everywhere
+	if len(images) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := db.BulkInsertIgnoredCVEForImages(requestData.CVEID, requestData.Reason, usernameFromRequest(r), requestData.ExpiresAt, images); err != nil {
+		log.Logger.Error("Failed to bulk insert ignored cve for images", "error", err)
+		http.Error(w, "Failed to save cve ignore request", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// expiresInPast returns true if an ignore's optional expiry time has already passed
+func expiresInPast(expiresAt *time.Time) bool {
+	return expiresAt != nil && !expiresAt.After(time.Now())
+}
+
+// writeBodyError responds to a request whose body could not be read or parsed
+func writeBodyError(w http.ResponseWriter, err error, msg string) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, msg, http.StatusBadRequest)
+}
+
 func rolesHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/roles.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "roles.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing roles html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
@@ -382,10 +2068,13 @@ func rolesHandler(w http.ResponseWriter, r *http.Request) {
 	// Check query params
 	namespace := q.Get("namespace")
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get role reports
-	reports, err := kube.GetRbacAssessmentReportList()
+	reports, err := kube.GetRbacAssessmentReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting VulnerabilityReports", "error", err.Error())
+		renderKubeError(w, r, "RbacAssessmentReports", err)
 		return
 	}
 	roles := rolesview.GetView(reports, rolesview.Filters{
@@ -401,7 +2090,7 @@ func rolesHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func roleHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/role.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "role.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing role html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
@@ -426,10 +2115,13 @@ func roleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	severity := q.Get("severity")
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get role reports
-	reports, err := kube.GetRbacAssessmentReportList()
+	reports, err := kube.GetRbacAssessmentReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting RBACAssessmentReports", "error", err.Error())
+		renderKubeError(w, r, "RbacAssessmentReports", err)
 		return
 	}
 	role, found := roleview.GetView(reports, roleview.Filters{
@@ -454,17 +2146,20 @@ func roleHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func clusterrolesHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/clusterroles.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "clusterroles.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing roles html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get role reports
-	reports, err := kube.GetClusterRbacAssessmentReportList()
+	reports, err := kube.GetClusterRbacAssessmentReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting clusterrbacassessmentreports", "error", err.Error())
+		renderKubeError(w, r, "ClusterRbacAssessmentReports", err)
 		return
 	}
 	roles := clusterrolesview.GetView(reports)
@@ -478,7 +2173,7 @@ func clusterrolesHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func clusterroleHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/clusterrole.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "clusterrole.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing clusterrole html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
@@ -497,10 +2192,13 @@ func clusterroleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	severity := q.Get("severity")
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get clusterrole reports
-	reports, err := kube.GetClusterRbacAssessmentReportList()
+	reports, err := kube.GetClusterRbacAssessmentReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting clusterrbacassessmentreports", "error", err.Error())
+		renderKubeError(w, r, "ClusterRbacAssessmentReports", err)
 		return
 	}
 	role, found := clusterroleview.GetView(reports, clusterroleview.Filters{
@@ -524,7 +2222,7 @@ func clusterroleHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func configauditsHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/configaudits.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "configaudits.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing configaudits html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
@@ -538,10 +2236,13 @@ func configauditsHandler(w http.ResponseWriter, r *http.Request) {
 	namespace := q.Get("namespace")
 	kind := q.Get("kind")
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get reports
-	reports, err := kube.GetConfigAuditReportList()
+	reports, err := kube.GetConfigAuditReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting configauditreports", "error", err.Error())
+		renderKubeError(w, r, "ConfigAuditReports", err)
 		return
 	}
 	audits := configauditsview.GetView(reports, configauditsview.Filters{
@@ -558,7 +2259,7 @@ func configauditsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func configauditHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/configaudit.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "configaudit.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing configaudit html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
@@ -589,10 +2290,13 @@ func configauditHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	severity := q.Get("severity")
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get configaudit reports
-	reports, err := kube.GetConfigAuditReportList()
+	reports, err := kube.GetConfigAuditReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting configauditreports", "error", err.Error())
+		renderKubeError(w, r, "ConfigAuditReports", err)
 		return
 	}
 	audit, found := configauditview.GetView(reports, configauditview.Filters{
@@ -618,17 +2322,20 @@ func configauditHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func clusterauditsHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/clusteraudits.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "clusteraudits.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing clusteraudits html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get reports
-	reports, err := kube.GetClusterInfraAssessmentReportList()
+	reports, err := kube.GetClusterInfraAssessmentReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting clusterinfraassessmentreports", "error", err.Error())
+		renderKubeError(w, r, "ClusterInfraAssessmentReports", err)
 		return
 	}
 	audits := clusterauditsview.GetView(reports)
@@ -642,7 +2349,7 @@ func clusterauditsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func clusterauditHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/clusteraudit.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "clusteraudit.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing clusteraudit html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
@@ -667,10 +2374,13 @@ func clusterauditHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	severity := q.Get("severity")
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get clusteraudit reports
-	reports, err := kube.GetClusterInfraAssessmentReportList()
+	reports, err := kube.GetClusterInfraAssessmentReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting clusterinfraassessmentreports", "error", err.Error())
+		renderKubeError(w, r, "ClusterInfraAssessmentReports", err)
 		return
 	}
 	audit, found := clusterauditview.GetView(reports, clusterauditview.Filters{
@@ -695,16 +2405,19 @@ func clusterauditHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func exposedsecretsHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/exposedsecrets.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "exposedsecrets.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing exposed secrets html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 
-	data, err := kube.GetExposedSecretReportList()
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	data, err := kube.GetExposedSecretReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting ExposedSecretReports", "error", err.Error())
+		renderKubeError(w, r, "ExposedSecretReports", err)
 		return
 	}
 	imageData := exposedsecretsview.GetView(data)
@@ -718,7 +2431,7 @@ func exposedsecretsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func exposedsecretHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/exposedsecret.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "exposedsecret.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing exposed secret html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
@@ -743,10 +2456,13 @@ func exposedsecretHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	severity := q.Get("severity")
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get secret reports
-	data, err := kube.GetExposedSecretReportList()
+	data, err := kube.GetExposedSecretReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting ExposedSecretReports", "error", err.Error())
+		renderKubeError(w, r, "ExposedSecretReports", err)
 		return
 	}
 
@@ -774,17 +2490,20 @@ func exposedsecretHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func complianceReportsHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/compliancereports.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "compliancereports.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing compliance reports html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
 		return
 	}
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get compliance reports
-	complianceData, err := kube.GetComplianceReportList()
+	complianceData, err := kube.GetComplianceReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting ComplianceReports", "error", err.Error())
+		renderKubeError(w, r, "ClusterComplianceReports", err)
 		return
 	}
 	complianceView := complianceview.GetView(complianceData)
@@ -798,7 +2517,7 @@ func complianceReportsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func complianceReportHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(content.Static, "static/compliancereport.html", "static/sidebar.html"))
+	tmpl := newTemplate(r, "compliancereport.html")
 	if tmpl == nil {
 		log.Logger.Error("encountered error parsing compliance report html template")
 		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
@@ -821,10 +2540,13 @@ func complianceReportHandler(w http.ResponseWriter, r *http.Request) {
 		severity = &s
 	}
 
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
 	// Get compliance reports
-	complianceData, err := kube.GetComplianceReportList()
+	complianceData, err := kube.GetComplianceReportList(ctx)
 	if err != nil {
-		log.Logger.Error("error getting ComplianceReports", "error", err.Error())
+		renderKubeError(w, r, "ClusterComplianceReports", err)
 		return
 	}
 	complianceView := complianceview.GetSingleReportData(complianceData, id, severity)
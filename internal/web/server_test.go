@@ -0,0 +1,164 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+)
+
+func TestParseImagesQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want imagesview.Query
+	}{
+		{
+			raw:  "namespace=default&registry=ghcr.io&minseverity=High&minscore=7.5&eosl=true&unscanned=1&sort=name&page=2&pagesize=20",
+			want: imagesview.Query{Namespace: "default", Registry: "ghcr.io", MinSeverity: "High", MinScore: 7.5, EOSLOnly: true, UnscannedOnly: true, Sort: "name", Page: 2, PageSize: 20},
+		},
+		{
+			raw:  "minscore=high",
+			want: imagesview.Query{},
+		},
+		{
+			// Out of range floats parse to ±Inf along with an error
+			raw:  "minscore=1e999",
+			want: imagesview.Query{},
+		},
+		{
+			raw:  "minscore=-1e999",
+			want: imagesview.Query{},
+		},
+		{
+			raw:  "eosl=maybe&unscanned=",
+			want: imagesview.Query{},
+		},
+		{
+			raw:  "page=two&pagesize=lots",
+			want: imagesview.Query{},
+		},
+		{
+			// Out of range ints parse to the largest int along with an error
+			raw:  "page=99999999999999999999&pagesize=99999999999999999999",
+			want: imagesview.Query{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parseImagesQuery(q); got != tt.want {
+				t.Errorf("parseImagesQuery(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreHandlersRejectPastExpiry(t *testing.T) {
+	initTestDB(t)
+	newTestKubeAPI(t, map[string]any{testVulnerabilityReportsPath: testVulnerabilityReports}, &sync.Map{})
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{
+			name:    "ignore",
+			handler: ignoreHandler,
+			body:    `{"registry":"index.docker.io","repository":"library/nginx","tag":"1.25","cve_id":"CVE-2024-0001","reason":"not exploitable","expires_at":%q}`,
+		},
+		{
+			name:    "bulk ignore",
+			handler: bulkIgnoreHandler,
+			body:    `{"registry":"index.docker.io","repository":"library/nginx","tag":"1.25","cve_ids":["CVE-2024-0001"],"reason":"not exploitable","expires_at":%q}`,
+		},
+		{
+			name:    "cve ignore",
+			handler: cveIgnoreHandler,
+			body:    `{"cve_id":"CVE-2024-0001","reason":"not exploitable","expires_at":%q}`,
+		},
+	}
+
+	countIgnores := func() int {
+		var count int
+		if err := db.Client.Get(&count, `SELECT COUNT(*) FROM ignoredImageVulnerabilities`); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.Client.Exec(`DELETE FROM ignoredImageVulnerabilities`); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, expiresAt := range []time.Time{time.Now().Add(-time.Hour), time.Now()} {
+				w := httptest.NewRecorder()
+				body := fmt.Sprintf(tt.body, expiresAt.Format(time.RFC3339Nano))
+				tt.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("got status %d for an expiry of %s, want 400", w.Code, expiresAt)
+				}
+				if got := countIgnores(); got != 0 {
+					t.Fatalf("got %d ignores after rejecting the request, want 0", got)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			body := fmt.Sprintf(tt.body, time.Now().Add(time.Hour).Format(time.RFC3339Nano))
+			tt.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+			if w.Code != http.StatusOK {
+				t.Errorf("got status %d for an expiry in the future, want 200: %s", w.Code, w.Body)
+			}
+			if got := countIgnores(); got != 1 {
+				t.Errorf("got %d ignores for an expiry in the future, want 1", got)
+			}
+		})
+	}
+}
+
+func TestImageHandlerRendersPartialFailureBanner(t *testing.T) {
+	initTestDB(t)
+	newTestKubeAPI(t, map[string]any{testVulnerabilityReportsPath: testVulnerabilityReports}, &sync.Map{})
+	const banner = "Showing partial data"
+	const failure = "Snapshots: the vulnerability trend chart is missing"
+
+	render := func() string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		target := "/image?" + url.Values{"registry": {"index.docker.io"}, "repository": {"library/nginx"}, "tag": {"1.25"}, "digest": {testDigest}}.Encode()
+		partialFailuresMiddleware(http.HandlerFunc(imageHandler)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+		}
+		return w.Body.String()
+	}
+
+	if body := render(); strings.Contains(body, banner) {
+		t.Error("the partial data banner renders without any failures")
+	}
+
+	// Losing the snapshots table fails the trend chart's query, but the rest of the page still renders
+	if _, err := db.Client.Exec(`DROP TABLE snapshotImages`); err != nil {
+		t.Fatal(err)
+	}
+	body := render()
+	if !strings.Contains(body, banner) || !strings.Contains(body, failure) {
+		t.Errorf("the partial data banner naming %q isn't rendered", failure)
+	}
+	if !strings.Contains(body, "CVE-2024-0001") {
+		t.Error("the image's vulnerabilities aren't rendered alongside the banner")
+	}
+}
@@ -0,0 +1,242 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	complianceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/compliance"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+)
+
+// SnapshotConfig contains the settings for the background snapshotter that records finding counts for the trend charts
+type SnapshotConfig struct {
+	// Interval is how often a snapshot is taken, 0 disables the snapshotter
+	Interval time.Duration
+	// Retention is how long snapshots are kept, 0 keeps them forever
+	Retention time.Duration
+	// LedgerInterval is how often the findings ledgers are updated while snapshots are disabled, 0 stops updating them
+	// Snapshots update the ledgers themselves when they're enabled
+	LedgerInterval time.Duration
+}
+
+// snapshotter periodically records finding counts and updates the findings ledger, so trends and remediation times survive workloads being deleted or rescanned
+type snapshotter struct {
+	config SnapshotConfig
+}
+
+// run takes snapshots on the configured interval until the context is done
+// The first snapshot is taken once the interval has passed since the last one, so restarts don't record extra snapshots
+func (s *snapshotter) run(ctx context.Context) {
+	wait := time.Duration(0)
+	latest, found, err := db.GetLatestSnapshotTime()
+	if err != nil {
+		log.Logger.Error("error getting the latest snapshot time, taking a snapshot now", "error", err)
+	} else if found {
+		wait = time.Until(latest.Add(s.config.Interval))
+		if wait < 0 {
+			wait = 0
+		}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if err := s.snapshot(ctx); err != nil {
+				log.Logger.Error("error taking snapshot, it will be retried on the next interval", "error", err)
+			}
+			s.prune()
+			timer.Reset(s.config.Interval)
+		}
+	}
+}
+
+// snapshot records the current finding counts and updates the findings ledger
+// A snapshot is skipped if the vulnerability reports can't be read, rather than recording zeros that would look like an improvement
+func (s *snapshotter) snapshot(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+
+	vulnerabilityData, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting vulnerability reports: %w", err)
+	}
+	takenAt := time.Now()
+	snapshot := newSnapshot(takenAt, imagesview.GetView(vulnerabilityData, nil, imagesview.Filters{}))
+
+	// The findings ledgers are updated alongside each snapshot, so first seen and resolved times are accurate to the snapshot interval
+	// The snapshot is only detailed, and usable as a baseline for the changes view, when every ledger was updated
+	snapshot.Detailed = updateLedgers(ctx, takenAt, vulnerabilityData)
+
+	complianceData, err := kube.GetComplianceReportList(ctx)
+	if err != nil {
+		log.Logger.Warn("error getting compliance reports, the snapshot won't include compliance results", "error", err)
+	} else {
+		for _, report := range complianceview.GetView(complianceData) {
+			snapshot.Compliance = append(snapshot.Compliance, db.ComplianceSnapshot{
+				ReportID:  report.ID,
+				Title:     report.Title,
+				PassCount: report.Summary.PassCount,
+				FailCount: report.Summary.FailCount,
+			})
+		}
+	}
+
+	if err := db.InsertSnapshot(snapshot); err != nil {
+		return err
+	}
+	log.Logger.Info("took snapshot", "images", len(snapshot.Images), "namespaces", len(snapshot.Namespaces), "compliance_reports", len(snapshot.Compliance), "detailed", snapshot.Detailed)
+	return nil
+}
+
+// updateLedgers records the current findings in the vulnerability, config audit and exposed secret ledgers
+// It returns false if any of the ledgers couldn't be updated
+func updateLedgers(ctx context.Context, observedAt time.Time, vulnerabilityData *v1alpha1.VulnerabilityReportList) bool {
+	updated := true
+	if err := db.RecordFindingObservations(observedAt, newFindingObservations(vulnerabilityData)); err != nil {
+		log.Logger.Error("error updating the findings ledger", "error", err)
+		updated = false
+	}
+
+	configAuditData, err := kube.GetConfigAuditReportList(ctx)
+	if err != nil {
+		log.Logger.Warn("error getting config audit reports, the config audit ledger won't be updated", "error", err)
+		updated = false
+	} else if err := db.RecordConfigAuditObservations(observedAt, newConfigAuditObservations(configAuditData)); err != nil {
+		log.Logger.Error("error updating the config audit ledger", "error", err)
+		updated = false
+	}
+
+	exposedSecretData, err := kube.GetExposedSecretReportList(ctx)
+	if err != nil {
+		log.Logger.Warn("error getting exposed secret reports, the exposed secret ledger won't be updated", "error", err)
+		updated = false
+	} else if err := db.RecordExposedSecretObservations(observedAt, newExposedSecretObservations(exposedSecretData)); err != nil {
+		log.Logger.Error("error updating the exposed secret ledger", "error", err)
+		updated = false
+	}
+	return updated
+}
+
+// ledgerUpdater periodically updates the findings ledgers while snapshots are disabled, so first seen, age, MTTR and SLA data keep being recorded without trend charts
+type ledgerUpdater struct {
+	interval time.Duration
+}
+
+// run updates the ledgers now and then on the configured interval until the context is done
+func (l *ledgerUpdater) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if err := l.update(ctx); err != nil {
+				log.Logger.Error("error updating the findings ledgers, it will be retried on the next interval", "error", err)
+			}
+			timer.Reset(l.interval)
+		}
+	}
+}
+
+// update records the current findings in the ledgers
+// Nothing is recorded if the vulnerability reports can't be read, rather than marking every finding resolved
+func (l *ledgerUpdater) update(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+
+	vulnerabilityData, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting vulnerability reports: %w", err)
+	}
+	updateLedgers(ctx, time.Now(), vulnerabilityData)
+	return nil
+}
+
+// prune deletes the snapshots older than the retention period
+func (s *snapshotter) prune() {
+	if s.config.Retention <= 0 {
+		return
+	}
+	deleted, err := db.DeleteSnapshotsBefore(time.Now().Add(-s.config.Retention))
+	if err != nil {
+		log.Logger.Error("error deleting expired snapshots", "error", err)
+		return
+	}
+	if deleted > 0 {
+		log.Logger.Info("deleted expired snapshots", "count", deleted, "retention", s.config.Retention)
+	}
+}
+
+// newSnapshot totals the images view's vulnerability counts per image, per namespace and for the cluster
+// The images view already leaves out ignored vulnerabilities, so the counts are net of ignores
+func newSnapshot(takenAt time.Time, images imagesview.View) db.Snapshot {
+	snapshot := db.Snapshot{
+		TakenAt: takenAt,
+	}
+
+	namespaces := make(map[string]db.SeverityCounts)
+	for _, image := range images {
+		if image.Unscanned {
+			continue
+		}
+
+		counts := db.SeverityCounts{
+			Critical: len(image.CriticalVulnerabilities),
+			High:     len(image.HighVulnerabilities),
+			Medium:   len(image.MediumVulnerabilities),
+			Low:      len(image.LowVulnerabilities),
+		}
+		snapshot.Totals = addSeverityCounts(snapshot.Totals, counts)
+		snapshot.Images = append(snapshot.Images, db.ImageSnapshot{
+			Registry:       image.Registry,
+			Repository:     image.Name,
+			Tag:            image.Tag,
+			Digest:         image.Digest,
+			SeverityCounts: counts,
+		})
+
+		// An image run in several namespaces counts towards each of them
+		imageNamespaces := make(map[string]struct{})
+		for resource := range image.Resources {
+			imageNamespaces[resource.Namespace] = struct{}{}
+		}
+		for ns := range imageNamespaces {
+			namespaces[ns] = addSeverityCounts(namespaces[ns], counts)
+		}
+	}
+
+	for ns, counts := range namespaces {
+		snapshot.Namespaces = append(snapshot.Namespaces, db.NamespaceSnapshot{
+			Namespace:      ns,
+			SeverityCounts: counts,
+		})
+	}
+	sort.Slice(snapshot.Namespaces, func(i, j int) bool {
+		return snapshot.Namespaces[i].Namespace < snapshot.Namespaces[j].Namespace
+	})
+
+	return snapshot
+}
+
+// addSeverityCounts returns the sum of two severity counts
+func addSeverityCounts(a, b db.SeverityCounts) db.SeverityCounts {
+	return db.SeverityCounts{
+		Critical: a.Critical + b.Critical,
+		High:     a.High + b.High,
+		Medium:   a.Medium + b.Medium,
+		Low:      a.Low + b.Low,
+	}
+}
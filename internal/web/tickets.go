@@ -0,0 +1,420 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/jira"
+	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+)
+
+// TicketConfig contains the settings for creating tickets for findings in a Jira compatible tracker
+type TicketConfig struct {
+	// Jira is the tracker tickets are created in, tickets are disabled when its URL is empty
+	Jira jira.Config
+	// Automatic creates tickets for critical vulnerabilities that first appear after it's enabled
+	Automatic bool
+	// Interval is how often ticket statuses are refreshed, and new critical vulnerabilities ticketed in automatic mode
+	Interval time.Duration
+}
+
+// ticketing creates tickets and refreshes their statuses, it's set from the server config and nil when no tracker is configured
+var ticketing *ticketer
+
+// maxAutomaticTicketsPerCheck limits how many tickets are created automatically each interval, so a bad scan can't flood the tracker
+// Vulnerabilities left over are ticketed on the following intervals
+const maxAutomaticTicketsPerCheck = 10
+
+// ticketer creates tickets for findings, and periodically refreshes the statuses of the tickets it created
+type ticketer struct {
+	config TicketConfig
+	client *jira.Client
+	// baseURL is the explorer's external URL for linking to findings from tickets, links are left out when it's empty
+	baseURL string
+
+	// mu serializes ticket creation, so a finding is never ticketed twice
+	mu sync.Mutex
+}
+
+func newTicketer(config TicketConfig, baseURL string) (*ticketer, error) {
+	if config.Jira.URL == "" {
+		return nil, nil
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("ticket interval must be greater than 0, got %s", config.Interval)
+	}
+
+	client, err := jira.New(config.Jira)
+	if err != nil {
+		return nil, err
+	}
+	return &ticketer{
+		config:  config,
+		client:  client,
+		baseURL: baseURL,
+	}, nil
+}
+
+// run refreshes ticket statuses and creates automatic tickets on the configured interval until the context is done
+// The first check happens right away, so automatic mode is baselined without waiting an interval
+func (t *ticketer) run(ctx context.Context) {
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		t.refreshStatuses(ctx)
+		if t.config.Automatic {
+			if err := t.createAutomatic(ctx); err != nil {
+				log.Logger.Error("error creating automatic tickets, they'll be created on the next interval", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshStatuses records the current status of every ticket, a ticket that can't be looked up keeps its last known status
+func (t *ticketer) refreshStatuses(ctx context.Context) {
+	tickets, err := db.GetTickets(db.TicketKey{})
+	if err != nil {
+		log.Logger.Error("error getting tickets", "error", err)
+		return
+	}
+
+	for _, ticket := range tickets {
+		status, err := t.client.Status(ctx, ticket.IssueKey)
+		if err != nil {
+			log.Logger.Warn("error getting ticket status, the last known status is shown", "issue", ticket.IssueKey, "error", err)
+			continue
+		}
+		if status == ticket.Status {
+			continue
+		}
+		if err := db.SetTicketStatus(ticket.IssueKey, status); err != nil {
+			log.Logger.Error("error recording ticket status", "issue", ticket.IssueKey, "error", err)
+		}
+	}
+}
+
+// createAutomatic tickets the critical vulnerabilities first seen since automatic mode was enabled for the project
+// Automatic mode is baselined to the latest snapshot when it's first enabled, so the vulnerabilities already in the findings ledger aren't ticketed
+// Ignored vulnerabilities aren't ticketed, and neither are vulnerabilities the findings ledger hasn't recorded yet
+func (t *ticketer) createAutomatic(ctx context.Context) error {
+	project := t.client.Project()
+	startedAt, found, err := db.GetTicketAutomationStart(project)
+	if err != nil {
+		return err
+	}
+	if !found {
+		// Findings are first seen when a snapshot updates the ledger, so baselining waits for a snapshot to have recorded the current findings
+		latest, ok, err := db.GetLatestSnapshotTime()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Logger.Info("automatic tickets will be baselined once the first snapshot is taken", "project", project)
+			return nil
+		}
+		if err := db.SetTicketAutomationStart(project, latest); err != nil {
+			return err
+		}
+		log.Logger.Info("baselined automatic tickets, critical vulnerabilities first seen from now on will be ticketed", "project", project, "baseline", latest)
+		return nil
+	}
+
+	kubeCtx, cancel := context.WithTimeout(ctx, kubeRequestTimeout)
+	defer cancel()
+	reports, err := kube.GetVulnerabilityReportList(kubeCtx)
+	if err != nil {
+		return fmt.Errorf("error getting vulnerability reports: %w", err)
+	}
+
+	firstSeen, err := db.GetOpenFindingsFirstSeen("", "")
+	if err != nil {
+		return err
+	}
+	// A ticket covers every package the CVE is found in, so the CVE was first seen with its earliest package
+	cveFirstSeen := make(map[db.TicketKey]time.Time)
+	for key, seen := range firstSeen {
+		k := db.TicketKey{Registry: key.Registry, Repository: key.Repository, CVEID: key.CVEID}
+		if earliest, ok := cveFirstSeen[k]; !ok || seen.Before(earliest) {
+			cveFirstSeen[k] = seen
+		}
+	}
+
+	existing, err := db.GetTickets(db.TicketKey{})
+	if err != nil {
+		return err
+	}
+
+	// The images view leaves out ignored vulnerabilities
+	var pending []db.TicketKey
+	seen := make(map[db.TicketKey]struct{})
+	for _, image := range imagesview.GetView(reports, nil, imagesview.Filters{}) {
+		for _, v := range image.CriticalVulnerabilities {
+			key := db.TicketKey{Registry: image.Registry, Repository: image.Name, CVEID: v.ID}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			if _, ok := existing[key]; ok {
+				continue
+			}
+			if first, ok := cveFirstSeen[key]; !ok || !first.After(startedAt) {
+				continue
+			}
+			pending = append(pending, key)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.Slice(pending, func(j, k int) bool {
+		a := utils.AssembleImageFullName(pending[j].Registry, pending[j].Repository, "", "") + " " + pending[j].CVEID
+		b := utils.AssembleImageFullName(pending[k].Registry, pending[k].Repository, "", "") + " " + pending[k].CVEID
+		return a < b
+	})
+	if len(pending) > maxAutomaticTicketsPerCheck {
+		log.Logger.Warn("more new critical vulnerabilities than can be ticketed at once, the rest will be ticketed on the following intervals", "pending", len(pending), "limit", maxAutomaticTicketsPerCheck)
+		pending = pending[:maxAutomaticTicketsPerCheck]
+	}
+
+	findings := t.findings(reports)
+	for _, key := range pending {
+		finding, ok := findings[key]
+		if !ok {
+			continue
+		}
+		ticket, _, err := t.create(ctx, finding, "", true)
+		if err != nil {
+			log.Logger.Error("error creating automatic ticket", "cve", key.CVEID, "repository", key.Repository, "error", err)
+			continue
+		}
+		log.Logger.Info("created automatic ticket", "issue", ticket.IssueKey, "cve", key.CVEID, "repository", key.Repository)
+	}
+	return nil
+}
+
+// create creates a ticket for the finding and records it, unless the finding already has one
+// The returned bool is false when the finding's existing ticket is returned instead
+func (t *ticketer) create(ctx context.Context, finding jira.Finding, createdBy string, automatic bool) (db.Ticket, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := db.TicketKey{Registry: finding.Registry, Repository: finding.Repository, CVEID: finding.CVEID}
+	existing, err := db.GetTickets(key)
+	if err != nil {
+		return db.Ticket{}, false, err
+	}
+	if ticket, ok := existing[key]; ok {
+		return ticket, false, nil
+	}
+
+	issue, err := t.client.CreateIssue(ctx, finding)
+	if err != nil {
+		return db.Ticket{}, false, err
+	}
+	ticket := db.Ticket{
+		Registry:   key.Registry,
+		Repository: key.Repository,
+		CVEID:      key.CVEID,
+		IssueKey:   issue.Key,
+		URL:        issue.URL,
+		CreatedAt:  time.Now(),
+		CreatedBy:  createdBy,
+		Automatic:  automatic,
+	}
+	// The status is best effort, it's refreshed on the next interval anyway
+	ticket.Status, err = t.client.Status(ctx, issue.Key)
+	if err != nil {
+		log.Logger.Warn("error getting new ticket's status", "issue", issue.Key, "error", err)
+	}
+
+	if err := db.InsertTicket(ticket); err != nil {
+		// The issue exists in the tracker even though it couldn't be recorded, so say which one it was
+		return db.Ticket{}, false, fmt.Errorf("created issue %s but couldn't record it: %w", issue.Key, err)
+	}
+	return ticket, true, nil
+}
+
+// findings gathers the ticket template data for every CVE in each image repository from the vulnerability reports
+func (t *ticketer) findings(reports *v1alpha1.VulnerabilityReportList) map[db.TicketKey]jira.Finding {
+	type sets struct {
+		tags, namespaces, workloads, links map[string]struct{}
+		packages                           map[jira.Package]struct{}
+	}
+	findings := make(map[db.TicketKey]*jira.Finding)
+	found := make(map[db.TicketKey]*sets)
+
+	for _, item := range reports.Items {
+		registry := utils.FormatPrettyImageRegistry(item.Report.Registry.Server)
+		repository := utils.FormatPrettyImageRepo(item.Report.Artifact.Repository)
+		namespace := item.ObjectMeta.Labels["trivy-operator.resource.namespace"]
+		workload := fmt.Sprintf("%s/%s/%s", namespace, item.ObjectMeta.Labels["trivy-operator.resource.kind"], item.ObjectMeta.Labels["trivy-operator.resource.name"])
+
+		for _, v := range item.Report.Vulnerabilities {
+			key := db.TicketKey{Registry: registry, Repository: repository, CVEID: v.VulnerabilityID}
+			f, ok := findings[key]
+			if !ok {
+				f = &jira.Finding{
+					CVEID:       v.VulnerabilityID,
+					Severity:    string(v.Severity),
+					Title:       v.Title,
+					Description: v.Description,
+					Registry:    registry,
+					Repository:  repository,
+					Image:       utils.AssembleImageFullName(registry, repository, "", ""),
+					URL:         t.link("/cve?id=" + url.QueryEscape(v.VulnerabilityID)),
+				}
+				findings[key] = f
+				found[key] = &sets{
+					tags:       make(map[string]struct{}),
+					namespaces: make(map[string]struct{}),
+					workloads:  make(map[string]struct{}),
+					links:      make(map[string]struct{}),
+					packages:   make(map[jira.Package]struct{}),
+				}
+			}
+			if v.Score != nil && *v.Score > f.Score {
+				f.Score = *v.Score
+			}
+
+			s := found[key]
+			if item.Report.Artifact.Tag != "" {
+				s.tags[item.Report.Artifact.Tag] = struct{}{}
+			}
+			if namespace != "" {
+				s.namespaces[namespace] = struct{}{}
+				s.workloads[workload] = struct{}{}
+			}
+			if v.PrimaryLink != "" {
+				s.links[v.PrimaryLink] = struct{}{}
+			}
+			for _, link := range v.Links {
+				s.links[link] = struct{}{}
+			}
+			s.packages[jira.Package{Name: v.Resource, InstalledVersion: v.InstalledVersion, FixedVersion: v.FixedVersion}] = struct{}{}
+		}
+	}
+
+	list := make(map[db.TicketKey]jira.Finding, len(findings))
+	for key, f := range findings {
+		s := found[key]
+		f.Tags = sortedKeys(s.tags)
+		f.Namespaces = sortedKeys(s.namespaces)
+		f.Workloads = sortedKeys(s.workloads)
+		f.Links = sortedKeys(s.links)
+		for p := range s.packages {
+			f.Packages = append(f.Packages, p)
+		}
+		sort.Slice(f.Packages, func(j, k int) bool {
+			if f.Packages[j].Name != f.Packages[k].Name {
+				return f.Packages[j].Name < f.Packages[k].Name
+			}
+			return f.Packages[j].InstalledVersion < f.Packages[k].InstalledVersion
+		})
+		list[key] = *f
+	}
+	return list
+}
+
+// link returns an absolute link to a page of the explorer, or an empty string without a base URL
+func (t *ticketer) link(path string) string {
+	if t.baseURL == "" {
+		return ""
+	}
+	return t.baseURL + path
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ticketRequest is the finding a ticket is requested for
+type ticketRequest struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	CVEID      string `json:"cve_id"`
+}
+
+// ticketHandler creates a ticket for a CVE in an image repository, responding with the ticket
+// A finding that already has a ticket isn't ticketed again, its existing ticket is returned with a 200 instead of a 201
+func ticketHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ticketing == nil {
+		http.Error(w, "No ticket tracker is configured", http.StatusNotFound)
+		return
+	}
+
+	var requestData ticketRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		log.Logger.Error("Failed to decode ticket request", "error", err)
+		writeBodyError(w, err, "Invalid JSON")
+		return
+	}
+	// Findings are keyed by the prettified registry and repository names the pages show
+	requestData.Registry = utils.FormatPrettyImageRegistry(requestData.Registry)
+	requestData.Repository = utils.FormatPrettyImageRepo(requestData.Repository)
+	if requestData.Repository == "" || requestData.CVEID == "" {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := kubeContext(r)
+	defer cancel()
+
+	reports, err := kube.GetVulnerabilityReportList(ctx)
+	if err != nil {
+		writeKubeAPIError(w, r, "VulnerabilityReports", err)
+		return
+	}
+
+	finding, ok := ticketing.findings(reports)[db.TicketKey{Registry: requestData.Registry, Repository: requestData.Repository, CVEID: requestData.CVEID}]
+	if !ok {
+		http.Error(w, "CVE not found in the image repository", http.StatusNotFound)
+		return
+	}
+
+	// The tracker's own timeout bounds creating the ticket, rather than the Kubernetes request timeout
+	ticket, created, err := ticketing.create(r.Context(), finding, usernameFromRequest(r), false)
+	if err != nil {
+		log.Logger.Error("Failed to create ticket", "cve", finding.CVEID, "repository", finding.Image, "error", err)
+		http.Error(w, fmt.Sprintf("Failed to create ticket: %s", err), http.StatusBadGateway)
+		return
+	}
+	if created {
+		log.Logger.Info("created ticket", "issue", ticket.IssueKey, "cve", finding.CVEID, "repository", finding.Image, "user", ticket.CreatedBy)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(w).Encode(ticket); err != nil {
+		log.Logger.Error("encountered error encoding ticket json response", "error", err)
+		return
+	}
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/jira"
+)
+
+func TestTicketerRecordsIssues(t *testing.T) {
+	initTestDB(t)
+
+	var mu sync.Mutex
+	created := 0
+	status, statusCode := "To Do", http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+			created++
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"id":"10001","key":"SEC-%d"}`, created)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/rest/api/2/issue/"):
+			w.WriteHeader(statusCode)
+			_, _ = fmt.Fprintf(w, `{"key":"SEC-1","fields":{"status":{"name":%q}}}`, status)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ticketer, err := newTicketer(TicketConfig{
+		Jira:     jira.Config{URL: server.URL, Project: "SEC", IssueType: "Bug", Timeout: 5 * time.Second},
+		Interval: time.Minute,
+	}, "https://explorer.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finding := jira.Finding{CVEID: "CVE-2024-0001", Severity: "CRITICAL", Registry: "index.docker.io", Repository: "library/nginx", Image: "nginx"}
+	key := db.TicketKey{Registry: finding.Registry, Repository: finding.Repository, CVEID: finding.CVEID}
+	recorded := func() db.Ticket {
+		t.Helper()
+		tickets, err := db.GetTickets(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ticket, ok := tickets[key]
+		if !ok {
+			t.Fatal("no ticket was recorded for the finding")
+		}
+		return ticket
+	}
+
+	ticket, createdNow, err := ticketer.create(context.Background(), finding, "alice", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !createdNow || ticket.IssueKey != "SEC-1" || ticket.Status != "To Do" {
+		t.Errorf("got ticket %+v created %t, want a new SEC-1 in To Do", ticket, createdNow)
+	}
+	if got := recorded(); got.IssueKey != "SEC-1" || got.URL != server.URL+"/browse/SEC-1" || got.CreatedBy != "alice" || got.Status != "To Do" {
+		t.Errorf("recorded ticket %+v, want SEC-1 in To Do created by alice", got)
+	}
+
+	// The finding's existing ticket is returned rather than creating another
+	ticket, createdNow, err = ticketer.create(context.Background(), finding, "bob", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	issues := created
+	mu.Unlock()
+	if createdNow || ticket.IssueKey != "SEC-1" || issues != 1 {
+		t.Errorf("got ticket %s created %t after %d issues were created, want the existing SEC-1", ticket.IssueKey, createdNow, issues)
+	}
+
+	// Refreshing records the issue's new status
+	mu.Lock()
+	status = "Done"
+	mu.Unlock()
+	ticketer.refreshStatuses(context.Background())
+	if got := recorded().Status; got != "Done" {
+		t.Errorf("got status %q after refreshing, want Done", got)
+	}
+
+	// A status that can't be looked up keeps the last known one
+	mu.Lock()
+	status, statusCode = "", http.StatusServiceUnavailable
+	mu.Unlock()
+	ticketer.refreshStatuses(context.Background())
+	if got := recorded().Status; got != "Done" {
+		t.Errorf("got status %q after a failed refresh, want the last known Done", got)
+	}
+}
@@ -0,0 +1,111 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// certReloadInterval is how often the certificate files are checked for changes
+const certReloadInterval = 30 * time.Second
+
+// certReloader serves a TLS certificate loaded from files, and reloads it when the files change
+// This lets certificates mounted from a Secret (such as one managed by cert-manager) be rotated without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload loads the certificate and key from their files
+func (c *certReloader) reload() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading TLS certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+	return nil
+}
+
+// latestModTime returns the most recent modification time of the certificate and key files
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error reading TLS file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// watch polls the certificate files for changes until the context is done
+// Failed reloads are logged and the previously loaded certificate keeps being served
+func (c *certReloader) watch(ctx context.Context) {
+	ticker := time.NewTicker(certReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, err := c.latestModTime()
+			if err != nil {
+				log.Logger.Error("error checking TLS certificate files for changes", "error", err)
+				continue
+			}
+
+			c.mu.RLock()
+			changed := !modTime.Equal(c.modTime)
+			c.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := c.reload(); err != nil {
+				log.Logger.Error("error reloading TLS certificate, continuing to serve the previous certificate", "error", err)
+				continue
+			}
+			log.Logger.Info("reloaded TLS certificate", "cert", c.certFile)
+		}
+	}
+}
+
+// GetCertificate implements the tls.Config GetCertificate callback
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
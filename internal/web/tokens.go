@@ -0,0 +1,160 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+)
+
+// tokensTemplateData contains the data for the /tokens page
+type tokensTemplateData struct {
+	PageRoute string
+	Tokens    []db.APIToken
+	Scopes    []string
+	// NewToken is the plaintext value of a token that was just created, it's only ever displayed once
+	NewToken string
+	Error    string
+}
+
+func tokensHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		renderTokensPage(w, r, tokensTemplateData{})
+	case http.MethodPost:
+		createTokenHandler(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func createTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Logger.Error("Failed to parse create token form", "error", err)
+		writeBodyError(w, err, "Invalid form")
+		return
+	}
+
+	// Users can't grant scopes they don't have, so only admins can create admin tokens
+	grantable := grantableScopes(r)
+	for _, scope := range r.PostForm["scopes"] {
+		if !containsScope(grantable, scope) {
+			log.Logger.Warn("rejecting api token with a scope the user can't grant", "user", usernameFromRequest(r), "scope", scope)
+			w.WriteHeader(http.StatusBadRequest)
+			renderTokensPage(w, r, tokensTemplateData{Error: fmt.Sprintf("You can't create a token with the %q scope", scope)})
+			return
+		}
+	}
+
+	token, err := db.CreateAPIToken(r.PostForm.Get("name"), usernameFromRequest(r), r.PostForm["scopes"])
+	if err != nil {
+		log.Logger.Warn("Failed to create api token", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		renderTokensPage(w, r, tokensTemplateData{Error: err.Error()})
+		return
+	}
+
+	renderTokensPage(w, r, tokensTemplateData{NewToken: token})
+}
+
+func revokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Logger.Error("Failed to parse revoke token form", "error", err)
+		writeBodyError(w, err, "Invalid form")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PostForm.Get("id"))
+	if err != nil {
+		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	// Admins can revoke any token, everyone else only their own
+	user, _ := userFromRequest(r)
+	if user.HasScope(db.ScopeAdmin) {
+		err = db.RevokeAPIToken(id, user.Name)
+	} else {
+		err = db.RevokeOwnedAPIToken(id, user.Name, user.Name)
+	}
+	if errors.Is(err, db.ErrAPITokenNotFound) {
+		http.Error(w, "Token not found or already revoked", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Logger.Error("Failed to revoke api token", "id", id, "error", err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/tokens", http.StatusSeeOther)
+}
+
+func renderTokensPage(w http.ResponseWriter, r *http.Request, data tokensTemplateData) {
+	tmpl := newTemplate(r, "tokens.html")
+	if tmpl == nil {
+		log.Logger.Error("encountered error parsing tokens html template")
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	// Admins see every token, everyone else only their own
+	user, _ := userFromRequest(r)
+	var tokens []db.APIToken
+	var err error
+	if user.HasScope(db.ScopeAdmin) {
+		tokens, err = db.GetAPITokens()
+	} else {
+		tokens, err = db.GetAPITokensByOwner(user.Name)
+	}
+	if err != nil {
+		log.Logger.Error("error getting api tokens", "error", err.Error())
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+
+	data.PageRoute = "tokens"
+	data.Tokens = tokens
+	data.Scopes = grantableScopes(r)
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		log.Logger.Error("encountered error executing tokens html template", "error", err)
+		http.Error(w, "Internal Server Error, check server logs", http.StatusInternalServerError)
+		return
+	}
+}
+
+// grantableScopes returns the scopes the user making the request can create tokens with
+// Anonymous users have no identity to be an admin by, so they can grant the session scopes
+func grantableScopes(r *http.Request) []string {
+	user, ok := userFromRequest(r)
+	if !ok {
+		user.Scopes = sessionScopes
+	}
+
+	var scopes []string
+	for _, scope := range db.APITokenScopes {
+		if user.HasScope(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
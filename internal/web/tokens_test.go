@@ -0,0 +1,156 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+)
+
+// withUser returns the request as made by the given user
+func withUser(r *http.Request, user User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+}
+
+func TestProxyAdmins(t *testing.T) {
+	a, err := newAuthenticator(AuthConfig{
+		Mode:              AuthModeProxy,
+		ProxyUserHeader:   "X-Forwarded-User",
+		ProxyTrustedCIDRs: []string{"192.0.2.0/24"},
+		ProxyAdmins:       []string{"jane"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got User
+	handler := a.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = userFromRequest(r)
+	}))
+	for _, tt := range []struct {
+		name  string
+		admin bool
+	}{
+		{name: "jane", admin: true},
+		{name: "bob", admin: false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Forwarded-User", tt.name)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if got.Name != tt.name {
+			t.Fatalf("got user %q, want %q", got.Name, tt.name)
+		}
+		if got.HasScope(db.ScopeAdmin) != tt.admin {
+			t.Errorf("%s has the admin scope = %t, want %t", tt.name, got.HasScope(db.ScopeAdmin), tt.admin)
+		}
+		if !got.HasScope(db.ScopeRead) || !got.HasScope(db.ScopeIgnoreWrite) {
+			t.Errorf("%s is missing the session scopes, got %v", tt.name, got.Scopes)
+		}
+	}
+
+	if _, err := newAuthenticator(AuthConfig{Mode: AuthModeNone, ProxyAdmins: []string{"jane"}}); err == nil {
+		t.Error("admins were accepted without the proxy auth mode")
+	}
+}
+
+func TestTokensOwnership(t *testing.T) {
+	initTestDB(t)
+	alice := User{Name: "alice", Scopes: sessionScopes}
+	admin := User{Name: "jane", Scopes: append(append([]string(nil), sessionScopes...), db.ScopeAdmin)}
+	for owner, name := range map[string]string{"alice": "alice-ci", "bob": "bob-ci"} {
+		if _, err := db.CreateAPIToken(name, owner, []string{db.ScopeRead}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tokenID := func(owner string) int {
+		t.Helper()
+		tokens, err := db.GetAPITokensByOwner(owner)
+		if err != nil || len(tokens) != 1 {
+			t.Fatalf("got tokens %+v and error %v for %s, want one token", tokens, err, owner)
+		}
+		return tokens[0].ID
+	}
+	bobsToken, alicesToken := tokenID("bob"), tokenID("alice")
+
+	list := func(user User) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		tokensHandler(w, withUser(httptest.NewRequest(http.MethodGet, "/tokens", nil), user))
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d listing tokens as %s, want 200", w.Code, user.Name)
+		}
+		return w.Body.String()
+	}
+	if body := list(alice); !strings.Contains(body, "alice-ci") || strings.Contains(body, "bob-ci") {
+		t.Error("alice's token list doesn't show only her own tokens")
+	}
+	if body := list(admin); !strings.Contains(body, "alice-ci") || !strings.Contains(body, "bob-ci") {
+		t.Error("an admin's token list doesn't show every token")
+	}
+
+	post := func(user User, path string, form url.Values) int {
+		t.Helper()
+		r := withUser(httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode())), user)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		if path == "/tokens/revoke" {
+			revokeTokenHandler(w, r)
+		} else {
+			tokensHandler(w, r)
+		}
+		return w.Code
+	}
+	revoke := func(user User, id int) int {
+		return post(user, "/tokens/revoke", url.Values{"id": {strconv.Itoa(id)}})
+	}
+
+	// Another user's token looks like it doesn't exist, and stays active
+	if code := revoke(alice, bobsToken); code != http.StatusNotFound {
+		t.Errorf("got status %d revoking another user's token, want 404", code)
+	}
+	if tokens, _ := db.GetAPITokensByOwner("bob"); tokens[0].IsRevoked() {
+		t.Error("alice revoked bob's token")
+	}
+	if code := revoke(alice, alicesToken); code != http.StatusSeeOther {
+		t.Errorf("got status %d revoking your own token, want 303", code)
+	}
+	if code := revoke(admin, bobsToken); code != http.StatusSeeOther {
+		t.Errorf("got status %d revoking another user's token as an admin, want 303", code)
+	}
+
+	// Users can't grant scopes they don't have
+	if code := post(alice, "/tokens", url.Values{"name": {"escalate"}, "scopes": {db.ScopeAdmin}}); code != http.StatusBadRequest {
+		t.Errorf("got status %d creating an admin token as a user, want 400", code)
+	}
+	if code := post(admin, "/tokens", url.Values{"name": {"automation"}, "scopes": {db.ScopeAdmin}}); code != http.StatusOK {
+		t.Errorf("got status %d creating an admin token as an admin, want 200", code)
+	}
+	if want := []string{db.ScopeRead, db.ScopeIgnoreWrite}; !reflect.DeepEqual(grantableScopes(httptest.NewRequest(http.MethodGet, "/tokens", nil)), want) {
+		t.Errorf("anonymous users can grant %v, want %v", grantableScopes(httptest.NewRequest(http.MethodGet, "/tokens", nil)), want)
+	}
+}
+
+func TestRequireTokenManagement(t *testing.T) {
+	handler := requireTokenManagement(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tt := range []struct {
+		name string
+		user User
+		want int
+	}{
+		{name: "session user", user: User{Name: "alice", Scopes: sessionScopes}, want: http.StatusOK},
+		{name: "read token", user: User{Name: "token:ci", Scopes: []string{db.ScopeRead}, APIToken: true}, want: http.StatusForbidden},
+		{name: "admin token", user: User{Name: "token:automation", Scopes: []string{db.ScopeAdmin}, APIToken: true}, want: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		handler(w, withUser(httptest.NewRequest(http.MethodGet, "/tokens", nil), tt.user))
+		if w.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package changes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+)
+
+// DefaultSince is how far back the changes view looks when no point in time is requested
+const DefaultSince = 24 * time.Hour
+
+// ParseSince parses the point in time to show changes since
+// It accepts a duration before now like "24h" or "168h", a time like "2024-05-01T09:00" in the server's time zone as sent by a datetime-local input, or an RFC 3339 time
+func ParseSince(raw string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(raw); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("duration %s must not be negative", raw)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", raw, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration like 24h or a time like 2006-01-02T15:04", raw)
+}
+
+// GetView diffs the current reports against a baseline snapshot for the /changes view
+func GetView(since time.Time, baseline Baseline, current Current) View {
+	view := View{
+		Since:    since,
+		Baseline: baseline.Snapshot.TakenAt,
+	}
+
+	view.Vulnerabilities = vulnerabilityChanges(baseline.Findings, current.Findings)
+	view.DeployedImages, view.RemovedImages = imageChanges(baseline.Images, current.Images)
+
+	type auditKey struct{ namespace, kind, name, checkID string }
+	baselineAudits := make(map[auditKey]struct{}, len(baseline.ConfigAudits))
+	for _, f := range baseline.ConfigAudits {
+		baselineAudits[auditKey{f.Namespace, f.Kind, f.Name, f.CheckID}] = struct{}{}
+	}
+	for _, f := range current.ConfigAudits {
+		if _, ok := baselineAudits[auditKey{f.Namespace, f.Kind, f.Name, f.CheckID}]; ok {
+			continue
+		}
+		view.ConfigAuditFailures = append(view.ConfigAuditFailures, ConfigAuditFailure{
+			Namespace: f.Namespace,
+			Kind:      f.Kind,
+			Name:      f.Name,
+			CheckID:   f.CheckID,
+			Title:     f.Title,
+			Severity:  f.Severity,
+		})
+	}
+
+	type secretKey struct{ registry, repository, ruleID, target string }
+	baselineSecrets := make(map[secretKey]struct{}, len(baseline.ExposedSecrets))
+	for _, s := range baseline.ExposedSecrets {
+		baselineSecrets[secretKey{s.Registry, s.Repository, s.RuleID, s.Target}] = struct{}{}
+	}
+	for _, s := range current.ExposedSecrets {
+		if _, ok := baselineSecrets[secretKey{s.Registry, s.Repository, s.RuleID, s.Target}]; ok {
+			continue
+		}
+		view.ExposedSecrets = append(view.ExposedSecrets, ExposedSecret{
+			Registry:   s.Registry,
+			Repository: s.Repository,
+			RuleID:     s.RuleID,
+			Target:     s.Target,
+			Title:      s.Title,
+			Severity:   s.Severity,
+		})
+	}
+
+	return sortView(view)
+}
+
+// vulnerabilityChanges groups the CVEs that appeared in or disappeared from each image repository
+func vulnerabilityChanges(baseline []db.Finding, current []db.FindingObservation) []ImageVulnerabilities {
+	type repoKey struct{ registry, repository string }
+	repos := make(map[repoKey]*ImageVulnerabilities)
+	get := func(registry, repository string) *ImageVulnerabilities {
+		key := repoKey{registry, repository}
+		if _, ok := repos[key]; !ok {
+			repos[key] = &ImageVulnerabilities{Registry: registry, Repository: repository}
+		}
+		return repos[key]
+	}
+
+	baselineKeys := make(map[db.FindingKey]struct{}, len(baseline))
+	for _, f := range baseline {
+		baselineKeys[db.FindingKey{Registry: f.Registry, Repository: f.Repository, CVEID: f.CVEID, Package: f.Package}] = struct{}{}
+	}
+	currentKeys := make(map[db.FindingKey]struct{}, len(current))
+	for _, o := range current {
+		currentKeys[o.FindingKey] = struct{}{}
+		if _, ok := baselineKeys[o.FindingKey]; ok {
+			continue
+		}
+		repo := get(o.Registry, o.Repository)
+		repo.New = append(repo.New, Vulnerability{CVEID: o.CVEID, Package: o.Package, Severity: o.Severity})
+	}
+	for _, f := range baseline {
+		if _, ok := currentKeys[db.FindingKey{Registry: f.Registry, Repository: f.Repository, CVEID: f.CVEID, Package: f.Package}]; ok {
+			continue
+		}
+		repo := get(f.Registry, f.Repository)
+		repo.Resolved = append(repo.Resolved, Vulnerability{CVEID: f.CVEID, Package: f.Package, Severity: f.Severity})
+	}
+
+	var list []ImageVulnerabilities
+	for _, repo := range repos {
+		list = append(list, *repo)
+	}
+	return list
+}
+
+// imageChanges returns the images that are in the current reports but not the baseline, and the images that are in the baseline but not the current reports
+func imageChanges(baseline, current []db.ImageSnapshot) ([]Image, []Image) {
+	type imageKey struct{ registry, repository, tag, digest string }
+	keys := func(images []db.ImageSnapshot) map[imageKey]struct{} {
+		set := make(map[imageKey]struct{}, len(images))
+		for _, i := range images {
+			set[imageKey{i.Registry, i.Repository, i.Tag, i.Digest}] = struct{}{}
+		}
+		return set
+	}
+	diff := func(images []db.ImageSnapshot, other map[imageKey]struct{}) []Image {
+		var list []Image
+		for _, i := range images {
+			if _, ok := other[imageKey{i.Registry, i.Repository, i.Tag, i.Digest}]; ok {
+				continue
+			}
+			list = append(list, Image{
+				Registry:   i.Registry,
+				Repository: i.Repository,
+				Tag:        i.Tag,
+				Digest:     i.Digest,
+				Critical:   i.Critical,
+				High:       i.High,
+				Medium:     i.Medium,
+				Low:        i.Low,
+			})
+		}
+		return list
+	}
+
+	return diff(current, keys(baseline)), diff(baseline, keys(current))
+}
+
+var severityOrder = map[string]int{
+	"CRITICAL": 0,
+	"HIGH":     1,
+	"MEDIUM":   2,
+	"LOW":      3,
+	"UNKNOWN":  4,
+}
+
+func sortView(v View) View {
+	sort.Slice(v.Vulnerabilities, func(j, k int) bool {
+		return v.Vulnerabilities[j].FullName() < v.Vulnerabilities[k].FullName()
+	})
+	for _, image := range v.Vulnerabilities {
+		for _, vulns := range [][]Vulnerability{image.New, image.Resolved} {
+			sort.Slice(vulns, func(j, k int) bool {
+				if severityOrder[vulns[j].Severity] != severityOrder[vulns[k].Severity] {
+					return severityOrder[vulns[j].Severity] < severityOrder[vulns[k].Severity]
+				}
+				if vulns[j].CVEID != vulns[k].CVEID {
+					return vulns[j].CVEID < vulns[k].CVEID
+				}
+				return vulns[j].Package < vulns[k].Package
+			})
+		}
+	}
+
+	for _, images := range [][]Image{v.DeployedImages, v.RemovedImages} {
+		sort.Slice(images, func(j, k int) bool {
+			return images[j].FullName() < images[k].FullName()
+		})
+	}
+
+	sort.Slice(v.ConfigAuditFailures, func(j, k int) bool {
+		a, b := v.ConfigAuditFailures[j], v.ConfigAuditFailures[k]
+		if severityOrder[strings.ToUpper(a.Severity)] != severityOrder[strings.ToUpper(b.Severity)] {
+			return severityOrder[strings.ToUpper(a.Severity)] < severityOrder[strings.ToUpper(b.Severity)]
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.CheckID < b.CheckID
+	})
+
+	sort.Slice(v.ExposedSecrets, func(j, k int) bool {
+		a, b := v.ExposedSecrets[j], v.ExposedSecrets[k]
+		if severityOrder[strings.ToUpper(a.Severity)] != severityOrder[strings.ToUpper(b.Severity)] {
+			return severityOrder[strings.ToUpper(a.Severity)] < severityOrder[strings.ToUpper(b.Severity)]
+		}
+		if a.FullName() != b.FullName() {
+			return a.FullName() < b.FullName()
+		}
+		return a.Target < b.Target
+	})
+
+	return v
+}
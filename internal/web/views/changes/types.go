@@ -0,0 +1,125 @@
+package changes
+
+import (
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+)
+
+// Current contains the current state of the reports, in the form the snapshots and findings ledgers record it
+type Current struct {
+	Images         []db.ImageSnapshot
+	Findings       []db.FindingObservation
+	ConfigAudits   []db.ConfigAuditFinding
+	ExposedSecrets []db.ExposedSecretFinding
+}
+
+// Baseline contains the state of the reports when a snapshot was taken
+type Baseline struct {
+	Snapshot       db.SnapshotInfo
+	Images         []db.ImageSnapshot
+	Findings       []db.Finding
+	ConfigAudits   []db.ConfigAuditFinding
+	ExposedSecrets []db.ExposedSecretFinding
+}
+
+// View the changes in the reports since a baseline snapshot
+type View struct {
+	// Since is the requested point in time
+	Since time.Time `json:"since"`
+	// Baseline is when the snapshot the reports were diffed against was taken, the latest one at or before Since
+	Baseline time.Time `json:"baseline"`
+
+	// Vulnerabilities are the image repositories with new or resolved CVEs, sorted by name
+	Vulnerabilities []ImageVulnerabilities `json:"vulnerabilities"`
+	// DeployedImages are images with a vulnerability report that weren't in the baseline
+	DeployedImages []Image `json:"deployed_images"`
+	// RemovedImages are images in the baseline that no longer have a vulnerability report
+	RemovedImages []Image `json:"removed_images"`
+	// ConfigAuditFailures are failed config audit checks that weren't failing in the baseline
+	ConfigAuditFailures []ConfigAuditFailure `json:"config_audit_failures"`
+	// ExposedSecrets are exposed secrets that weren't in the baseline
+	ExposedSecrets []ExposedSecret `json:"exposed_secrets"`
+}
+
+// ImageVulnerabilities contains the CVEs that appeared in or disappeared from an image repository
+// CVEs are compared per repository like the findings ledger, so upgrading an image's tag resolves the CVEs it fixes
+type ImageVulnerabilities struct {
+	Registry   string          `json:"registry"`
+	Repository string          `json:"repository"`
+	New        []Vulnerability `json:"new"`
+	Resolved   []Vulnerability `json:"resolved"`
+}
+
+// Vulnerability is a CVE in a package
+type Vulnerability struct {
+	CVEID    string `json:"cve_id"`
+	Package  string `json:"package"`
+	Severity string `json:"severity"`
+}
+
+// Image is a deployed or removed image along with its vulnerability counts, net of ignored vulnerabilities
+type Image struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Digest     string `json:"digest"`
+	Critical   int    `json:"critical"`
+	High       int    `json:"high"`
+	Medium     int    `json:"medium"`
+	Low        int    `json:"low"`
+}
+
+// ConfigAuditFailure is a failed config audit check on a resource
+type ConfigAuditFailure struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	CheckID   string `json:"check_id"`
+	Title     string `json:"title"`
+	Severity  string `json:"severity"`
+}
+
+// ExposedSecret is a secret found in an image repository
+type ExposedSecret struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	RuleID     string `json:"rule_id"`
+	Target     string `json:"target"`
+	Title      string `json:"title"`
+	Severity   string `json:"severity"`
+}
+
+// NewVulnerabilities returns the number of new CVEs across every image repository
+func (v View) NewVulnerabilities() int {
+	var count int
+	for _, image := range v.Vulnerabilities {
+		count += len(image.New)
+	}
+	return count
+}
+
+// ResolvedVulnerabilities returns the number of resolved CVEs across every image repository
+func (v View) ResolvedVulnerabilities() int {
+	var count int
+	for _, image := range v.Vulnerabilities {
+		count += len(image.Resolved)
+	}
+	return count
+}
+
+// FullName returns the image repository's name for display
+func (i ImageVulnerabilities) FullName() string {
+	return utils.AssembleImageFullName(i.Registry, i.Repository, "", "")
+}
+
+// FullName returns the image's name for display
+func (i Image) FullName() string {
+	return utils.AssembleImageFullName(i.Registry, i.Repository, i.Tag, i.Digest)
+}
+
+// FullName returns the image repository's name for display
+func (s ExposedSecret) FullName() string {
+	return utils.AssembleImageFullName(s.Registry, s.Repository, "", "")
+}
@@ -0,0 +1,232 @@
+package compare
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+)
+
+// Filters contains the supported filters for the compare view
+type Filters struct {
+	// A and B are image references, like "nginx:1.25" or "ghcr.io/org/app@sha256:..."
+	A string
+	B string
+
+	// optional filters
+	ShowIgnored bool
+}
+
+// GetView converts some report data to the /compare view
+// Check the view's A.Found and B.Found to see whether both images were found in the report list
+func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters) View {
+	var view View
+	var aVulns, bVulns map[string]Vulnerability
+	view.A, aVulns = getImage(data, filters.A, filters.ShowIgnored)
+	view.B, bVulns = getImage(data, filters.B, filters.ShowIgnored)
+	if !view.A.Found || !view.B.Found {
+		return view
+	}
+
+	for id, v := range aVulns {
+		if _, ok := bVulns[id]; ok {
+			continue
+		}
+		view.Fixed = append(view.Fixed, v)
+	}
+	for id, v := range bVulns {
+		if _, ok := aVulns[id]; ok {
+			view.Unchanged = append(view.Unchanged, v)
+			continue
+		}
+		view.Introduced = append(view.Introduced, v)
+	}
+	view.PackageChanges = packageChanges(packageVersions(aVulns), packageVersions(bVulns))
+
+	return sortView(view)
+}
+
+// getImage finds the VulnerabilityReport for an image reference, and returns the image and its CVEs by ID
+// An image reference without a tag or digest is looked up with the latest tag, like a container runtime would
+func getImage(data *v1alpha1.VulnerabilityReportList, ref string, showIgnored bool) (Image, map[string]Vulnerability) {
+	image := Image{Reference: ref}
+	image.Registry, image.Repository, image.Tag, image.Digest = utils.ParseImageReference(ref)
+	if image.Tag == "" && image.Digest == "" {
+		image.Tag = "latest"
+	}
+
+	for _, item := range data.Items {
+		if !utils.ImageReferenceMatches(ref,
+			utils.FormatPrettyImageRegistry(item.Report.Registry.Server),
+			utils.FormatPrettyImageRepo(item.Report.Artifact.Repository),
+			item.Report.Artifact.Tag,
+			item.Report.Artifact.Digest,
+		) {
+			continue
+		}
+
+		// Every workload running the image has its own report of the same scan, so the first one found is enough
+		image.Found = true
+		image.Tag = item.Report.Artifact.Tag
+		image.Digest = item.Report.Artifact.Digest
+
+		var ignoredCVEs map[string]db.IgnoredImageVulnerability
+		if !showIgnored {
+			var err error
+			ignoredCVEs, err = db.GetIgnoredCVEsForImage(item.Report.Registry.Server, image.Repository, image.Tag)
+			if err != nil {
+				log.Logger.Error("error getting ignored CVEs", "error", err.Error())
+				// Continue without ignored CVEs rather than failing the request
+				ignoredCVEs = nil
+			}
+		}
+
+		vulns := make(map[string]Vulnerability)
+		ignored := make(map[string]struct{})
+		for _, v := range item.Report.Vulnerabilities {
+			if _, isIgnored := ignoredCVEs[v.VulnerabilityID]; isIgnored {
+				ignored[v.VulnerabilityID] = struct{}{}
+				continue
+			}
+
+			vuln, ok := vulns[v.VulnerabilityID]
+			if !ok {
+				vuln = Vulnerability{
+					ID:       v.VulnerabilityID,
+					Severity: string(v.Severity),
+					Title:    v.Title,
+					URL:      v.PrimaryLink,
+				}
+				if v.Score != nil {
+					vuln.Score = *v.Score
+				}
+			}
+			p := Package{
+				Name:             v.Resource,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+			}
+			if !vuln.hasPackage(p) {
+				vuln.Packages = append(vuln.Packages, p)
+			}
+			vulns[v.VulnerabilityID] = vuln
+		}
+		image.IgnoredCount = len(ignored)
+
+		return image, vulns
+	}
+
+	return image, nil
+}
+
+func (v Vulnerability) hasPackage(p Package) bool {
+	for _, pkg := range v.Packages {
+		if pkg == p {
+			return true
+		}
+	}
+	return false
+}
+
+// packageVersions returns the installed versions of each vulnerable package
+func packageVersions(vulns map[string]Vulnerability) map[string]map[string]struct{} {
+	versions := make(map[string]map[string]struct{})
+	for _, v := range vulns {
+		for _, p := range v.Packages {
+			if versions[p.Name] == nil {
+				versions[p.Name] = make(map[string]struct{})
+			}
+			versions[p.Name][p.InstalledVersion] = struct{}{}
+		}
+	}
+	return versions
+}
+
+// packageChanges compares the vulnerable package versions of image A to image B
+// An image can install more than one version of a package, so the highest versions are compared to tell upgrades from downgrades
+func packageChanges(a, b map[string]map[string]struct{}) []PackageChange {
+	var changes []PackageChange
+	for name, aVersions := range a {
+		bVersions, ok := b[name]
+		if !ok {
+			changes = append(changes, PackageChange{Name: name, FromVersion: joinVersions(aVersions), Change: ChangeRemoved})
+			continue
+		}
+
+		from, to := joinVersions(aVersions), joinVersions(bVersions)
+		if from == to {
+			continue
+		}
+		change := ChangeUpgraded
+		if utils.CompareVersions(highestVersion(bVersions), highestVersion(aVersions)) < 0 {
+			change = ChangeDowngraded
+		}
+		changes = append(changes, PackageChange{Name: name, FromVersion: from, ToVersion: to, Change: change})
+	}
+	for name, bVersions := range b {
+		if _, ok := a[name]; ok {
+			continue
+		}
+		changes = append(changes, PackageChange{Name: name, ToVersion: joinVersions(bVersions), Change: ChangeAdded})
+	}
+
+	sort.Slice(changes, func(j, k int) bool {
+		return changes[j].Name < changes[k].Name
+	})
+	return changes
+}
+
+// joinVersions returns the versions sorted and comma separated
+func joinVersions(versions map[string]struct{}) string {
+	var list []string
+	for v := range versions {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(j, k int) bool {
+		return utils.CompareVersions(list[j], list[k]) < 0
+	})
+	return strings.Join(list, ", ")
+}
+
+func highestVersion(versions map[string]struct{}) string {
+	var highest string
+	for v := range versions {
+		if highest == "" || utils.CompareVersions(v, highest) > 0 {
+			highest = v
+		}
+	}
+	return highest
+}
+
+var severityOrder = map[string]int{
+	"CRITICAL": 0,
+	"HIGH":     1,
+	"MEDIUM":   2,
+	"LOW":      3,
+	"UNKNOWN":  4,
+}
+
+func sortView(v View) View {
+	// Sort CVEs by severity, then score, so the most important differences are first
+	for _, vulns := range [][]Vulnerability{v.Fixed, v.Introduced, v.Unchanged} {
+		sort.Slice(vulns, func(j, k int) bool {
+			if severityOrder[vulns[j].Severity] != severityOrder[vulns[k].Severity] {
+				return severityOrder[vulns[j].Severity] < severityOrder[vulns[k].Severity]
+			}
+			if vulns[j].Score != vulns[k].Score {
+				return vulns[j].Score > vulns[k].Score
+			}
+			return vulns[j].ID < vulns[k].ID
+		})
+		for _, vuln := range vulns {
+			sort.Slice(vuln.Packages, func(j, k int) bool {
+				return vuln.Packages[j].Name < vuln.Packages[k].Name
+			})
+		}
+	}
+	return v
+}
@@ -0,0 +1,69 @@
+package compare
+
+// View the differences between the vulnerabilities of two images
+// Image A is the image being upgraded from, and image B the image being upgraded to
+type View struct {
+	A Image `json:"a"`
+	B Image `json:"b"`
+	// Fixed are CVEs in image A that aren't in image B
+	Fixed []Vulnerability `json:"fixed"`
+	// Introduced are CVEs in image B that aren't in image A
+	Introduced []Vulnerability `json:"introduced"`
+	// Unchanged are CVEs in both images
+	Unchanged []Vulnerability `json:"unchanged"`
+	// PackageChanges are vulnerable packages whose installed versions differ between the images
+	PackageChanges []PackageChange `json:"package_changes"`
+}
+
+// Image contains data about one of the compared images
+type Image struct {
+	Reference  string `json:"reference"` // the image reference that was requested
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Digest     string `json:"digest"`
+	Found      bool   `json:"found"` // whether a VulnerabilityReport was found for the image
+	// IgnoredCount is the number of this image's CVEs left out of the comparison because they're ignored
+	IgnoredCount int `json:"ignored_count"`
+}
+
+// Vulnerability data related to a CVE
+type Vulnerability struct {
+	// CVE ID
+	ID string `json:"id"`
+	// CVE severity level (eg. Critical/High/Medium/Low)
+	Severity string `json:"severity"`
+	// CVE score from 0-10 with with one decimal place
+	Score float64 `json:"score"`
+	// CVE title (eg. libcarlsjr: remote code execution)
+	Title string `json:"title"`
+	// URL is the URL to the proper CVE database
+	URL string `json:"url"`
+	// Packages are the vulnerable packages, from image B for unchanged CVEs
+	Packages []Package `json:"packages"`
+}
+
+// Package a vulnerable package in an image
+type Package struct {
+	Name             string `json:"name"`
+	InstalledVersion string `json:"installed_version"`
+	FixedVersion     string `json:"fixed_version"`
+}
+
+// Package change types
+const (
+	ChangeUpgraded   = "upgraded"
+	ChangeDowngraded = "downgraded"
+	// ChangeRemoved means the package has no vulnerabilities in image B, it may have been removed or upgraded to a version with none
+	ChangeRemoved = "no longer vulnerable"
+	// ChangeAdded means the package has no vulnerabilities in image A, it may have been added or changed to a vulnerable version
+	ChangeAdded = "newly vulnerable"
+)
+
+// PackageChange describes a vulnerable package whose installed versions differ between the images
+type PackageChange struct {
+	Name        string `json:"name"`
+	FromVersion string `json:"from_version"` // versions installed in image A, comma separated
+	ToVersion   string `json:"to_version"`   // versions installed in image B, comma separated
+	Change      string `json:"change"`
+}
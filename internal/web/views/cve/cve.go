@@ -0,0 +1,180 @@
+package cve
+
+import (
+	"sort"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+)
+
+// Filters contains the supported filters for the cve view
+type Filters struct {
+	ID string
+}
+
+// GetView converts some report data to the /cve view
+// returns view data and "true" if the CVE was found in the report list
+func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters) (View, bool) {
+	view := View{
+		ID: filters.ID,
+	}
+	iMap := make(map[string]*Image)
+	var found bool
+
+	for _, item := range data.Items {
+		// Collect this report's findings for the CVE in question, there may be more than one vulnerable package
+		var packages []Package
+		for _, v := range item.Report.Vulnerabilities {
+			if v.VulnerabilityID != filters.ID {
+				continue
+			}
+
+			// Fill in the CVE details from the first finding
+			if !found {
+				found = true
+				view.Severity = string(v.Severity)
+				if v.Score != nil {
+					view.Score = *v.Score
+				}
+				view.Title = v.Title
+				view.URL = v.PrimaryLink
+			}
+
+			packages = append(packages, Package{
+				Name:             v.Resource,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+			})
+		}
+		if len(packages) == 0 {
+			continue
+		}
+
+		resourceData := ResourceMetadata{
+			Kind:      item.ObjectMeta.Labels["trivy-operator.resource.kind"],
+			Name:      item.ObjectMeta.Labels["trivy-operator.resource.name"],
+			Namespace: item.ObjectMeta.Labels["trivy-operator.resource.namespace"],
+		}
+
+		// Determine if this image is already in the map
+		// We add its resources to the current item in the map if it already exists
+		iMapKey := utils.AssembleImageFullName(
+			utils.FormatPrettyImageRegistry(item.Report.Registry.Server),
+			utils.FormatPrettyImageRepo(item.Report.Artifact.Repository),
+			item.Report.Artifact.Tag,
+			item.Report.Artifact.Digest,
+		)
+		if image, ok := iMap[iMapKey]; ok {
+			if !image.hasResource(resourceData) {
+				image.Resources = append(image.Resources, resourceData)
+			}
+			continue
+		}
+
+		image := &Image{
+			Registry:   utils.FormatPrettyImageRegistry(item.Report.Registry.Server),
+			Repository: utils.FormatPrettyImageRepo(item.Report.Artifact.Repository),
+			Tag:        item.Report.Artifact.Tag,
+			Digest:     item.Report.Artifact.Digest,
+			Packages:   uniquePackages(packages),
+			Resources:  []ResourceMetadata{resourceData},
+		}
+
+		// Get this image's ignore status for the CVE from the database
+		ignoredCVEs, err := db.GetIgnoredCVEsForImage(item.Report.Registry.Server, image.Repository, image.Tag)
+		if err != nil {
+			log.Logger.Error("error getting ignored CVEs", "error", err.Error())
+			// Continue without ignored CVEs rather than failing the request
+			ignoredCVEs = nil
+		}
+		if ignored, ok := ignoredCVEs[filters.ID]; ok {
+			image.IsIgnored = true
+			image.IgnoreReason = ignored.Reason
+			image.IgnoredBy = ignored.IgnoredBy
+		}
+
+		iMap[iMapKey] = image
+	}
+
+	if !found {
+		return View{}, false
+	}
+
+	for _, image := range iMap {
+		view.Images = append(view.Images, *image)
+	}
+
+	return sortView(view), true
+}
+
+// SetTickets sets the ticket created for the CVE in each affected image's repository, updating the images in place
+func SetTickets(v View, tickets map[db.TicketKey]db.Ticket) {
+	for i, image := range v.Images {
+		t, ok := tickets[db.TicketKey{
+			Registry:   image.Registry,
+			Repository: image.Repository,
+			CVEID:      v.ID,
+		}]
+		if ok {
+			v.Images[i].Ticket = &Ticket{
+				Key:    t.IssueKey,
+				URL:    t.URL,
+				Status: t.Status,
+			}
+		}
+	}
+}
+
+func (i *Image) hasResource(r ResourceMetadata) bool {
+	for _, resource := range i.Resources {
+		if resource == r {
+			return true
+		}
+	}
+	return false
+}
+
+// uniquePackages removes duplicate package findings
+// Seems rare, but Trivy Operator sometimes gives duplicate CVE data for an image
+func uniquePackages(packages []Package) []Package {
+	var unique []Package
+	seen := make(map[Package]struct{})
+	for _, p := range packages {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		unique = append(unique, p)
+	}
+	return unique
+}
+
+func sortView(v View) View {
+	// Sort images by name, so the affected images are easy to scan through
+	sort.Slice(v.Images, func(j, k int) bool {
+		nameJ := utils.AssembleImageFullName(v.Images[j].Registry, v.Images[j].Repository, v.Images[j].Tag, v.Images[j].Digest)
+		nameK := utils.AssembleImageFullName(v.Images[k].Registry, v.Images[k].Repository, v.Images[k].Tag, v.Images[k].Digest)
+		if nameJ != nameK {
+			return nameJ < nameK
+		}
+		return v.Images[j].Digest < v.Images[k].Digest
+	})
+
+	// Sort each image's resources by namespace, kind, then name
+	for _, image := range v.Images {
+		sort.Slice(image.Resources, func(j, k int) bool {
+			if image.Resources[j].Namespace != image.Resources[k].Namespace {
+				return image.Resources[j].Namespace < image.Resources[k].Namespace
+			}
+			if image.Resources[j].Kind != image.Resources[k].Kind {
+				return image.Resources[j].Kind < image.Resources[k].Kind
+			}
+			return image.Resources[j].Name < image.Resources[k].Name
+		})
+	}
+
+	return v
+}
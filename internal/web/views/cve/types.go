@@ -0,0 +1,81 @@
+package cve
+
+// View data about a CVE and every image it affects
+type View struct {
+	// CVE ID
+	ID string `json:"id"`
+	// CVE severity level (eg. Critical/High/Medium/Low)
+	Severity string `json:"severity"`
+	// CVE score from 0-10 with with one decimal place
+	Score float64 `json:"score"`
+	// CVE title (eg. libcarlsjr: remote code execution)
+	Title string `json:"title"`
+	// URL is the URL to the proper CVE database
+	URL    string  `json:"url"`
+	Images []Image `json:"images"`
+}
+
+// Image contains data about an image affected by the CVE
+type Image struct {
+	Registry   string `json:"registry"`   // registry server (e.g., index.docker.io)
+	Repository string `json:"repository"` // repository name
+	Tag        string `json:"tag"`        // image tag
+	Digest     string `json:"digest"`     // sha digest of the image
+	// Packages are the vulnerable packages in this image, an image may ship the same vulnerable code in more than one package
+	Packages []Package `json:"packages"`
+	// Resources are the workloads running this image
+	Resources []ResourceMetadata `json:"resources"`
+	// Whether this CVE is ignored for this image
+	IsIgnored bool `json:"ignored"`
+	// Reason why this CVE is ignored (if applicable)
+	IgnoreReason string `json:"ignore_reason,omitempty"`
+	// User that ignored this CVE (if applicable, empty when ignored anonymously)
+	IgnoredBy string `json:"ignored_by,omitempty"`
+	// Ticket is the issue created in the ticket tracker for the CVE in this image's repository, nil if there isn't one
+	Ticket *Ticket `json:"ticket,omitempty"`
+}
+
+// Ticket is an issue created in the ticket tracker for the CVE
+type Ticket struct {
+	Key string `json:"key"`
+	URL string `json:"url"`
+	// Status is the issue's status when it was last checked, empty until it has been checked
+	Status string `json:"status"`
+}
+
+// Package contains data about a vulnerable package in an image
+type Package struct {
+	// Package name (eg. curl, libcurl)
+	Name string `json:"name"`
+	// The vulnerable installed package version
+	InstalledVersion string `json:"installed_version"`
+	// The version this vulnerability is fixed in
+	FixedVersion string `json:"fixed_version"`
+}
+
+// ResourceMetadata contains data about a Kubernetes resource running an affected image
+type ResourceMetadata struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// IgnoredCount returns the number of affected images the CVE is ignored for
+func (v View) IgnoredCount() int {
+	var count int
+	for _, i := range v.Images {
+		if i.IsIgnored {
+			count++
+		}
+	}
+	return count
+}
+
+// ResourceCount returns the number of workloads running an affected image
+func (v View) ResourceCount() int {
+	var count int
+	for _, i := range v.Images {
+		count += len(i.Resources)
+	}
+	return count
+}
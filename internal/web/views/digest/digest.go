@@ -0,0 +1,262 @@
+package digest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/email"
+	changesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/changes"
+	complianceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/compliance"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+	indexview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/index"
+	slaview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/sla"
+
+	"sigs.k8s.io/yaml"
+)
+
+// maxListed is the most items listed in each section of a digest, the rest are counted
+const maxListed = 10
+
+// Names of the lists counted in View.Truncated
+const (
+	ListTopImages           = "top_images"
+	ListSLABreaches         = "sla_breaches"
+	ListRepositoryChanges   = "repository_changes"
+	ListDeployedImages      = "deployed_images"
+	ListRemovedImages       = "removed_images"
+	ListConfigAuditFailures = "config_audit_failures"
+	ListExposedSecrets      = "exposed_secrets"
+)
+
+// ParseRecipients parses a YAML or JSON list of digest recipients
+func ParseRecipients(raw string) (Recipients, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var recipients Recipients
+	if err := yaml.UnmarshalStrict([]byte(raw), &recipients); err != nil {
+		return nil, fmt.Errorf("failed to parse digest recipients: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(recipients))
+	for i, r := range recipients {
+		if r.Name == "" {
+			return nil, fmt.Errorf("digest recipient %d has no name", i+1)
+		}
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("digest recipient name %q is used more than once", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if len(r.To) == 0 {
+			return nil, fmt.Errorf("digest recipient %s has no addresses", r.Name)
+		}
+		if err := email.ValidateAddresses(r.To); err != nil {
+			return nil, fmt.Errorf("digest recipient %s: %w", r.Name, err)
+		}
+		for _, ns := range r.Namespaces {
+			if ns == "" {
+				return nil, fmt.Errorf("digest recipient %s has an empty namespace", r.Name)
+			}
+		}
+	}
+	return recipients, nil
+}
+
+// GetView builds a recipient's digest from the cluster-wide views
+// A digest scoped to namespaces only covers the images run in them, and leaves out the cluster-wide compliance reports
+// Changes are attributed to namespaces through the image repositories currently run in them, so images removed from every scoped namespace aren't listed
+func GetView(recipient Recipient, images imagesview.View, compliance complianceview.View, sla slaview.View, slaEnabled bool, changes changesview.View, changesFound bool, generatedAt time.Time) View {
+	v := View{
+		Recipient:    recipient,
+		GeneratedAt:  generatedAt,
+		Since:        changes.Since,
+		SLAEnabled:   slaEnabled,
+		ChangesFound: changesFound,
+		Truncated:    make(map[string]int),
+	}
+
+	namespaces := make(map[string]struct{}, len(recipient.Namespaces))
+	for _, ns := range recipient.Namespaces {
+		namespaces[ns] = struct{}{}
+	}
+	scoped := len(namespaces) > 0
+	inScope := func(namespace string) bool {
+		if !scoped {
+			return true
+		}
+		_, ok := namespaces[namespace]
+		return ok
+	}
+
+	// Images run in the recipient's namespaces, and the repositories they belong to for attributing changes
+	var scopedImages imagesview.View
+	repositories := make(map[string]struct{})
+	for _, image := range images {
+		var imageNamespaces []string
+		seen := make(map[string]struct{})
+		for resource := range image.Resources {
+			if _, ok := seen[resource.Namespace]; ok || !inScope(resource.Namespace) {
+				continue
+			}
+			seen[resource.Namespace] = struct{}{}
+			imageNamespaces = append(imageNamespaces, resource.Namespace)
+		}
+		if scoped && len(imageNamespaces) == 0 {
+			continue
+		}
+		scopedImages = append(scopedImages, image)
+		repositories[image.Registry+"/"+image.Name] = struct{}{}
+
+		sort.Strings(imageNamespaces)
+		v.TopImages = append(v.TopImages, Image{
+			Registry:   image.Registry,
+			Repository: image.Name,
+			Tag:        image.Tag,
+			Digest:     image.Digest,
+			Critical:   len(image.CriticalVulnerabilities),
+			High:       len(image.HighVulnerabilities),
+			Medium:     len(image.MediumVulnerabilities),
+			Low:        len(image.LowVulnerabilities),
+			Namespaces: imageNamespaces,
+		})
+	}
+	inRepositories := func(registry, repository string) bool {
+		if !scoped {
+			return true
+		}
+		_, ok := repositories[registry+"/"+repository]
+		return ok
+	}
+
+	// Severity totals and compliance summaries, like the index page
+	if scoped {
+		compliance = nil
+	}
+	v.Summary = indexview.GetView(scopedImages, compliance)
+	v.ImageCount = len(scopedImages)
+
+	// Images with the most severe vulnerabilities, leaving out images without any
+	sort.SliceStable(v.TopImages, func(j, k int) bool {
+		a, b := v.TopImages[j], v.TopImages[k]
+		if a.Critical != b.Critical {
+			return a.Critical > b.Critical
+		}
+		if a.High != b.High {
+			return a.High > b.High
+		}
+		if a.Medium != b.Medium {
+			return a.Medium > b.Medium
+		}
+		return a.Low > b.Low
+	})
+	var vulnerable []Image
+	for _, image := range v.TopImages {
+		if image.Critical+image.High+image.Medium+image.Low > 0 {
+			vulnerable = append(vulnerable, image)
+		}
+	}
+	v.TopImages = truncate(vulnerable, ListTopImages, v.Truncated)
+
+	// SLA findings are already sorted with the longest overdue first
+	var breaches []slaview.Finding
+	for _, f := range sla.Findings {
+		if !inScope(f.Namespace) {
+			continue
+		}
+		switch f.Status {
+		case slaview.StatusBreached:
+			v.SLABreached++
+			breaches = append(breaches, f)
+		case slaview.StatusDueSoon:
+			v.SLADueSoon++
+		}
+	}
+	v.SLABreaches = truncate(breaches, ListSLABreaches, v.Truncated)
+
+	if !changesFound {
+		return v
+	}
+	v.ChangesBaseline = changes.Baseline
+
+	var repositoryChanges []RepositoryChange
+	for _, image := range changes.Vulnerabilities {
+		if !inRepositories(image.Registry, image.Repository) {
+			continue
+		}
+		c := RepositoryChange{
+			Registry:   image.Registry,
+			Repository: image.Repository,
+			New:        len(image.New),
+			Resolved:   len(image.Resolved),
+		}
+		for _, vuln := range image.New {
+			switch vuln.Severity {
+			case "CRITICAL":
+				c.NewCritical++
+			case "HIGH":
+				c.NewHigh++
+			}
+		}
+		v.NewVulnerabilities += c.New
+		v.ResolvedVulnerabilities += c.Resolved
+		if c.New > 0 {
+			repositoryChanges = append(repositoryChanges, c)
+		}
+	}
+	sort.SliceStable(repositoryChanges, func(j, k int) bool {
+		a, b := repositoryChanges[j], repositoryChanges[k]
+		if a.NewCritical != b.NewCritical {
+			return a.NewCritical > b.NewCritical
+		}
+		if a.NewHigh != b.NewHigh {
+			return a.NewHigh > b.NewHigh
+		}
+		return a.New > b.New
+	})
+	v.RepositoryChanges = truncate(repositoryChanges, ListRepositoryChanges, v.Truncated)
+
+	var deployed, removed []changesview.Image
+	for _, image := range changes.DeployedImages {
+		if inRepositories(image.Registry, image.Repository) {
+			deployed = append(deployed, image)
+		}
+	}
+	for _, image := range changes.RemovedImages {
+		if inRepositories(image.Registry, image.Repository) {
+			removed = append(removed, image)
+		}
+	}
+	v.DeployedImages = truncate(deployed, ListDeployedImages, v.Truncated)
+	v.RemovedImages = truncate(removed, ListRemovedImages, v.Truncated)
+
+	var configAudits []changesview.ConfigAuditFailure
+	for _, f := range changes.ConfigAuditFailures {
+		if inScope(f.Namespace) {
+			configAudits = append(configAudits, f)
+		}
+	}
+	v.ConfigAuditFailures = truncate(configAudits, ListConfigAuditFailures, v.Truncated)
+
+	var secrets []changesview.ExposedSecret
+	for _, s := range changes.ExposedSecrets {
+		if inRepositories(s.Registry, s.Repository) {
+			secrets = append(secrets, s)
+		}
+	}
+	v.ExposedSecrets = truncate(secrets, ListExposedSecrets, v.Truncated)
+
+	return v
+}
+
+// truncate limits a list to maxListed items, counting the rest in truncated under the list's name
+func truncate[T any](list []T, name string, truncated map[string]int) []T {
+	if len(list) <= maxListed {
+		return list
+	}
+	truncated[name] = len(list) - maxListed
+	return list[:maxListed]
+}
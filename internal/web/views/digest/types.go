@@ -0,0 +1,115 @@
+package digest
+
+import (
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+	changesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/changes"
+	indexview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/index"
+	slaview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/sla"
+)
+
+// Recipient is a list of email addresses sent a digest, optionally scoped to some namespaces
+type Recipient struct {
+	// Name identifies the recipient in logs and in the record of when it was last sent a digest, so it should not be changed once in use
+	Name string `json:"name"`
+	// To are the addresses the digest is sent to
+	To []string `json:"to"`
+	// Namespaces scopes the digest to the images and findings in these namespaces, the digest covers the whole cluster when empty
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// Recipients is a list of digest recipients
+type Recipients []Recipient
+
+// View contains the data a digest email is rendered from
+type View struct {
+	Recipient   Recipient
+	GeneratedAt time.Time
+	// Since is when the previous digest was sent, changes are listed since then
+	Since time.Time
+	// BaseURL is the explorer's external URL for linking to its pages, links are left out when it's empty
+	BaseURL string
+	// Missing describes data that failed to load, so the digest can say what it's missing
+	Missing []string
+
+	// Summary contains the severity totals, and compliance report summaries in digests covering the whole cluster
+	Summary    indexview.View
+	ImageCount int
+	// TopImages are the images with the most severe vulnerabilities, most severe first
+	TopImages []Image
+
+	// SLAEnabled is false when no SLA policies are configured
+	SLAEnabled  bool
+	SLABreached int
+	SLADueSoon  int
+	// SLABreaches are the findings breaching their SLA, longest overdue first
+	SLABreaches []slaview.Finding
+
+	// ChangesFound is false when there's no snapshot old enough to diff the reports against
+	ChangesFound bool
+	// ChangesBaseline is when the snapshot the changes were diffed against was taken
+	ChangesBaseline         time.Time
+	NewVulnerabilities      int
+	ResolvedVulnerabilities int
+	// RepositoryChanges are the image repositories with the most new CVEs, most first
+	RepositoryChanges   []RepositoryChange
+	DeployedImages      []changesview.Image
+	RemovedImages       []changesview.Image
+	ConfigAuditFailures []changesview.ConfigAuditFailure
+	ExposedSecrets      []changesview.ExposedSecret
+
+	// Truncated counts the items left out of each list to keep the digest readable, keyed by list name
+	Truncated map[string]int
+}
+
+// Image is an image and its vulnerability counts, net of ignored vulnerabilities
+type Image struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+	Critical   int
+	High       int
+	Medium     int
+	Low        int
+	// Namespaces are the namespaces running the image, limited to the recipient's namespaces in a scoped digest
+	Namespaces []string
+}
+
+// RepositoryChange contains the number of CVEs that appeared in or disappeared from an image repository
+type RepositoryChange struct {
+	Registry    string
+	Repository  string
+	New         int
+	NewCritical int
+	NewHigh     int
+	Resolved    int
+}
+
+// Scope describes what the digest covers for display
+func (v View) Scope() string {
+	if len(v.Recipient.Namespaces) == 0 {
+		return "all namespaces"
+	}
+	if len(v.Recipient.Namespaces) == 1 {
+		return "namespace " + v.Recipient.Namespaces[0]
+	}
+	return "namespaces " + strings.Join(v.Recipient.Namespaces, ", ")
+}
+
+// Total returns the length of a list before it was truncated
+func (v View) Total(list string, listed int) int {
+	return listed + v.Truncated[list]
+}
+
+// FullName returns the image's name as it would be written in a Pod spec
+func (i Image) FullName() string {
+	return utils.AssembleImageFullName(i.Registry, i.Repository, i.Tag, i.Digest)
+}
+
+// FullName returns the image repository's name for display
+func (r RepositoryChange) FullName() string {
+	return utils.AssembleImageFullName(r.Registry, r.Repository, "", "")
+}
@@ -3,6 +3,7 @@ package image
 import (
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/starttoaster/trivy-operator-explorer/internal/db"
 	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
@@ -20,6 +21,14 @@ type Filters struct {
 	HasFix      bool
 	ShowIgnored bool
 	Resources   []string
+	// Target only shows vulnerabilities found in this scanned target, like "debian 12.6" or "app/package-lock.json"
+	Target string
+	// Class only shows vulnerabilities in this class of target, one of ClassOSPackages or ClassLanguagePackages
+	Class string
+	// PublishedAfter and PublishedBefore only show vulnerabilities published in the range, zero values leave it open
+	// Vulnerabilities without a published date are hidden when either is set
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
 }
 
 // GetView converts some report data to the /image view
@@ -50,6 +59,7 @@ func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters, ignoredCVE
 			i.OSEndOfServiceLife = "true"
 		}
 
+		targets := make(map[string]struct{})
 		for _, v := range item.Report.Vulnerabilities {
 			// Construct this vulnerability's view data
 			score := 0.0
@@ -59,10 +69,14 @@ func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters, ignoredCVE
 			// Check if this CVE is ignored
 			isIgnored := false
 			ignoredReason := ""
+			ignoredBy := ""
+			var ignoreExpiresAt *time.Time
 			if ignoredCVEs != nil {
 				if val, ok := ignoredCVEs[v.VulnerabilityID]; ok {
 					isIgnored = true
 					ignoredReason = val.Reason
+					ignoredBy = val.IgnoredBy
+					ignoreExpiresAt = val.ExpiresAt
 				}
 			}
 
@@ -77,6 +91,22 @@ func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters, ignoredCVE
 				FixedVersion:      v.FixedVersion,
 				IsIgnored:         isIgnored,
 				IgnoreReason:      ignoredReason,
+				IgnoredBy:         ignoredBy,
+				IgnoreExpiresAt:   ignoreExpiresAt,
+				Description:       v.Description,
+				Links:             v.Links,
+				Target:            v.Target,
+				Class:             v.Class,
+				PackageType:       v.PackageType,
+				PackagePath:       v.PkgPath,
+				PackageURL:        v.PkgPURL,
+				CVSSSource:        v.CVSSSource,
+				CVSS:              getCVSS(v),
+				PublishedDate:     v.PublishedDate,
+				LastModifiedDate:  v.LastModifiedDate,
+			}
+			if v.Target != "" {
+				targets[v.Target] = struct{}{}
 			}
 
 			// We need to check if the vulnerability is unique
@@ -99,6 +129,21 @@ func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters, ignoredCVE
 					continue
 				}
 
+				// Filter target and class
+				if filters.Target != "" && vuln.Target != filters.Target {
+					continue
+				}
+				if filters.Class != "" && vuln.Class != filters.Class {
+					continue
+				}
+
+				// Filter published date
+				if !filters.PublishedAfter.IsZero() || !filters.PublishedBefore.IsZero() {
+					if !publishedInRange(vuln.PublishedDate, filters.PublishedAfter, filters.PublishedBefore) {
+						continue
+					}
+				}
+
 				// Filter by resource
 				if len(filters.Resources) != 0 && filters.Resources[0] != "" {
 					var add bool
@@ -116,6 +161,11 @@ func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters, ignoredCVE
 			}
 		}
 
+		for target := range targets {
+			i.Targets = append(i.Targets, target)
+		}
+		sort.Strings(i.Targets)
+		i.Resources = getImageResources(data, filters)
 		i = sortView(i)
 
 		return i, true
@@ -124,6 +174,112 @@ func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters, ignoredCVE
 	return View{}, false
 }
 
+// SetFirstSeen sets when each of the view's vulnerabilities was first seen from the findings ledger, updating the vulnerabilities in place
+func SetFirstSeen(v View, firstSeen map[db.FindingKey]time.Time) {
+	for i, vuln := range v.Vulnerabilities {
+		seen, ok := firstSeen[db.FindingKey{
+			Registry:   v.Registry,
+			Repository: v.Repository,
+			CVEID:      vuln.ID,
+			Package:    vuln.Resource,
+		}]
+		if ok {
+			v.Vulnerabilities[i].FirstSeen = &seen
+		}
+	}
+}
+
+// SetTickets sets the ticket created for each of the view's vulnerabilities, updating the vulnerabilities in place
+func SetTickets(v View, tickets map[db.TicketKey]db.Ticket) {
+	for i, vuln := range v.Vulnerabilities {
+		t, ok := tickets[db.TicketKey{
+			Registry:   v.Registry,
+			Repository: v.Repository,
+			CVEID:      vuln.ID,
+		}]
+		if ok {
+			v.Vulnerabilities[i].Ticket = &Ticket{
+				Key:    t.IssueKey,
+				URL:    t.URL,
+				Status: t.Status,
+			}
+		}
+	}
+}
+
+// getImageResources returns the resources running the image, from every report for the image
+func getImageResources(data *v1alpha1.VulnerabilityReportList, filters Filters) []ResourceMetadata {
+	resourceMap := make(map[ResourceMetadata]struct{})
+	for _, item := range data.Items {
+		itemImageName := utils.AssembleImageFullName(
+			utils.FormatPrettyImageRegistry(item.Report.Registry.Server),
+			utils.FormatPrettyImageRepo(item.Report.Artifact.Repository),
+			item.Report.Artifact.Tag,
+			item.Report.Artifact.Digest,
+		)
+		if filters.Name != itemImageName || filters.Digest != item.Report.Artifact.Digest {
+			continue
+		}
+
+		resourceMap[ResourceMetadata{
+			Kind:      item.ObjectMeta.Labels["trivy-operator.resource.kind"],
+			Name:      item.ObjectMeta.Labels["trivy-operator.resource.name"],
+			Namespace: item.ObjectMeta.Labels["trivy-operator.resource.namespace"],
+		}] = struct{}{}
+	}
+
+	var resources []ResourceMetadata
+	for r := range resourceMap {
+		resources = append(resources, r)
+	}
+	sort.Slice(resources, func(j, k int) bool {
+		if resources[j].Namespace != resources[k].Namespace {
+			return resources[j].Namespace < resources[k].Namespace
+		}
+		if resources[j].Kind != resources[k].Kind {
+			return resources[j].Kind < resources[k].Kind
+		}
+		return resources[j].Name < resources[k].Name
+	})
+	return resources
+}
+
+// getCVSS returns a vulnerability's CVSS vectors and scores sorted by source
+func getCVSS(v v1alpha1.Vulnerability) []CVSS {
+	var cvss []CVSS
+	for source, c := range v.CVSS {
+		cvss = append(cvss, CVSS{
+			Source:    string(source),
+			V2Vector:  c.V2Vector,
+			V2Score:   c.V2Score,
+			V3Vector:  c.V3Vector,
+			V3Score:   c.V3Score,
+			V40Vector: c.V40Vector,
+			V40Score:  c.V40Score,
+		})
+	}
+	sort.Slice(cvss, func(j, k int) bool {
+		return cvss[j].Source < cvss[k].Source
+	})
+	return cvss
+}
+
+// publishedInRange returns true if an RFC 3339 published date is within the range, a zero time leaves that end open
+// The before bound includes the whole day, so a range from and to the same day matches CVEs published that day
+func publishedInRange(published string, after, before time.Time) bool {
+	t, err := time.Parse(time.RFC3339, published)
+	if err != nil {
+		return false
+	}
+	if !after.IsZero() && t.Before(after) {
+		return false
+	}
+	if !before.IsZero() && !t.Before(before.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
 func (i View) isUniqueVulnerability(cveID string) bool {
 	for _, vuln := range i.Vulnerabilities {
 		if cveID == vuln.ID {
@@ -1,40 +1,124 @@
 package image
 
+import "time"
+
 // View data about an image and their vulnerabilities
 type View Data
 
 // Data contains data about image vulnerabilities and metadata about the Resources running that image
 type Data struct {
-	Registry           string // registry server (e.g., index.docker.io)
-	Repository         string // repository name
-	Tag                string // image tag
-	Digest             string // sha digest of the image
-	OSFamily           string // distro name like "debian" or "alpine"
-	OSVersion          string // distro version like "12.6"
-	OSEndOfServiceLife string // end of service life data
-	Vulnerabilities    []Vulnerability
+	Registry           string             `json:"registry"`               // registry server (e.g., index.docker.io)
+	Repository         string             `json:"repository"`             // repository name
+	Tag                string             `json:"tag"`                    // image tag
+	Digest             string             `json:"digest"`                 // sha digest of the image
+	OSFamily           string             `json:"os_family"`              // distro name like "debian" or "alpine"
+	OSVersion          string             `json:"os_version"`             // distro version like "12.6"
+	OSEndOfServiceLife string             `json:"os_end_of_service_life"` // end of service life data
+	Resources          []ResourceMetadata `json:"resources"`
+	Vulnerabilities    []Vulnerability    `json:"vulnerabilities"`
+	// Targets are the scanned targets with vulnerabilities in the image, before filtering, like "debian 12.6" or "app/package-lock.json"
+	Targets []string `json:"targets"`
+}
+
+// ResourceMetadata data related to a k8s resource running the image
+type ResourceMetadata struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
 }
 
 // Vulnerability data related to a CVE
 type Vulnerability struct {
 	// CVE ID
-	ID string
+	ID string `json:"id"`
 	// CVE severity level (eg. Critical/High/Medium/Low)
-	Severity string
+	Severity string `json:"severity"`
 	// CVE score from 0-10 with with one decimal place
-	Score float64
+	Score float64 `json:"score"`
 	// URL is the URL to the proper CVE database
-	URL string
+	URL string `json:"url"`
 	// CVE vulnerable resource (eg. curl, libcurl)
-	Resource string
+	Resource string `json:"resource"`
 	// CVE title (eg. libcarlsjr: remote code execution)
-	Title string
+	Title string `json:"title"`
 	// The vulnerable installed resource version
-	VulnerableVersion string
+	VulnerableVersion string `json:"installed_version"`
 	// The version this vulnerability is fixed in
-	FixedVersion string
+	FixedVersion string `json:"fixed_version"`
 	// Whether this CVE is ignored
-	IsIgnored bool
+	IsIgnored bool `json:"ignored"`
 	// Reason why this CVE is ignored (if applicable)
-	IgnoreReason string
+	IgnoreReason string `json:"ignore_reason,omitempty"`
+	// User that ignored this CVE (if applicable, empty when ignored anonymously)
+	IgnoredBy string `json:"ignored_by,omitempty"`
+	// When the ignore stops applying (if applicable, nil when it never expires)
+	IgnoreExpiresAt *time.Time `json:"ignore_expires_at,omitempty"`
+
+	// CVE description
+	Description string `json:"description"`
+	// Links are references about the CVE, like advisories and patches
+	Links []string `json:"links"`
+	// Target is the scanned target the vulnerable resource was found in, like "debian 12.6" or "app/package-lock.json"
+	Target string `json:"target"`
+	// Class is the kind of target, "os-pkgs" for OS packages or "lang-pkgs" for language packages
+	Class string `json:"class"`
+	// PackageType is the package ecosystem, like "debian" or "npm"
+	PackageType string `json:"package_type"`
+	// PackagePath is the file the vulnerable resource was found in, for language packages
+	PackagePath string `json:"package_path"`
+	// PackageURL is the package URL (purl) of the vulnerable resource
+	PackageURL string `json:"package_url"`
+	// CVSSSource is the source of the score, like "nvd" or "redhat"
+	CVSSSource string `json:"cvss_source"`
+	// CVSS are the CVSS vectors and scores from each source, sorted by source
+	CVSS []CVSS `json:"cvss"`
+	// PublishedDate and LastModifiedDate are RFC 3339 timestamps, and may be empty
+	PublishedDate    string `json:"published_date"`
+	LastModifiedDate string `json:"last_modified_date"`
+
+	// FirstSeen is when the findings ledger first observed the vulnerability in the image's repository, nil if it hasn't been recorded yet
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+	// Ticket is the issue created in the ticket tracker for the vulnerability in the image's repository, nil if there isn't one
+	Ticket *Ticket `json:"ticket,omitempty"`
+}
+
+// Ticket is an issue created in the ticket tracker for a vulnerability
+type Ticket struct {
+	Key string `json:"key"`
+	URL string `json:"url"`
+	// Status is the issue's status when it was last checked, empty until it has been checked
+	Status string `json:"status"`
+}
+
+// CVSS the CVSS vectors and scores for a CVE from one source
+type CVSS struct {
+	Source    string  `json:"source"`
+	V2Vector  string  `json:"v2_vector,omitempty"`
+	V2Score   float64 `json:"v2_score,omitempty"`
+	V3Vector  string  `json:"v3_vector,omitempty"`
+	V3Score   float64 `json:"v3_score,omitempty"`
+	V40Vector string  `json:"v40_vector,omitempty"`
+	V40Score  float64 `json:"v40_score,omitempty"`
+}
+
+// Target classes reported by Trivy
+const (
+	ClassOSPackages       = "os-pkgs"
+	ClassLanguagePackages = "lang-pkgs"
+)
+
+// PublishedDay returns the day the CVE was published as YYYY-MM-DD, or an empty string if it's unknown
+func (v Vulnerability) PublishedDay() string {
+	if len(v.PublishedDate) < len("2006-01-02") {
+		return ""
+	}
+	return v.PublishedDate[:len("2006-01-02")]
+}
+
+// LastModifiedDay returns the day the CVE was last modified as YYYY-MM-DD, or an empty string if it's unknown
+func (v Vulnerability) LastModifiedDay() string {
+	if len(v.LastModifiedDate) < len("2006-01-02") {
+		return ""
+	}
+	return v.LastModifiedDate[:len("2006-01-02")]
 }
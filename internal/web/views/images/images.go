@@ -3,6 +3,7 @@ package images
 import (
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/starttoaster/trivy-operator-explorer/internal/db"
 	"github.com/starttoaster/trivy-operator-explorer/internal/kube"
@@ -225,3 +226,22 @@ func (i *Data) addVulnerabilityData(v Vulnerability) {
 		i.LowVulnerabilities = append(i.LowVulnerabilities, v)
 	}
 }
+
+// SetFirstSeen sets when each of the view's vulnerabilities was first seen from the findings ledger, updating the vulnerabilities in place
+func SetFirstSeen(v View, firstSeen map[db.FindingKey]time.Time) {
+	for _, image := range v {
+		for _, vulns := range [][]Vulnerability{image.CriticalVulnerabilities, image.HighVulnerabilities, image.MediumVulnerabilities, image.LowVulnerabilities} {
+			for j, vuln := range vulns {
+				seen, ok := firstSeen[db.FindingKey{
+					Registry:   image.Registry,
+					Repository: image.Name,
+					CVEID:      vuln.ID,
+					Package:    vuln.Resource,
+				}]
+				if ok {
+					vulns[j].FirstSeen = &seen
+				}
+			}
+		}
+	}
+}
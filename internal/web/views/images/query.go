@@ -0,0 +1,315 @@
+package images
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+)
+
+// Sort orders available to the images view
+const (
+	SortCritical   = "critical"   // most critical vulnerabilities first, the default
+	SortScore      = "score"      // highest CVSS score first
+	SortName       = "name"       // alphabetical by image name
+	SortNamespaces = "namespaces" // most namespaces running the image first
+	SortFixable    = "fixable"    // most vulnerabilities with a fix available first
+)
+
+// SortOptions are the valid sort orders, in the order they're presented
+var SortOptions = []string{SortCritical, SortScore, SortName, SortNamespaces, SortFixable}
+
+// Severities are the valid minimum severities, from most to least severe
+var Severities = []string{"Critical", "High", "Medium", "Low"}
+
+const (
+	// DefaultPageSize is the number of images on a page when no page size is requested
+	DefaultPageSize = 50
+	// MaxPageSize is the largest page size that can be requested
+	MaxPageSize = 500
+)
+
+// Query represents the filters, sort order and page requested from the images view
+// These are applied after GetView, so the other views built from the images view always see every image
+type Query struct {
+	Namespace     string  // only images run in this namespace
+	Registry      string  // only images from this registry, index.docker.io and docker.io both match Docker Hub
+	MinSeverity   string  // only images with a vulnerability of this severity or worse
+	MinScore      float64 // only images with a vulnerability scoring at least this
+	EOSLOnly      bool    // only images based on an OS past its end of service life
+	UnscannedOnly bool    // only images without a vulnerability report
+	Sort          string  // one of SortOptions
+	Page          int     // 1-indexed page number
+	PageSize      int     // number of images on a page
+}
+
+// Page is a single page of the images view
+type Page struct {
+	Data       View
+	Query      Query
+	TotalItems int // number of images matching the query across all pages
+	TotalPages int
+
+	// Namespaces and Registries are the values available to filter on, from the unfiltered view
+	Namespaces []string
+	Registries []string
+}
+
+// HasPrev returns true if there's a page before this one
+func (p Page) HasPrev() bool {
+	return p.Query.Page > 1
+}
+
+// HasNext returns true if there's a page after this one
+func (p Page) HasNext() bool {
+	return p.Query.Page < p.TotalPages
+}
+
+// GetPage filters and sorts the images view, and returns the requested page of it
+// Invalid sort orders and out of range pages or page sizes are replaced with the nearest valid value
+func GetPage(v View, q Query) Page {
+	q = normalizeQuery(q)
+
+	p := Page{
+		Query:      q,
+		Namespaces: namespaces(v),
+		Registries: registries(v),
+	}
+
+	var filtered View
+	for _, image := range v {
+		if q.matches(image) {
+			filtered = append(filtered, image)
+		}
+	}
+	sortPage(filtered, q.Sort)
+
+	p.TotalItems = len(filtered)
+	p.TotalPages = (len(filtered) + q.PageSize - 1) / q.PageSize
+	if p.TotalPages == 0 {
+		p.TotalPages = 1
+	}
+	if p.Query.Page > p.TotalPages {
+		p.Query.Page = p.TotalPages
+	}
+
+	start := (p.Query.Page - 1) * q.PageSize
+	end := start + q.PageSize
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	p.Data = filtered[start:end]
+
+	return p
+}
+
+// normalizeQuery replaces invalid query values with defaults
+func normalizeQuery(q Query) Query {
+	validSort := false
+	for _, s := range SortOptions {
+		if q.Sort == s {
+			validSort = true
+			break
+		}
+	}
+	if !validSort {
+		q.Sort = SortCritical
+	}
+
+	if severityRank(q.MinSeverity) == 0 {
+		q.MinSeverity = ""
+	}
+	// Scores range from 0 to 10, so a score that isn't a finite number can't be a useful filter
+	if math.IsNaN(q.MinScore) || math.IsInf(q.MinScore, 0) || q.MinScore < 0 {
+		q.MinScore = 0
+	}
+
+	if q.PageSize <= 0 {
+		q.PageSize = DefaultPageSize
+	}
+	if q.PageSize > MaxPageSize {
+		q.PageSize = MaxPageSize
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+
+	return q
+}
+
+// matches returns true if the image passes every filter in the query
+func (q Query) matches(image Data) bool {
+	if q.Namespace != "" && !image.inNamespace(q.Namespace) {
+		return false
+	}
+	if q.Registry != "" && utils.FormatPrettyImageRegistry(normalizeRegistry(q.Registry)) != image.registry() {
+		return false
+	}
+	if q.MinSeverity != "" && image.maxSeverityRank() < severityRank(q.MinSeverity) {
+		return false
+	}
+	if q.MinScore > 0 && image.MaxScore() < q.MinScore {
+		return false
+	}
+	if q.EOSLOnly && image.OSEndOfServiceLife == "" {
+		return false
+	}
+	if q.UnscannedOnly && !image.Unscanned {
+		return false
+	}
+	return true
+}
+
+// sortPage sorts the filtered images by the given sort order
+// GetView already sorts by severity counts, so the stable sorts keep that order between ties
+func sortPage(v View, order string) {
+	switch order {
+	case SortScore:
+		sort.SliceStable(v, func(j, k int) bool {
+			return v[j].MaxScore() > v[k].MaxScore()
+		})
+	case SortName:
+		sort.SliceStable(v, func(j, k int) bool {
+			return v[j].FullName() < v[k].FullName()
+		})
+	case SortNamespaces:
+		sort.SliceStable(v, func(j, k int) bool {
+			return v[j].NamespaceCount() > v[k].NamespaceCount()
+		})
+	case SortFixable:
+		sort.SliceStable(v, func(j, k int) bool {
+			return v[j].FixAvailableCount > v[k].FixAvailableCount
+		})
+	}
+}
+
+// FullName returns the image's name as it would be written in a Pod spec
+func (i Data) FullName() string {
+	return utils.AssembleImageFullName(i.Registry, i.Name, i.Tag, i.Digest)
+}
+
+// MaxScore returns the highest score of the image's vulnerabilities
+func (i Data) MaxScore() float64 {
+	var max float64
+	for _, vulns := range [][]Vulnerability{i.CriticalVulnerabilities, i.HighVulnerabilities, i.MediumVulnerabilities, i.LowVulnerabilities} {
+		for _, v := range vulns {
+			if v.Score > max {
+				max = v.Score
+			}
+		}
+	}
+	return max
+}
+
+// NamespaceCount returns the number of namespaces running the image
+func (i Data) NamespaceCount() int {
+	ns := make(map[string]struct{})
+	for r := range i.Resources {
+		ns[r.Namespace] = struct{}{}
+	}
+	return len(ns)
+}
+
+// OldestFirstSeen returns when the image's oldest vulnerability was first seen, nil if none of them have been recorded by the findings ledger
+func (i Data) OldestFirstSeen() *time.Time {
+	var oldest *time.Time
+	for _, vulns := range [][]Vulnerability{i.CriticalVulnerabilities, i.HighVulnerabilities, i.MediumVulnerabilities, i.LowVulnerabilities} {
+		for _, v := range vulns {
+			if v.FirstSeen != nil && (oldest == nil || v.FirstSeen.Before(*oldest)) {
+				oldest = v.FirstSeen
+			}
+		}
+	}
+	return oldest
+}
+
+// registry returns the image's pretty registry
+// Unscanned images come from Pod specs where the registry is still part of the name, so it's split off here
+func (i Data) registry() string {
+	if !i.Unscanned || i.Registry != "" {
+		return i.Registry
+	}
+	registry, _, _, _ := utils.ParseImageReference(i.Name)
+	return registry
+}
+
+// inNamespace returns true if a resource in the namespace runs the image
+func (i Data) inNamespace(namespace string) bool {
+	for r := range i.Resources {
+		if r.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSeverityRank returns the rank of the image's most severe vulnerability, or 0 if it has none
+func (i Data) maxSeverityRank() int {
+	switch {
+	case len(i.CriticalVulnerabilities) > 0:
+		return severityRank("Critical")
+	case len(i.HighVulnerabilities) > 0:
+		return severityRank("High")
+	case len(i.MediumVulnerabilities) > 0:
+		return severityRank("Medium")
+	case len(i.LowVulnerabilities) > 0:
+		return severityRank("Low")
+	}
+	return 0
+}
+
+// severityRank ranks a severity from 4 for Critical to 1 for Low, and returns 0 for unknown severities
+func severityRank(severity string) int {
+	for i, s := range Severities {
+		if strings.EqualFold(s, severity) {
+			return len(Severities) - i
+		}
+	}
+	return 0
+}
+
+// normalizeRegistry maps the common Docker Hub registry aliases to the registry name used in vulnerability reports
+func normalizeRegistry(registry string) string {
+	if registry == "docker.io" {
+		return "index.docker.io"
+	}
+	return registry
+}
+
+// namespaces returns the sorted namespaces running any image in the view
+func namespaces(v View) []string {
+	set := make(map[string]struct{})
+	for _, image := range v {
+		for r := range image.Resources {
+			if r.Namespace != "" {
+				set[r.Namespace] = struct{}{}
+			}
+		}
+	}
+	return sortedKeys(set)
+}
+
+// registries returns the sorted registries of the images in the view, with Docker Hub as index.docker.io
+func registries(v View) []string {
+	set := make(map[string]struct{})
+	for _, image := range v {
+		if image.registry() == "" {
+			set["index.docker.io"] = struct{}{}
+			continue
+		}
+		set[image.registry()] = struct{}{}
+	}
+	return sortedKeys(set)
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
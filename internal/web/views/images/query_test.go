@@ -0,0 +1,222 @@
+package images
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// testView returns n images named image-00 and up, image i has a critical vulnerability scoring i
+func testView(n int) View {
+	var v View
+	for i := 0; i < n; i++ {
+		v = append(v, Data{
+			Registry:                "ghcr.io",
+			Name:                    fmt.Sprintf("image-%02d", i),
+			Tag:                     "latest",
+			CriticalVulnerabilities: []Vulnerability{{ID: fmt.Sprintf("CVE-2024-%04d", i), Severity: "Critical", Score: float64(i)}},
+		})
+	}
+	return v
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  Query
+	}{
+		{
+			name:  "defaults",
+			query: Query{},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: DefaultPageSize},
+		},
+		{
+			name:  "valid values are kept",
+			query: Query{Sort: SortName, MinSeverity: "High", MinScore: 7.5, Page: 3, PageSize: 10},
+			want:  Query{Sort: SortName, MinSeverity: "High", MinScore: 7.5, Page: 3, PageSize: 10},
+		},
+		{
+			name:  "page size is clamped to the maximum",
+			query: Query{PageSize: MaxPageSize + 1},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: MaxPageSize},
+		},
+		{
+			name:  "negative page size",
+			query: Query{PageSize: -5},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: DefaultPageSize},
+		},
+		{
+			name:  "zero page",
+			query: Query{Page: 0},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: DefaultPageSize},
+		},
+		{
+			name:  "negative page",
+			query: Query{Page: -3},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: DefaultPageSize},
+		},
+		{
+			name:  "unknown sort and severity",
+			query: Query{Sort: "newest", MinSeverity: "Catastrophic"},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: DefaultPageSize},
+		},
+		{
+			name:  "infinite min score",
+			query: Query{MinScore: math.Inf(1)},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: DefaultPageSize},
+		},
+		{
+			name:  "not a number min score",
+			query: Query{MinScore: math.NaN()},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: DefaultPageSize},
+		},
+		{
+			name:  "negative min score",
+			query: Query{MinScore: -1},
+			want:  Query{Sort: SortCritical, Page: 1, PageSize: DefaultPageSize},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeQuery(tt.query); got != tt.want {
+				t.Errorf("normalizeQuery(%+v) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPage(t *testing.T) {
+	tests := []struct {
+		name       string
+		images     int
+		query      Query
+		page       int
+		pageSize   int
+		totalItems int
+		totalPages int
+		first      string
+		count      int
+	}{
+		{
+			name:       "first page",
+			images:     25,
+			query:      Query{Sort: SortName, PageSize: 10},
+			page:       1,
+			pageSize:   10,
+			totalItems: 25,
+			totalPages: 3,
+			first:      "image-00",
+			count:      10,
+		},
+		{
+			name:       "last partial page",
+			images:     25,
+			query:      Query{Sort: SortName, Page: 3, PageSize: 10},
+			page:       3,
+			pageSize:   10,
+			totalItems: 25,
+			totalPages: 3,
+			first:      "image-20",
+			count:      5,
+		},
+		{
+			name:       "page past the end is the last page",
+			images:     25,
+			query:      Query{Sort: SortName, Page: 99, PageSize: 10},
+			page:       3,
+			pageSize:   10,
+			totalItems: 25,
+			totalPages: 3,
+			first:      "image-20",
+			count:      5,
+		},
+		{
+			name:       "zero page is the first page",
+			images:     25,
+			query:      Query{Sort: SortName, Page: 0, PageSize: 10},
+			page:       1,
+			pageSize:   10,
+			totalItems: 25,
+			totalPages: 3,
+			first:      "image-00",
+			count:      10,
+		},
+		{
+			name:       "negative page is the first page",
+			images:     25,
+			query:      Query{Sort: SortName, Page: -1, PageSize: 10},
+			page:       1,
+			pageSize:   10,
+			totalItems: 25,
+			totalPages: 3,
+			first:      "image-00",
+			count:      10,
+		},
+		{
+			name:       "page size is clamped to the maximum",
+			images:     MaxPageSize + 10,
+			query:      Query{Sort: SortName, PageSize: MaxPageSize * 2},
+			page:       1,
+			pageSize:   MaxPageSize,
+			totalItems: MaxPageSize + 10,
+			totalPages: 2,
+			first:      "image-00",
+			count:      MaxPageSize,
+		},
+		{
+			name:       "min score filters",
+			images:     10,
+			query:      Query{Sort: SortScore, MinScore: 7},
+			page:       1,
+			pageSize:   DefaultPageSize,
+			totalItems: 3,
+			totalPages: 1,
+			first:      "image-09",
+			count:      3,
+		},
+		{
+			name:       "infinite min score doesn't filter",
+			images:     10,
+			query:      Query{Sort: SortScore, MinScore: math.Inf(1)},
+			page:       1,
+			pageSize:   DefaultPageSize,
+			totalItems: 10,
+			totalPages: 1,
+			first:      "image-09",
+			count:      10,
+		},
+		{
+			name:       "no matches is a single empty page",
+			images:     10,
+			query:      Query{Registry: "quay.io", Page: 2},
+			page:       1,
+			pageSize:   DefaultPageSize,
+			totalItems: 0,
+			totalPages: 1,
+			count:      0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := GetPage(testView(tt.images), tt.query)
+			if p.Query.Page != tt.page || p.Query.PageSize != tt.pageSize {
+				t.Errorf("got page %d of size %d, want page %d of size %d", p.Query.Page, p.Query.PageSize, tt.page, tt.pageSize)
+			}
+			if p.TotalItems != tt.totalItems || p.TotalPages != tt.totalPages {
+				t.Errorf("got %d items on %d pages, want %d items on %d pages", p.TotalItems, p.TotalPages, tt.totalItems, tt.totalPages)
+			}
+			if len(p.Data) != tt.count {
+				t.Fatalf("got %d images on the page, want %d", len(p.Data), tt.count)
+			}
+			if tt.count > 0 && p.Data[0].Name != tt.first {
+				t.Errorf("first image is %s, want %s", p.Data[0].Name, tt.first)
+			}
+			if p.HasPrev() != (tt.page > 1) || p.HasNext() != (tt.page < tt.totalPages) {
+				t.Errorf("got HasPrev %t and HasNext %t on page %d of %d", p.HasPrev(), p.HasNext(), tt.page, tt.totalPages)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package images
 
+import "time"
+
 // View a list of data about images and their vulnerabilities
 type View []Data
 
@@ -50,4 +52,6 @@ type Vulnerability struct {
 	VulnerableVersion string
 	// The version this vulnerability is fixed in
 	FixedVersion string
+	// When the findings ledger first observed the vulnerability in the image's repository, nil if it hasn't been recorded yet
+	FirstSeen *time.Time
 }
@@ -1,6 +1,7 @@
 package index
 
 import (
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
 	complianceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/compliance"
 )
 
@@ -18,4 +19,8 @@ type View struct {
 
 	// Data for compliance reports
 	ComplianceReports []complianceview.Data
+
+	// Data for the trend charts, from the snapshots in the database
+	Trend            []db.TrendPoint
+	ComplianceTrends []db.ComplianceTrend
 }
@@ -0,0 +1,119 @@
+package mttr
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+)
+
+// Severities are the severities with a row in the view, from most to least severe
+var Severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// totals accumulates the findings of one severity
+type totals struct {
+	resolved      int
+	remediateTime time.Duration
+	open          int
+	openAge       time.Duration
+	oldestOpenAge time.Duration
+}
+
+// add counts a finding towards the totals
+func (t *totals) add(f db.Finding, now time.Time) {
+	if f.IsResolved() {
+		t.resolved++
+		t.remediateTime += f.ResolvedAt.Time.Sub(f.FirstSeen)
+		return
+	}
+	age := now.Sub(f.FirstSeen)
+	t.open++
+	t.openAge += age
+	if age > t.oldestOpenAge {
+		t.oldestOpenAge = age
+	}
+}
+
+// row converts the totals to a view row
+func (t totals) row(severity string) Row {
+	r := Row{
+		Severity:      severity,
+		Resolved:      t.resolved,
+		Open:          t.open,
+		OldestOpenAge: t.oldestOpenAge,
+	}
+	if t.resolved > 0 {
+		r.MeanTimeToRemediate = t.remediateTime / time.Duration(t.resolved)
+	}
+	if t.open > 0 {
+		r.MeanOpenAge = t.openAge / time.Duration(t.open)
+	}
+	return r
+}
+
+// GetView converts the findings ledger to the /mttr view
+// A finding observed in several namespaces counts towards each of them, but only once towards the cluster
+func GetView(findings []db.Finding, now time.Time) View {
+	cluster := make(map[string]*totals)
+	namespaces := make(map[string]map[string]*totals)
+
+	for _, f := range findings {
+		severity := normalizeSeverity(f.Severity)
+		totalsFor(cluster, severity).add(f, now)
+
+		for _, ns := range f.Namespaces {
+			if _, ok := namespaces[ns]; !ok {
+				namespaces[ns] = make(map[string]*totals)
+			}
+			totalsFor(namespaces[ns], severity).add(f, now)
+		}
+	}
+
+	v := View{
+		Cluster: rows(cluster),
+	}
+	for name, severities := range namespaces {
+		v.Namespaces = append(v.Namespaces, Namespace{
+			Name: name,
+			Rows: rows(severities),
+		})
+	}
+	sort.Slice(v.Namespaces, func(i, j int) bool {
+		return v.Namespaces[i].Name < v.Namespaces[j].Name
+	})
+
+	return v
+}
+
+// totalsFor returns the totals for a severity, adding them to the map if they're not in it yet
+func totalsFor(m map[string]*totals, severity string) *totals {
+	t, ok := m[severity]
+	if !ok {
+		t = &totals{}
+		m[severity] = t
+	}
+	return t
+}
+
+// rows returns a row for each severity with findings, from most to least severe
+func rows(m map[string]*totals) []Row {
+	var r []Row
+	for _, severity := range Severities {
+		if t, ok := m[severity]; ok {
+			r = append(r, t.row(severity))
+		}
+	}
+	return r
+}
+
+// normalizeSeverity uppercases a severity, and returns UNKNOWN for severities that aren't recognized
+func normalizeSeverity(severity string) string {
+	severity = strings.ToUpper(severity)
+	for _, s := range Severities {
+		if s == severity {
+			return s
+		}
+	}
+	return "UNKNOWN"
+}
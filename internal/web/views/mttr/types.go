@@ -0,0 +1,62 @@
+package mttr
+
+import (
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+)
+
+// View contains remediation times from the findings ledger, for the cluster and each namespace
+type View struct {
+	// Cluster contains a row per severity across every namespace
+	Cluster []Row
+	// Namespaces are sorted by name
+	Namespaces []Namespace
+}
+
+// Namespace contains a namespace's remediation times, a row per severity
+type Namespace struct {
+	Name string
+	Rows []Row
+}
+
+// Row contains the remediation times of the findings of one severity
+type Row struct {
+	Severity string
+
+	// Resolved is the number of findings that have disappeared from the vulnerability reports
+	Resolved int
+	// MeanTimeToRemediate is the mean time between a resolved finding being first seen and resolved
+	MeanTimeToRemediate time.Duration
+
+	// Open is the number of findings still in the vulnerability reports
+	Open int
+	// MeanOpenAge is the mean time since the open findings were first seen
+	MeanOpenAge time.Duration
+	// OldestOpenAge is the time since the oldest open finding was first seen
+	OldestOpenAge time.Duration
+}
+
+// MTTR returns the mean time to remediate formatted for display, or "-" if no findings have been resolved
+func (r Row) MTTR() string {
+	if r.Resolved == 0 {
+		return "-"
+	}
+	return utils.FormatDuration(r.MeanTimeToRemediate)
+}
+
+// OpenAge returns the mean age of the open findings formatted for display, or "-" if there are none
+func (r Row) OpenAge() string {
+	if r.Open == 0 {
+		return "-"
+	}
+	return utils.FormatDuration(r.MeanOpenAge)
+}
+
+// OldestAge returns the age of the oldest open finding formatted for display, or "-" if there are none
+func (r Row) OldestAge() string {
+	if r.Open == 0 {
+		return "-"
+	}
+	return utils.FormatDuration(r.OldestOpenAge)
+}
@@ -0,0 +1,91 @@
+package namespace
+
+import (
+	configauditsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/configaudits"
+	exposedsecretsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/exposedsecrets"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+	rolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/roles"
+)
+
+// GetView converts the cluster-wide views of each report type to the /namespace view for a single namespace
+// The views passed in are already sorted by severity, and that order is kept
+func GetView(name string, images imagesview.View, audits configauditsview.View, roles rolesview.View, secrets exposedsecretsview.View) View {
+	v := View{
+		Name: name,
+	}
+
+	// Images are shared between namespaces, so only keep the ones run by this namespace's resources
+	for _, image := range images {
+		if image.Unscanned {
+			continue
+		}
+
+		resources := make(map[imagesview.ResourceMetadata]struct{})
+		for resource := range image.Resources {
+			if resource.Namespace == name {
+				resources[resource] = struct{}{}
+			}
+		}
+		if len(resources) == 0 {
+			continue
+		}
+
+		image.Resources = resources
+		v.Images = append(v.Images, image)
+		v.ImageTotals = v.ImageTotals.Add(SeverityTotals{
+			Critical: len(image.CriticalVulnerabilities),
+			High:     len(image.HighVulnerabilities),
+			Medium:   len(image.MediumVulnerabilities),
+			Low:      len(image.LowVulnerabilities),
+		})
+	}
+
+	for _, audit := range audits {
+		if audit.Namespace != name {
+			continue
+		}
+		v.ConfigAudits = append(v.ConfigAudits, audit)
+		v.ConfigAuditTotals = v.ConfigAuditTotals.Add(SeverityTotals{
+			Critical: len(audit.CriticalVulnerabilities),
+			High:     len(audit.HighVulnerabilities),
+			Medium:   len(audit.MediumVulnerabilities),
+			Low:      len(audit.LowVulnerabilities),
+		})
+	}
+
+	for _, role := range roles {
+		if role.Namespace != name {
+			continue
+		}
+		v.Roles = append(v.Roles, role)
+		v.RoleTotals = v.RoleTotals.Add(SeverityTotals{
+			Critical: len(role.CriticalVulnerabilities),
+			High:     len(role.HighVulnerabilities),
+			Medium:   len(role.MediumVulnerabilities),
+			Low:      len(role.LowVulnerabilities),
+		})
+	}
+
+	for _, secret := range secrets {
+		resources := make(map[exposedsecretsview.ResourceMetadata]struct{})
+		for resource := range secret.Resources {
+			if resource.Namespace == name {
+				resources[resource] = struct{}{}
+			}
+		}
+		if len(resources) == 0 {
+			continue
+		}
+
+		secret.Resources = resources
+		v.ExposedSecrets = append(v.ExposedSecrets, secret)
+		v.ExposedSecretTotals = v.ExposedSecretTotals.Add(SeverityTotals{
+			Critical: len(secret.Critical),
+			High:     len(secret.High),
+			Medium:   len(secret.Medium),
+			Low:      len(secret.Low),
+		})
+	}
+
+	return v
+}
@@ -0,0 +1,75 @@
+package namespace
+
+import (
+	configauditsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/configaudits"
+	exposedsecretsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/exposedsecrets"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+	rolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/roles"
+)
+
+// View contains every report's data for a single namespace
+type View struct {
+	Name string
+
+	// Images run by the namespace's workloads, with only this namespace's resources listed
+	Images         imagesview.View
+	ConfigAudits   configauditsview.View
+	Roles          rolesview.View
+	ExposedSecrets exposedsecretsview.View
+
+	// Severity totals per report type
+	ImageTotals         SeverityTotals
+	ConfigAuditTotals   SeverityTotals
+	RoleTotals          SeverityTotals
+	ExposedSecretTotals SeverityTotals
+}
+
+// SeverityTotals contains finding counts by severity
+type SeverityTotals struct {
+	Critical int
+	High     int
+	Medium   int
+	Low      int
+}
+
+// Risk weights used to rank namespaces, so one critical finding outweighs several lower severity ones
+const (
+	criticalRiskWeight = 10
+	highRiskWeight     = 5
+	mediumRiskWeight   = 2
+	lowRiskWeight      = 1
+)
+
+// Add returns the sum of two severity totals
+func (t SeverityTotals) Add(o SeverityTotals) SeverityTotals {
+	return SeverityTotals{
+		Critical: t.Critical + o.Critical,
+		High:     t.High + o.High,
+		Medium:   t.Medium + o.Medium,
+		Low:      t.Low + o.Low,
+	}
+}
+
+// Count returns the total number of findings
+func (t SeverityTotals) Count() int {
+	return t.Critical + t.High + t.Medium + t.Low
+}
+
+// RiskScore returns the severity-weighted number of findings
+func (t SeverityTotals) RiskScore() int {
+	return t.Critical*criticalRiskWeight + t.High*highRiskWeight + t.Medium*mediumRiskWeight + t.Low*lowRiskWeight
+}
+
+// Totals returns the severity totals across every report type
+func (v View) Totals() SeverityTotals {
+	return v.ImageTotals.Add(v.ConfigAuditTotals).Add(v.RoleTotals).Add(v.ExposedSecretTotals)
+}
+
+// WorkloadCount returns the number of workloads in the namespace running a scanned image
+func (v View) WorkloadCount() int {
+	var count int
+	for _, image := range v.Images {
+		count += len(image.Resources)
+	}
+	return count
+}
@@ -0,0 +1,59 @@
+package namespaces
+
+import (
+	"sort"
+
+	configauditsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/configaudits"
+	exposedsecretsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/exposedsecrets"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+	namespaceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/namespace"
+	rolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/roles"
+)
+
+// GetView converts the cluster-wide views of each report type to the /namespaces view
+func GetView(images imagesview.View, audits configauditsview.View, roles rolesview.View, secrets exposedsecretsview.View) View {
+	// Collect every namespace that has a report of any type
+	names := make(map[string]struct{})
+	for _, image := range images {
+		if image.Unscanned {
+			continue
+		}
+		for resource := range image.Resources {
+			names[resource.Namespace] = struct{}{}
+		}
+	}
+	for _, audit := range audits {
+		names[audit.Namespace] = struct{}{}
+	}
+	for _, role := range roles {
+		names[role.Namespace] = struct{}{}
+	}
+	for _, secret := range secrets {
+		for resource := range secret.Resources {
+			names[resource.Namespace] = struct{}{}
+		}
+	}
+	// Cluster scoped resources don't belong to a namespace
+	delete(names, "")
+
+	var v View
+	for name := range names {
+		v = append(v, namespaceview.GetView(name, images, audits, roles, secrets))
+	}
+
+	return sortView(v)
+}
+
+func sortView(v View) View {
+	// Sort the slice by risk in descending order, then by name
+	sort.Slice(v, func(j, k int) bool {
+		riskJ := v[j].Totals().RiskScore()
+		riskK := v[k].Totals().RiskScore()
+		if riskJ != riskK {
+			return riskJ > riskK
+		}
+		return v[j].Name < v[k].Name
+	})
+
+	return v
+}
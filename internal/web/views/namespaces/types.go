@@ -0,0 +1,8 @@
+package namespaces
+
+import (
+	namespaceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/namespace"
+)
+
+// View a list of namespaces ranked by risk
+type View []namespaceview.View
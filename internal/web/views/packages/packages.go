@@ -0,0 +1,189 @@
+package packages
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	log "github.com/starttoaster/trivy-operator-explorer/internal/logger"
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+
+	"github.com/aquasecurity/trivy-operator/pkg/apis/aquasecurity/v1alpha1"
+)
+
+// Filters represents the available optional filters to the packages view
+type Filters struct {
+	ShowIgnored bool
+	Name        string // only versions of this package
+	Image       string // only packages in the image named by this image reference
+}
+
+// packageKey identifies a package version
+type packageKey struct {
+	name    string
+	version string
+}
+
+// GetView converts some report data to the /packages view
+func GetView(data *v1alpha1.VulnerabilityReportList, filters Filters) View {
+	pMap := make(map[packageKey]*Data)
+	// CVEs seen per package version, Trivy Operator gives the same CVE once per report for images running in many workloads
+	cveMap := make(map[packageKey]map[string]Vulnerability)
+
+	for _, item := range data.Items {
+		image := Image{
+			Registry: utils.FormatPrettyImageRegistry(item.Report.Registry.Server),
+			Name:     utils.FormatPrettyImageRepo(item.Report.Artifact.Repository),
+			Tag:      item.Report.Artifact.Tag,
+			Digest:   item.Report.Artifact.Digest,
+		}
+		if filters.Image != "" && !utils.ImageReferenceMatches(filters.Image, image.Registry, image.Name, image.Tag, image.Digest) {
+			continue
+		}
+		resourceData := ResourceMetadata{
+			Kind:      item.ObjectMeta.Labels["trivy-operator.resource.kind"],
+			Name:      item.ObjectMeta.Labels["trivy-operator.resource.name"],
+			Namespace: item.ObjectMeta.Labels["trivy-operator.resource.namespace"],
+		}
+
+		// Get ignored CVEs from database (if 'show ignored' filter is false)
+		var ignoredCVEs map[string]db.IgnoredImageVulnerability
+		if !filters.ShowIgnored {
+			var err error
+			ignoredCVEs, err = db.GetIgnoredCVEsForImage(item.Report.Registry.Server, image.Name, image.Tag)
+			if err != nil {
+				log.Logger.Error("error getting ignored CVEs", "error", err.Error())
+				// Continue without ignored CVEs rather than failing the request
+				ignoredCVEs = nil
+			}
+		}
+
+		for _, v := range item.Report.Vulnerabilities {
+			if _, isIgnored := ignoredCVEs[v.VulnerabilityID]; isIgnored {
+				continue
+			}
+
+			if filters.Name != "" && v.Resource != filters.Name {
+				continue
+			}
+
+			key := packageKey{name: v.Resource, version: v.InstalledVersion}
+			p, ok := pMap[key]
+			if !ok {
+				p = &Data{
+					Name:             v.Resource,
+					InstalledVersion: v.InstalledVersion,
+					Images:           make(map[Image]struct{}),
+					Resources:        make(map[ResourceMetadata]struct{}),
+				}
+				pMap[key] = p
+				cveMap[key] = make(map[string]Vulnerability)
+			}
+			p.Images[image] = struct{}{}
+			p.Resources[resourceData] = struct{}{}
+
+			if _, ok := cveMap[key][v.VulnerabilityID]; !ok {
+				cveMap[key][v.VulnerabilityID] = Vulnerability{
+					ID:           v.VulnerabilityID,
+					Severity:     string(v.Severity),
+					FixedVersion: v.FixedVersion,
+				}
+			}
+		}
+	}
+
+	var view View
+	for key, p := range pMap {
+		for _, vuln := range cveMap[key] {
+			p.addVulnerabilityData(vuln)
+
+			fixedVersion := lowestFixedVersion(p.InstalledVersion, vuln.FixedVersion)
+			if fixedVersion == "" {
+				p.UnfixedCount++
+				continue
+			}
+			// Every fixable CVE must be cleared, so the package needs the highest of the CVEs' fixed versions
+			if p.MinFixedVersion == "" || utils.CompareVersions(fixedVersion, p.MinFixedVersion) > 0 {
+				p.MinFixedVersion = fixedVersion
+			}
+		}
+		view = append(view, *p)
+	}
+
+	return sortView(view)
+}
+
+// lowestFixedVersion returns the lowest version in a CVE's fixed version list that's newer than the installed version
+// Trivy lists one fixed version per release branch, like "1.1.1w, 3.0.12", and only one of them is an upgrade on the same branch
+func lowestFixedVersion(installedVersion, fixedVersions string) string {
+	var lowest string
+	for _, fixedVersion := range strings.Split(fixedVersions, ",") {
+		fixedVersion = strings.TrimSpace(fixedVersion)
+		if fixedVersion == "" || utils.CompareVersions(fixedVersion, installedVersion) <= 0 {
+			continue
+		}
+		if lowest == "" || utils.CompareVersions(fixedVersion, lowest) < 0 {
+			lowest = fixedVersion
+		}
+	}
+	return lowest
+}
+
+func (d *Data) addVulnerabilityData(v Vulnerability) {
+	switch v.Severity {
+	case "CRITICAL":
+		d.CriticalVulnerabilities = append(d.CriticalVulnerabilities, v)
+	case "HIGH":
+		d.HighVulnerabilities = append(d.HighVulnerabilities, v)
+	case "MEDIUM":
+		d.MediumVulnerabilities = append(d.MediumVulnerabilities, v)
+	case "LOW":
+		d.LowVulnerabilities = append(d.LowVulnerabilities, v)
+	}
+}
+
+func sortView(v View) View {
+	// Sort each package version's CVEs by ID so they're stable between page loads
+	for _, p := range v {
+		for _, vulns := range [][]Vulnerability{p.CriticalVulnerabilities, p.HighVulnerabilities, p.MediumVulnerabilities, p.LowVulnerabilities} {
+			sort.Slice(vulns, func(j, k int) bool {
+				return vulns[j].ID < vulns[k].ID
+			})
+		}
+	}
+
+	// Sort the slice by severity in descending order, then by name and version
+	sort.Slice(v, func(j, k int) bool {
+		if len(v[j].CriticalVulnerabilities) != len(v[k].CriticalVulnerabilities) {
+			return len(v[j].CriticalVulnerabilities) > len(v[k].CriticalVulnerabilities)
+		}
+		if len(v[j].HighVulnerabilities) != len(v[k].HighVulnerabilities) {
+			return len(v[j].HighVulnerabilities) > len(v[k].HighVulnerabilities)
+		}
+		if len(v[j].MediumVulnerabilities) != len(v[k].MediumVulnerabilities) {
+			return len(v[j].MediumVulnerabilities) > len(v[k].MediumVulnerabilities)
+		}
+		if len(v[j].LowVulnerabilities) != len(v[k].LowVulnerabilities) {
+			return len(v[j].LowVulnerabilities) > len(v[k].LowVulnerabilities)
+		}
+		if v[j].Name != v[k].Name {
+			return v[j].Name < v[k].Name
+		}
+		return utils.CompareVersions(v[j].InstalledVersion, v[k].InstalledVersion) < 0
+	})
+
+	return v
+}
+
+// FixableVulnerabilities returns the package version's CVEs that have a fix newer than the installed version
+func (d Data) FixableVulnerabilities() []Vulnerability {
+	var fixable []Vulnerability
+	for _, vulns := range [][]Vulnerability{d.CriticalVulnerabilities, d.HighVulnerabilities, d.MediumVulnerabilities, d.LowVulnerabilities} {
+		for _, v := range vulns {
+			if lowestFixedVersion(d.InstalledVersion, v.FixedVersion) != "" {
+				fixable = append(fixable, v)
+			}
+		}
+	}
+	return fixable
+}
@@ -0,0 +1,50 @@
+package packages
+
+// View a list of data about vulnerable packages
+type View []Data
+
+// Data contains data about a vulnerable package version and the CVEs, images and workloads it accounts for
+type Data struct {
+	Name             string // package name (eg. openssl, libcurl)
+	InstalledVersion string // the vulnerable installed package version
+	// MinFixedVersion is the lowest version that fixes all of this package version's fixable CVEs
+	MinFixedVersion string
+	// UnfixedCount is the number of this package version's CVEs that don't have a fix available
+	UnfixedCount            int
+	CriticalVulnerabilities []Vulnerability
+	HighVulnerabilities     []Vulnerability
+	MediumVulnerabilities   []Vulnerability
+	LowVulnerabilities      []Vulnerability
+	Images                  map[Image]struct{}            // images shipping this package version
+	Resources               map[ResourceMetadata]struct{} // data about resources running those images
+}
+
+// Image contains data about an image shipping a vulnerable package
+type Image struct {
+	Registry string
+	Name     string
+	Tag      string
+	Digest   string
+}
+
+// ResourceMetadata data related to a k8s resource using a vulnerable image
+type ResourceMetadata struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// Vulnerability data related to a CVE
+type Vulnerability struct {
+	// CVE ID
+	ID string
+	// CVE severity level (eg. Critical/High/Medium/Low)
+	Severity string
+	// The version this vulnerability is fixed in
+	FixedVersion string
+}
+
+// CVECount returns the number of CVEs in this package version
+func (d Data) CVECount() int {
+	return len(d.CriticalVulnerabilities) + len(d.HighVulnerabilities) + len(d.MediumVulnerabilities) + len(d.LowVulnerabilities)
+}
@@ -0,0 +1,116 @@
+package remediation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+)
+
+// csvHeader are the columns of the CSV export
+var csvHeader = []string{
+	"rank", "package", "installed_version", "target_version", "risk_score", "impact",
+	"critical", "high", "medium", "low", "unfixed", "workloads", "cves", "images", "resources",
+}
+
+// ImageNames returns the names of the images shipping the installed version
+func (u Upgrade) ImageNames() []string {
+	names := make([]string, 0, len(u.Images))
+	for _, i := range u.Images {
+		names = append(names, utils.AssembleImageFullName(i.Registry, i.Name, i.Tag, i.Digest))
+	}
+	return names
+}
+
+// resourceNames returns the workloads running the installed version as namespace/kind/name
+func (u Upgrade) resourceNames() []string {
+	names := make([]string, 0, len(u.Resources))
+	for _, r := range u.Resources {
+		names = append(names, fmt.Sprintf("%s/%s/%s", r.Namespace, r.Kind, r.Name))
+	}
+	return names
+}
+
+// WriteCSV writes the plan as CSV, one upgrade per row in ranked order
+// List columns are separated by spaces so they stay in a single cell
+func (v View) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("error writing csv header: %w", err)
+	}
+
+	for i, u := range v.Upgrades {
+		err := cw.Write([]string{
+			strconv.Itoa(i + 1),
+			u.Package,
+			u.InstalledVersion,
+			u.TargetVersion,
+			strconv.Itoa(u.RiskScore()),
+			strconv.Itoa(u.Impact()),
+			strconv.Itoa(u.Resolved.Critical),
+			strconv.Itoa(u.Resolved.High),
+			strconv.Itoa(u.Resolved.Medium),
+			strconv.Itoa(u.Resolved.Low),
+			strconv.Itoa(u.UnfixedCount),
+			strconv.Itoa(len(u.Resources)),
+			strings.Join(u.CVEs, " "),
+			strings.Join(u.ImageNames(), " "),
+			strings.Join(u.resourceNames(), " "),
+		})
+		if err != nil {
+			return fmt.Errorf("error writing csv row for %s %s: %w", u.Package, u.InstalledVersion, err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("error flushing csv: %w", err)
+	}
+	return nil
+}
+
+// WriteMarkdown writes the plan as a Markdown document, with a summary and a table of upgrades in ranked order
+func (v View) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	scope := "the cluster"
+	if v.Image != "" {
+		scope = "`" + v.Image + "`"
+	}
+	totals := v.Totals()
+	fmt.Fprintf(&b, "# Remediation plan for %s\n\n", scope)
+	fmt.Fprintf(&b, "%d package upgrades resolve %d critical, %d high, %d medium and %d low CVEs.\n\n",
+		len(v.Upgrades), totals.Critical, totals.High, totals.Medium, totals.Low)
+
+	b.WriteString("| Rank | Package | Installed | Target | Critical | High | Medium | Low | Unfixed | Workloads | Images |\n")
+	b.WriteString("| ---: | --- | --- | --- | ---: | ---: | ---: | ---: | ---: | ---: | --- |\n")
+	for i, u := range v.Upgrades {
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %d | %d | %d | %d | %d | %d | %s |\n",
+			i+1,
+			markdownCell(u.Package),
+			markdownCell(u.InstalledVersion),
+			markdownCell(u.TargetVersion),
+			u.Resolved.Critical,
+			u.Resolved.High,
+			u.Resolved.Medium,
+			u.Resolved.Low,
+			u.UnfixedCount,
+			len(u.Resources),
+			markdownCell(strings.Join(u.ImageNames(), ", ")),
+		)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("error writing markdown: %w", err)
+	}
+	return nil
+}
+
+// markdownCell escapes characters that would break a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,86 @@
+package remediation
+
+import (
+	"sort"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+	packagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/packages"
+)
+
+// GetView converts a packages view to the /remediation view
+// Pass a packages view filtered to an image for a plan scoped to it, image is the reference it was filtered with
+func GetView(packages packagesview.View, image string) View {
+	view := View{Image: image}
+
+	for _, p := range packages {
+		if p.MinFixedVersion == "" {
+			continue
+		}
+
+		u := Upgrade{
+			Package:          p.Name,
+			InstalledVersion: p.InstalledVersion,
+			TargetVersion:    p.MinFixedVersion,
+			UnfixedCount:     p.UnfixedCount,
+		}
+		for _, v := range p.FixableVulnerabilities() {
+			switch v.Severity {
+			case "CRITICAL":
+				u.Resolved.Critical++
+			case "HIGH":
+				u.Resolved.High++
+			case "MEDIUM":
+				u.Resolved.Medium++
+			case "LOW":
+				u.Resolved.Low++
+			}
+			u.CVEs = append(u.CVEs, v.ID)
+		}
+		for i := range p.Images {
+			u.Images = append(u.Images, i)
+		}
+		for r := range p.Resources {
+			u.Resources = append(u.Resources, r)
+		}
+
+		view.Upgrades = append(view.Upgrades, u)
+	}
+
+	return sortView(view)
+}
+
+func sortView(v View) View {
+	// Rank upgrades by the risk they remove across the cluster, then by the risk they remove from each image
+	sort.Slice(v.Upgrades, func(j, k int) bool {
+		uj, uk := v.Upgrades[j], v.Upgrades[k]
+		if uj.Impact() != uk.Impact() {
+			return uj.Impact() > uk.Impact()
+		}
+		if uj.RiskScore() != uk.RiskScore() {
+			return uj.RiskScore() > uk.RiskScore()
+		}
+		if uj.Package != uk.Package {
+			return uj.Package < uk.Package
+		}
+		return uj.InstalledVersion < uk.InstalledVersion
+	})
+
+	for _, u := range v.Upgrades {
+		sort.Strings(u.CVEs)
+		sort.Slice(u.Images, func(j, k int) bool {
+			return utils.AssembleImageFullName(u.Images[j].Registry, u.Images[j].Name, u.Images[j].Tag, u.Images[j].Digest) <
+				utils.AssembleImageFullName(u.Images[k].Registry, u.Images[k].Name, u.Images[k].Tag, u.Images[k].Digest)
+		})
+		sort.Slice(u.Resources, func(j, k int) bool {
+			if u.Resources[j].Namespace != u.Resources[k].Namespace {
+				return u.Resources[j].Namespace < u.Resources[k].Namespace
+			}
+			if u.Resources[j].Kind != u.Resources[k].Kind {
+				return u.Resources[j].Kind < u.Resources[k].Kind
+			}
+			return u.Resources[j].Name < u.Resources[k].Name
+		})
+	}
+
+	return v
+}
@@ -0,0 +1,51 @@
+package remediation
+
+import (
+	namespaceview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/namespace"
+	packagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/packages"
+)
+
+// View a remediation plan, the package upgrades that resolve fixable CVEs ranked by the risk they remove
+type View struct {
+	// Image is the image reference the plan is scoped to, or empty for a cluster-wide plan
+	Image    string
+	Upgrades []Upgrade
+}
+
+// Upgrade a package version upgrade and the CVEs it resolves
+type Upgrade struct {
+	Package          string // package name (eg. openssl, libcurl)
+	InstalledVersion string // the vulnerable installed package version
+	// TargetVersion is the lowest version that resolves every fixable CVE of the installed version
+	TargetVersion string
+	// Resolved counts the CVEs the upgrade resolves by severity
+	Resolved namespaceview.SeverityTotals
+	// CVEs are the IDs of the CVEs the upgrade resolves
+	CVEs []string
+	// UnfixedCount is the number of the installed version's CVEs that don't have a fix available, and remain after the upgrade
+	UnfixedCount int
+	Images       []packagesview.Image            // images shipping the installed version
+	Resources    []packagesview.ResourceMetadata // workloads running those images
+}
+
+// RiskScore returns the severity-weighted number of CVEs the upgrade resolves
+func (u Upgrade) RiskScore() int {
+	return u.Resolved.RiskScore()
+}
+
+// Impact returns the risk the upgrade removes across the cluster, the risk score for each workload running the package
+func (u Upgrade) Impact() int {
+	if len(u.Resources) == 0 {
+		return u.RiskScore()
+	}
+	return u.RiskScore() * len(u.Resources)
+}
+
+// Totals returns the CVEs resolved by every upgrade in the plan by severity
+func (v View) Totals() namespaceview.SeverityTotals {
+	var totals namespaceview.SeverityTotals
+	for _, u := range v.Upgrades {
+		totals = totals.Add(u.Resolved)
+	}
+	return totals
+}
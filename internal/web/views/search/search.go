@@ -0,0 +1,304 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	clusterrolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/clusterroles"
+	configauditsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/configaudits"
+	exposedsecretsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/exposedsecrets"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+	rolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/roles"
+)
+
+const (
+	// MinQueryLength is the shortest query that's searched, shorter queries match too much to be useful
+	MinQueryLength = 2
+	// MaxGroupResults is the most results returned for each group
+	MaxGroupResults = 50
+)
+
+// Sources contains the report views that are searched
+type Sources struct {
+	Images         imagesview.View
+	ConfigAudits   configauditsview.View
+	Roles          rolesview.View
+	ClusterRoles   clusterrolesview.View
+	ExposedSecrets exposedsecretsview.View
+}
+
+// GetView searches the sources for a case insensitive substring match of the query
+// Groups without any matches are left out of the view
+func GetView(query string, sources Sources) View {
+	v := View{Query: strings.TrimSpace(query)}
+	if len(v.Query) < MinQueryLength {
+		return v
+	}
+	q := strings.ToLower(v.Query)
+
+	groups := []*group{
+		searchCVEs(q, sources.Images),
+		searchPackages(q, sources.Images),
+		searchImages(q, sources.Images),
+		searchNamespaces(q, sources),
+		searchWorkloads(q, sources),
+		searchConfigAuditChecks(q, sources.ConfigAudits),
+		searchRbacChecks(q, sources.Roles, sources.ClusterRoles),
+		searchSecrets(q, sources.ExposedSecrets),
+	}
+	for _, g := range groups {
+		if len(g.results) == 0 {
+			continue
+		}
+		v.Groups = append(v.Groups, g.toGroup())
+	}
+
+	return v
+}
+
+// group collects the results of a kind, deduplicated by title and URL
+type group struct {
+	name    string
+	results map[string]Result
+}
+
+func newGroup(name string) *group {
+	return &group{name: name, results: make(map[string]Result)}
+}
+
+func (g *group) add(r Result) {
+	key := r.Title + "\x00" + r.URL
+	if _, ok := g.results[key]; ok {
+		return
+	}
+	g.results[key] = r
+}
+
+// toGroup sorts the results by title and truncates them to MaxGroupResults
+func (g *group) toGroup() Group {
+	out := Group{Name: g.name, Total: len(g.results)}
+	for _, r := range g.results {
+		out.Results = append(out.Results, r)
+	}
+	sort.Slice(out.Results, func(j, k int) bool {
+		if out.Results[j].Title != out.Results[k].Title {
+			return out.Results[j].Title < out.Results[k].Title
+		}
+		return out.Results[j].URL < out.Results[k].URL
+	})
+	if len(out.Results) > MaxGroupResults {
+		out.Results = out.Results[:MaxGroupResults]
+	}
+	return out
+}
+
+// matches returns true if any of the fields contain the lowercased query
+func matches(q string, fields ...string) bool {
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), q) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageVulnerabilities returns all of an image's vulnerabilities
+func imageVulnerabilities(image imagesview.Data) []imagesview.Vulnerability {
+	var vulns []imagesview.Vulnerability
+	vulns = append(vulns, image.CriticalVulnerabilities...)
+	vulns = append(vulns, image.HighVulnerabilities...)
+	vulns = append(vulns, image.MediumVulnerabilities...)
+	vulns = append(vulns, image.LowVulnerabilities...)
+	return vulns
+}
+
+func searchCVEs(q string, images imagesview.View) *group {
+	g := newGroup("CVEs")
+	imageCounts := make(map[string]map[string]struct{})
+	for _, image := range images {
+		for _, vuln := range imageVulnerabilities(image) {
+			if !matches(q, vuln.ID) {
+				continue
+			}
+			if imageCounts[vuln.ID] == nil {
+				imageCounts[vuln.ID] = make(map[string]struct{})
+			}
+			imageCounts[vuln.ID][image.FullName()] = struct{}{}
+			g.add(Result{
+				Title:  vuln.ID,
+				Detail: vuln.Severity + " " + vuln.Title,
+				URL:    "/cve?" + url.Values{"id": {vuln.ID}}.Encode(),
+			})
+		}
+	}
+	for key, r := range g.results {
+		r.Detail = fmt.Sprintf("%s (%d images)", r.Detail, len(imageCounts[r.Title]))
+		g.results[key] = r
+	}
+	return g
+}
+
+func searchPackages(q string, images imagesview.View) *group {
+	g := newGroup("Packages")
+	for _, image := range images {
+		for _, vuln := range imageVulnerabilities(image) {
+			if !matches(q, vuln.Resource) {
+				continue
+			}
+			g.add(Result{
+				Title:  vuln.Resource,
+				Detail: "Vulnerable package",
+				URL:    "/packages?" + url.Values{"name": {vuln.Resource}, "showignored": {"true"}}.Encode(),
+			})
+		}
+	}
+	return g
+}
+
+func searchImages(q string, images imagesview.View) *group {
+	g := newGroup("Images")
+	for _, image := range images {
+		if !matches(q, image.FullName()) {
+			continue
+		}
+		params := url.Values{"repository": {image.Name}, "tag": {image.Tag}, "digest": {image.Digest}}
+		if image.Registry != "" {
+			params.Set("registry", image.Registry)
+		}
+		detail := fmt.Sprintf("%d vulnerabilities", len(imageVulnerabilities(image)))
+		if image.Unscanned {
+			detail = "Unscanned"
+		}
+		g.add(Result{
+			Title:  image.FullName(),
+			Detail: detail,
+			URL:    "/image?" + params.Encode(),
+		})
+	}
+	return g
+}
+
+func searchNamespaces(q string, sources Sources) *group {
+	g := newGroup("Namespaces")
+	add := func(namespace string) {
+		if namespace == "" || !matches(q, namespace) {
+			return
+		}
+		g.add(Result{
+			Title:  namespace,
+			Detail: "Namespace",
+			URL:    "/namespace?" + url.Values{"name": {namespace}}.Encode(),
+		})
+	}
+	for _, image := range sources.Images {
+		for r := range image.Resources {
+			add(r.Namespace)
+		}
+	}
+	for _, audit := range sources.ConfigAudits {
+		add(audit.Namespace)
+	}
+	for _, role := range sources.Roles {
+		add(role.Namespace)
+	}
+	for _, secret := range sources.ExposedSecrets {
+		for r := range secret.Resources {
+			add(r.Namespace)
+		}
+	}
+	return g
+}
+
+func searchWorkloads(q string, sources Sources) *group {
+	g := newGroup("Workloads")
+	add := func(kind, name, namespace string) {
+		if name == "" || !matches(q, name) {
+			return
+		}
+		g.add(Result{
+			Title:  name,
+			Detail: kind + " in " + namespace,
+			URL:    "/workload?" + url.Values{"namespace": {namespace}, "kind": {kind}, "name": {name}}.Encode(),
+		})
+	}
+	for _, image := range sources.Images {
+		for r := range image.Resources {
+			add(r.Kind, r.Name, r.Namespace)
+		}
+	}
+	for _, secret := range sources.ExposedSecrets {
+		for r := range secret.Resources {
+			add(r.Kind, r.Name, r.Namespace)
+		}
+	}
+	return g
+}
+
+func searchConfigAuditChecks(q string, audits configauditsview.View) *group {
+	g := newGroup("Config Audit Checks")
+	for _, audit := range audits {
+		checks := append(append(append(append([]configauditsview.Vulnerability{}, audit.CriticalVulnerabilities...), audit.HighVulnerabilities...), audit.MediumVulnerabilities...), audit.LowVulnerabilities...)
+		for _, check := range checks {
+			if !matches(q, check.ID) {
+				continue
+			}
+			g.add(Result{
+				Title:  check.ID + " " + check.Title,
+				Detail: fmt.Sprintf("%s %s in %s", audit.Kind, audit.Name, audit.Namespace),
+				URL:    "/configaudit?" + url.Values{"name": {audit.Name}, "namespace": {audit.Namespace}, "kind": {audit.Kind}}.Encode(),
+			})
+		}
+	}
+	return g
+}
+
+func searchRbacChecks(q string, roles rolesview.View, clusterRoles clusterrolesview.View) *group {
+	g := newGroup("RBAC Checks")
+	for _, role := range roles {
+		checks := append(append(append(append([]rolesview.Vulnerability{}, role.CriticalVulnerabilities...), role.HighVulnerabilities...), role.MediumVulnerabilities...), role.LowVulnerabilities...)
+		for _, check := range checks {
+			if !matches(q, check.ID) {
+				continue
+			}
+			g.add(Result{
+				Title:  check.ID + " " + check.Title,
+				Detail: fmt.Sprintf("Role %s in %s", role.Name, role.Namespace),
+				URL:    "/role?" + url.Values{"name": {role.Name}, "namespace": {role.Namespace}}.Encode(),
+			})
+		}
+	}
+	for _, role := range clusterRoles {
+		checks := append(append(append(append([]clusterrolesview.Vulnerability{}, role.CriticalVulnerabilities...), role.HighVulnerabilities...), role.MediumVulnerabilities...), role.LowVulnerabilities...)
+		for _, check := range checks {
+			if !matches(q, check.ID) {
+				continue
+			}
+			g.add(Result{
+				Title:  check.ID + " " + check.Title,
+				Detail: "ClusterRole " + role.Name,
+				URL:    "/clusterrole?" + url.Values{"name": {role.Name}}.Encode(),
+			})
+		}
+	}
+	return g
+}
+
+func searchSecrets(q string, secrets exposedsecretsview.View) *group {
+	g := newGroup("Exposed Secrets")
+	for _, image := range secrets {
+		all := append(append(append(append([]exposedsecretsview.Secret{}, image.Critical...), image.High...), image.Medium...), image.Low...)
+		for _, secret := range all {
+			if !matches(q, secret.Title) {
+				continue
+			}
+			g.add(Result{
+				Title:  secret.Title,
+				Detail: fmt.Sprintf("%s in %s", secret.Target, image.Name),
+				URL:    "/exposedsecret?" + url.Values{"image": {image.Name}, "digest": {image.Digest}}.Encode(),
+			})
+		}
+	}
+	return g
+}
@@ -0,0 +1,36 @@
+package search
+
+// View the results of a search across every report type, grouped by what matched
+type View struct {
+	Query  string  `json:"query"`
+	Groups []Group `json:"groups"`
+}
+
+// Group a kind of search result, like CVEs or workloads
+type Group struct {
+	Name    string   `json:"name"`
+	Results []Result `json:"results"`
+	// Total is the number of matches, which may be more than the results returned
+	Total int `json:"total"`
+}
+
+// Result a single search result linking to the page that shows it
+type Result struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+	URL    string `json:"url"`
+}
+
+// Truncated returns true if the group has more matches than results
+func (g Group) Truncated() bool {
+	return g.Total > len(g.Results)
+}
+
+// ResultCount returns the total number of matches across all groups
+func (v View) ResultCount() int {
+	var count int
+	for _, g := range v.Groups {
+		count += g.Total
+	}
+	return count
+}
@@ -0,0 +1,79 @@
+package sla
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Policy sets the number of days findings of each severity have to be remediated in, 0 means findings of that severity have no deadline
+type Policy struct {
+	Name string `json:"name"`
+	// NamespaceSelector limits the policy to namespaces with all of these labels, a policy without one applies to every namespace
+	NamespaceSelector map[string]string `json:"namespaceSelector,omitempty"`
+	Critical          int               `json:"critical"`
+	High              int               `json:"high"`
+	Medium            int               `json:"medium"`
+	Low               int               `json:"low"`
+}
+
+// Policies are evaluated in order, and the first policy matching a namespace's labels applies to it
+type Policies []Policy
+
+// ParsePolicies parses a YAML or JSON list of policies
+func ParsePolicies(raw string) (Policies, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var policies Policies
+	if err := yaml.UnmarshalStrict([]byte(raw), &policies); err != nil {
+		return nil, fmt.Errorf("error parsing SLA policies: %w", err)
+	}
+
+	for i, p := range policies {
+		if p.Name == "" {
+			policies[i].Name = fmt.Sprintf("policy %d", i+1)
+		}
+		if p.Critical < 0 || p.High < 0 || p.Medium < 0 || p.Low < 0 {
+			return nil, fmt.Errorf("SLA policy %q has a negative number of days", policies[i].Name)
+		}
+	}
+	return policies, nil
+}
+
+// For returns the first policy matching a namespace's labels, and false if none of them match
+func (p Policies) For(labels map[string]string) (Policy, bool) {
+	for _, policy := range p {
+		if policy.matches(labels) {
+			return policy, true
+		}
+	}
+	return Policy{}, false
+}
+
+// matches returns true if the labels contain every label in the policy's namespace selector
+func (p Policy) matches(labels map[string]string) bool {
+	for k, v := range p.NamespaceSelector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Days returns the number of days findings of a severity have to be remediated in, 0 if they have no deadline
+func (p Policy) Days(severity string) int {
+	switch strings.ToUpper(severity) {
+	case "CRITICAL":
+		return p.Critical
+	case "HIGH":
+		return p.High
+	case "MEDIUM":
+		return p.Medium
+	case "LOW":
+		return p.Low
+	}
+	return 0
+}
@@ -0,0 +1,136 @@
+package sla
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/db"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+)
+
+// GetView evaluates the SLA policies against the images view's vulnerabilities and when the findings ledger first saw them
+// The images view should leave out ignored vulnerabilities, since an approved ignore exempts a finding from its SLA
+// A finding is evaluated once for each namespace running the image, using the policy matching that namespace's labels
+func GetView(images imagesview.View, firstSeen map[db.FindingKey]time.Time, namespaceLabels map[string]map[string]string, config Config, now time.Time) View {
+	v := View{
+		Policies: config.Policies,
+	}
+	if len(config.Policies) == 0 {
+		return v
+	}
+
+	counts := make(map[Count]Count)
+	for _, image := range images {
+		if image.Unscanned {
+			continue
+		}
+
+		// Group the image's resources by namespace, since each namespace may have its own policy and owner
+		namespaces := make(map[string][]imagesview.ResourceMetadata)
+		for resource := range image.Resources {
+			namespaces[resource.Namespace] = append(namespaces[resource.Namespace], resource)
+		}
+		for _, resources := range namespaces {
+			sortResources(resources)
+		}
+
+		for _, vulns := range [][]imagesview.Vulnerability{image.CriticalVulnerabilities, image.HighVulnerabilities, image.MediumVulnerabilities, image.LowVulnerabilities} {
+			for _, vuln := range vulns {
+				severity := strings.ToUpper(vuln.Severity)
+				seen, tracked := firstSeen[db.FindingKey{
+					Registry:   image.Registry,
+					Repository: image.Name,
+					CVEID:      vuln.ID,
+					Package:    vuln.Resource,
+				}]
+
+				for ns, resources := range namespaces {
+					policy, ok := config.Policies.For(namespaceLabels[ns])
+					if !ok || policy.Days(severity) == 0 {
+						continue
+					}
+					if !tracked {
+						v.Untracked++
+						continue
+					}
+
+					due := seen.Add(time.Duration(policy.Days(severity)) * 24 * time.Hour)
+					status := ""
+					switch {
+					case !now.Before(due):
+						status = StatusBreached
+					case !now.Before(due.Add(-config.Warning)):
+						status = StatusDueSoon
+					default:
+						continue
+					}
+
+					v.Findings = append(v.Findings, Finding{
+						Registry:    image.Registry,
+						Repository:  image.Name,
+						Tag:         image.Tag,
+						Digest:      image.Digest,
+						CVEID:       vuln.ID,
+						Package:     vuln.Resource,
+						Severity:    severity,
+						Namespace:   ns,
+						Resources:   resources,
+						Owner:       namespaceLabels[ns][config.OwnerLabel],
+						Policy:      policy.Name,
+						FirstSeen:   seen,
+						Due:         due,
+						Status:      status,
+						evaluatedAt: now,
+					})
+
+					key := Count{Namespace: ns, Severity: severity}
+					c := counts[key]
+					c.Namespace, c.Severity = ns, severity
+					if status == StatusBreached {
+						c.Breached++
+					} else {
+						c.DueSoon++
+					}
+					counts[key] = c
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(v.Findings, func(i, j int) bool {
+		return v.Findings[i].Due.Before(v.Findings[j].Due)
+	})
+
+	for _, c := range counts {
+		v.Counts = append(v.Counts, c)
+	}
+	sort.Slice(v.Counts, func(i, j int) bool {
+		if v.Counts[i].Namespace != v.Counts[j].Namespace {
+			return v.Counts[i].Namespace < v.Counts[j].Namespace
+		}
+		return severityRank(v.Counts[i].Severity) < severityRank(v.Counts[j].Severity)
+	})
+
+	return v
+}
+
+// sortResources sorts resources by kind then name
+func sortResources(resources []imagesview.ResourceMetadata) {
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind < resources[j].Kind
+		}
+		return resources[i].Name < resources[j].Name
+	})
+}
+
+// severityRank returns the index of a severity in Severities, so counts sort from most to least severe
+func severityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(Severities)
+}
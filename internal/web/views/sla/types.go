@@ -0,0 +1,114 @@
+package sla
+
+import (
+	"time"
+
+	"github.com/starttoaster/trivy-operator-explorer/internal/utils"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+)
+
+// Config contains the settings SLA policies are evaluated with
+type Config struct {
+	Policies Policies
+	// Warning is how long before its deadline a finding is listed as due soon
+	Warning time.Duration
+	// OwnerLabel is the namespace label naming the team that owns the namespace's findings
+	OwnerLabel string
+}
+
+// Finding statuses
+const (
+	StatusBreached = "breached"
+	StatusDueSoon  = "due soon"
+)
+
+// Severities are the severities SLA policies set deadlines for, from most to least severe
+var Severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW"}
+
+// View contains the findings breaching or close to breaching their SLA
+type View struct {
+	// Findings are sorted by deadline, so the longest overdue are first
+	Findings []Finding `json:"findings"`
+	// Counts are the number of breached and due soon findings per namespace and severity, sorted by namespace then severity
+	Counts []Count `json:"counts"`
+	// Untracked is the number of findings with an SLA that haven't been recorded by the findings ledger yet, so can't be evaluated
+	Untracked int `json:"untracked"`
+
+	Policies Policies `json:"policies"`
+}
+
+// Finding is a vulnerability in an image run in a namespace, and its deadline
+type Finding struct {
+	Registry   string                        `json:"registry"`
+	Repository string                        `json:"repository"`
+	Tag        string                        `json:"tag"`
+	Digest     string                        `json:"digest"`
+	CVEID      string                        `json:"cve_id"`
+	Package    string                        `json:"package"`
+	Severity   string                        `json:"severity"`
+	Namespace  string                        `json:"namespace"`
+	Resources  []imagesview.ResourceMetadata `json:"resources"`
+	Owner      string                        `json:"owner"`
+	Policy     string                        `json:"policy"`
+	FirstSeen  time.Time                     `json:"first_seen"`
+	Due        time.Time                     `json:"due"`
+	Status     string                        `json:"status"`
+
+	// evaluatedAt is when the finding's status was evaluated, for formatting the time left
+	evaluatedAt time.Time
+}
+
+// Count contains the number of breached and due soon findings of a severity in a namespace
+type Count struct {
+	Namespace string `json:"namespace"`
+	Severity  string `json:"severity"`
+	Breached  int    `json:"breached"`
+	DueSoon   int    `json:"due_soon"`
+}
+
+// FullName returns the finding's image name as it would be written in a Pod spec
+func (f Finding) FullName() string {
+	return utils.AssembleImageFullName(f.Registry, f.Repository, f.Tag, f.Digest)
+}
+
+// TimeLeft returns how long is left until the finding's deadline, or how long it's overdue by, formatted for display
+func (f Finding) TimeLeft() string {
+	if f.Status == StatusBreached {
+		return utils.FormatDuration(f.evaluatedAt.Sub(f.Due)) + " overdue"
+	}
+	return utils.FormatDuration(f.Due.Sub(f.evaluatedAt)) + " left"
+}
+
+// Breached returns the total number of breached findings
+func (v View) Breached() int {
+	var total int
+	for _, c := range v.Counts {
+		total += c.Breached
+	}
+	return total
+}
+
+// DueSoon returns the total number of findings due soon
+func (v View) DueSoon() int {
+	var total int
+	for _, c := range v.Counts {
+		total += c.DueSoon
+	}
+	return total
+}
+
+// SeverityCounts returns the breached and due soon totals for each severity across every namespace
+func (v View) SeverityCounts() []Count {
+	var counts []Count
+	for _, severity := range Severities {
+		c := Count{Severity: severity}
+		for _, nc := range v.Counts {
+			if nc.Severity == severity {
+				c.Breached += nc.Breached
+				c.DueSoon += nc.DueSoon
+			}
+		}
+		counts = append(counts, c)
+	}
+	return counts
+}
@@ -0,0 +1,26 @@
+package workload
+
+import (
+	configauditsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/configaudits"
+	exposedsecretsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/exposedsecrets"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+	rolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/roles"
+)
+
+// View contains every report's data for a single workload
+type View struct {
+	Namespace string
+	Kind      string
+	Name      string
+	// ReplicaSets are the ReplicaSets owned by a Deployment, which Trivy Operator reports on in place of the Deployment
+	ReplicaSets []string
+	// ServiceAccounts used by the workload's Pods
+	ServiceAccounts []string
+
+	// Images run by the workload's containers
+	Images         imagesview.View
+	ConfigAudits   configauditsview.View
+	ExposedSecrets exposedsecretsview.View
+	// Roles bound to the workload's ServiceAccounts
+	Roles rolesview.View
+}
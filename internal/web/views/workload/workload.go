@@ -0,0 +1,97 @@
+package workload
+
+import (
+	"sort"
+
+	configauditsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/configaudits"
+	exposedsecretsview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/exposedsecrets"
+	imagesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/images"
+	rolesview "github.com/starttoaster/trivy-operator-explorer/internal/web/views/roles"
+)
+
+// Filters contains the supported filters for the workload view
+type Filters struct {
+	Namespace string
+	Kind      string
+	Name      string
+
+	// optional data about the workload from the Kubernetes API
+	ReplicaSets     []string
+	ServiceAccounts []string
+	Roles           []string
+}
+
+// GetView converts the cluster-wide views of each report type to the /workload view
+// Reports are matched to the workload by the trivy-operator.resource.* labels, including the reports of a Deployment's ReplicaSets
+// returns view data and "true" if any report was found for the workload
+func GetView(filters Filters, images imagesview.View, audits configauditsview.View, roles rolesview.View, secrets exposedsecretsview.View) (View, bool) {
+	v := View{
+		Namespace:       filters.Namespace,
+		Kind:            filters.Kind,
+		Name:            filters.Name,
+		ReplicaSets:     filters.ReplicaSets,
+		ServiceAccounts: filters.ServiceAccounts,
+	}
+	sort.Strings(v.ReplicaSets)
+	sort.Strings(v.ServiceAccounts)
+
+	// isWorkload checks whether a report's resource is this workload, or one of its ReplicaSets
+	isWorkload := func(namespace, kind, name string) bool {
+		if namespace != filters.Namespace {
+			return false
+		}
+		if kind == filters.Kind && name == filters.Name {
+			return true
+		}
+		if kind == "ReplicaSet" {
+			for _, rs := range filters.ReplicaSets {
+				if name == rs {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, image := range images {
+		if image.Unscanned {
+			continue
+		}
+		for resource := range image.Resources {
+			if isWorkload(resource.Namespace, resource.Kind, resource.Name) {
+				v.Images = append(v.Images, image)
+				break
+			}
+		}
+	}
+
+	for _, audit := range audits {
+		if isWorkload(audit.Namespace, audit.Kind, audit.Name) {
+			v.ConfigAudits = append(v.ConfigAudits, audit)
+		}
+	}
+
+	for _, secret := range secrets {
+		for resource := range secret.Resources {
+			if isWorkload(resource.Namespace, resource.Kind, resource.Name) {
+				v.ExposedSecrets = append(v.ExposedSecrets, secret)
+				break
+			}
+		}
+	}
+
+	for _, role := range roles {
+		if role.Namespace != filters.Namespace {
+			continue
+		}
+		for _, name := range filters.Roles {
+			if role.Name == name {
+				v.Roles = append(v.Roles, role)
+				break
+			}
+		}
+	}
+
+	found := len(v.Images) > 0 || len(v.ConfigAudits) > 0 || len(v.ExposedSecrets) > 0
+	return v, found
+}
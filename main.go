@@ -25,14 +25,34 @@ import (
 //go:embed static/compliancereports.html
 //go:embed static/compliancereport.html
 //go:embed static/index.html
+//go:embed static/tokens.html
+//go:embed static/error.html
+//go:embed static/banner.html
+//go:embed static/cve.html
+//go:embed static/packages.html
+//go:embed static/namespaces.html
+//go:embed static/namespace.html
+//go:embed static/workload.html
+//go:embed static/search.html
+//go:embed static/compare.html
+//go:embed static/remediation.html
+//go:embed static/mttr.html
+//go:embed static/sla.html
+//go:embed static/changes.html
+//go:embed static/digest.html
+//go:embed static/digest.txt
 //go:embed static/img/t.ico
 //go:embed static/css/output.css
 //go:embed static/css/extra.css
 //go:embed static/js/chart.js
+//go:embed static/js/trend-chart.js
 //go:embed static/js/images-hasfix.js
 //go:embed static/js/images-resources-table.js
 //go:embed static/js/image-resources.js
 //go:embed static/js/image-ignore.js
+//go:embed static/js/image-details.js
+//go:embed static/js/cve-ignore.js
+//go:embed static/js/ticket.js
 var static embed.FS
 
 func main() {
@@ -0,0 +1,127 @@
+document.addEventListener('DOMContentLoaded', function() {
+    const modal = document.getElementById('cve-ignore-modal');
+    const form = document.getElementById('cve-ignore-form');
+    const quickReasons = ['Risk is tolerable', 'This alert is inaccurate', 'Vulnerable code is not used'];
+
+    function closeModal() {
+        modal.classList.add('hidden');
+        form.reset();
+    }
+
+    document.addEventListener('click', function(e) {
+        // Open the ignore modal
+        if (e.target.id === 'cve-ignore-btn') {
+            e.preventDefault();
+            modal.classList.remove('hidden');
+        }
+
+        // Close the modal with the cancel button, or by clicking on the backdrop
+        if (e.target.id === 'cve-ignore-cancel-btn' || e.target === modal) {
+            closeModal();
+        }
+
+        // Prefill the textarea with the selected quick reason, keeping any custom text
+        if (e.target.type === 'radio' && e.target.name === 'quick-reason') {
+            const textarea = document.getElementById('cve-reason-textarea');
+            let customText = textarea.value.trim();
+            for (const qr of quickReasons) {
+                if (customText.startsWith(qr)) {
+                    customText = customText.substring(qr.length).trim().replace(/^\.\s*/, '').trim();
+                    break;
+                }
+            }
+            textarea.value = customText ? e.target.value + '. ' + customText : e.target.value + '.';
+        }
+    });
+
+    // Close the modal with the escape key
+    document.addEventListener('keydown', function(e) {
+        if (e.key === 'Escape' && !modal.classList.contains('hidden')) {
+            closeModal();
+        }
+    });
+
+    // Ignore the CVE in every affected image
+    form.addEventListener('submit', function(e) {
+        e.preventDefault();
+
+        const reason = (new FormData(form).get('reason') || '').trim();
+        if (!reason) {
+            showErrorMessage('Please provide a reason for ignoring this CVE.');
+            return;
+        }
+
+        const cveId = form.dataset.cveId;
+        const submitBtn = document.getElementById('cve-ignore-submit-btn');
+        const originalText = submitBtn.textContent;
+        submitBtn.textContent = 'Ignoring...';
+        submitBtn.disabled = true;
+
+        fetch('/ignore/cve', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-CSRF-Token': csrfToken(),
+            },
+            body: JSON.stringify({
+                cve_id: cveId,
+                reason: reason,
+            })
+        })
+        .then(response => {
+            if (response.ok) {
+                showSuccessMessage(`${cveId} has been ignored in all affected images.`);
+                setTimeout(() => {
+                    window.location.href = window.location.href;
+                }, 1000);
+            } else {
+                throw new Error(`HTTP error! status: ${response.status}`);
+            }
+        })
+        .catch(error => {
+            console.error('Error ignoring CVE:', error);
+            showErrorMessage(`Failed to ignore ${cveId}. Please try again.`);
+        })
+        .finally(() => {
+            submitBtn.textContent = originalText;
+            submitBtn.disabled = false;
+        });
+    });
+});
+
+// Returns the CSRF token rendered into the page, which must be sent with every request that makes changes
+function csrfToken() {
+    const meta = document.querySelector('meta[name="csrf-token"]');
+    return meta ? meta.getAttribute('content') : '';
+}
+
+// Helper functions for showing messages
+function showSuccessMessage(message) {
+    showMessage(message, 'success');
+}
+
+function showErrorMessage(message) {
+    showMessage(message, 'error');
+}
+
+function showMessage(message, type) {
+    const messageEl = document.createElement('div');
+    messageEl.className = `fixed top-4 left-4 z-50 px-4 py-3 rounded-lg shadow-lg transition-all duration-300 ${
+        type === 'success'
+            ? 'bg-green-100 text-green-800 border border-green-200 dark:bg-green-900 dark:text-green-200 dark:border-green-700'
+            : 'bg-red-100 text-red-800 border border-red-200 dark:bg-red-900 dark:text-red-200 dark:border-red-700'
+    }`;
+    messageEl.textContent = message;
+    document.body.appendChild(messageEl);
+
+    // Auto remove after 5 seconds
+    setTimeout(() => {
+        messageEl.style.opacity = '0';
+        messageEl.style.transform = 'translateX(100%)';
+        setTimeout(() => {
+            if (messageEl.parentNode) {
+                messageEl.parentNode.removeChild(messageEl);
+            }
+        }, 300);
+    }, 5000);
+}
@@ -0,0 +1,27 @@
+// Toggle the detail panel below a CVE's row
+function toggleVulnerabilityDetails(id) {
+    const row = document.getElementById(`vuln-details-${id}`);
+    const icon = document.getElementById(`vuln-details-icon-${id}`);
+    if (!row) {
+        return;
+    }
+    row.classList.toggle('hidden');
+    if (icon) {
+        icon.style.transform = row.classList.contains('hidden') ? 'rotate(0deg)' : 'rotate(180deg)';
+    }
+}
+
+// Apply the target, class and published date filters to the page URL, keeping the page's other filters
+function applyVulnerabilityFilters(form) {
+    const url = new URL(window.location.href);
+    ['target', 'class', 'publishedafter', 'publishedbefore'].forEach(name => {
+        const value = form.elements[name].value;
+        if (value) {
+            url.searchParams.set(name, value);
+        } else {
+            url.searchParams.delete(name);
+        }
+    });
+    window.location.href = url.toString();
+    return false;
+}
@@ -216,6 +216,12 @@ document.addEventListener('DOMContentLoaded', function() {
                 cve_ids: Array.from(selectedCVEs),
                 reason: reason.trim(),
             };
+
+            // Ignores expire at the start of the chosen day, in the browser's time zone
+            const expires = formData.get('expires');
+            if (expires) {
+                requestData.expires_at = new Date(expires + 'T00:00:00').toISOString();
+            }
             
             // Show loading state
             const submitBtn = document.getElementById('bulk-submit-btn');
@@ -228,6 +234,7 @@ document.addEventListener('DOMContentLoaded', function() {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
+                    'X-CSRF-Token': csrfToken(),
                 },
                 body: JSON.stringify(requestData)
             })
@@ -296,6 +303,7 @@ document.addEventListener('DOMContentLoaded', function() {
                 method: 'DELETE',
                 headers: {
                     'Content-Type': 'application/json',
+                    'X-CSRF-Token': csrfToken(),
                 },
                 body: JSON.stringify(requestData)
             })
@@ -341,6 +349,12 @@ document.addEventListener('DOMContentLoaded', function() {
     
 });
 
+// Returns the CSRF token rendered into the page, which must be sent with every request that makes changes
+function csrfToken() {
+    const meta = document.querySelector('meta[name="csrf-token"]');
+    return meta ? meta.getAttribute('content') : '';
+}
+
 // Helper functions for showing messages
 function showSuccessMessage(message) {
     showMessage(message, 'success');
@@ -0,0 +1,53 @@
+// Creates tickets from the "create ticket" buttons on the image and CVE pages
+// csrfToken, showSuccessMessage and showErrorMessage come from the page's ignore script
+document.addEventListener('DOMContentLoaded', function() {
+    document.addEventListener('click', function(e) {
+        const button = e.target.closest('.create-ticket-btn');
+        if (!button) {
+            return;
+        }
+
+        const cveId = button.getAttribute('data-cve-id');
+        const requestData = {
+            registry: button.getAttribute('data-registry') || '',
+            repository: button.getAttribute('data-repository') || '',
+            cve_id: cveId
+        };
+
+        // Show loading state, creating an issue in the tracker can take a moment
+        button.disabled = true;
+        const originalText = button.textContent;
+        button.textContent = 'creating...';
+
+        fetch('/ticket', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+                'X-CSRF-Token': csrfToken(),
+            },
+            body: JSON.stringify(requestData)
+        })
+        .then(response => {
+            if (!response.ok) {
+                // The server explains why the tracker rejected the ticket
+                return response.text().then(text => {
+                    throw new Error(text.trim() || `HTTP error! status: ${response.status}`);
+                });
+            }
+            return response.json();
+        })
+        .then(ticket => {
+            showSuccessMessage(`Ticket ${ticket.issue_key} created for ${cveId}.`);
+            // Reload page to show the ticket on every row it applies to, preserving URL parameters
+            setTimeout(() => {
+                window.location.href = window.location.href;
+            }, 1000);
+        })
+        .catch(error => {
+            console.error('Error creating ticket:', error);
+            showErrorMessage(`Failed to create a ticket for ${cveId}. ${error.message}`);
+            button.disabled = false;
+            button.textContent = originalText;
+        });
+    });
+});
@@ -0,0 +1,87 @@
+// Trend charts drawn from the snapshots recorded by the server
+
+function trendChartDefaults() {
+    Chart.defaults.font.size = 16;
+    if (window.matchMedia && window.matchMedia('(prefers-color-scheme: dark)').matches) {
+        Chart.defaults.color = '#fff';
+    } else {
+        Chart.defaults.color = '#000';
+    }
+}
+
+function trendLabels(points) {
+    return points.map(p => new Date(p.taken_at).toLocaleString());
+}
+
+function trendDataset(label, data, color) {
+    return {
+        label: label,
+        data: data,
+        borderColor: `rgba(${color}, 1)`,
+        backgroundColor: `rgba(${color}, 0.8)`,
+        borderWidth: 2,
+        pointRadius: 2,
+        tension: 0.2
+    };
+}
+
+function trendOptions(title) {
+    return {
+        responsive: true,
+        maintainAspectRatio: false,
+        plugins: {
+            title: {
+                display: true,
+                text: title,
+                font: {
+                    size: 18
+                }
+            },
+            legend: {
+                position: 'bottom'
+            }
+        },
+        scales: {
+            y: {
+                beginAtZero: true,
+                ticks: {
+                    precision: 0
+                }
+            }
+        }
+    };
+}
+
+// Draws vulnerability counts by severity over time
+function renderSeverityTrend(canvasId, title, points) {
+    trendChartDefaults();
+    new Chart(document.getElementById(canvasId), {
+        type: 'line',
+        data: {
+            labels: trendLabels(points),
+            datasets: [
+                trendDataset('Critical', points.map(p => p.critical), '220, 53, 69'),
+                trendDataset('High', points.map(p => p.high), '255, 125, 20'),
+                trendDataset('Medium', points.map(p => p.medium), '255, 193, 7'),
+                trendDataset('Low', points.map(p => p.low), '13, 110, 253')
+            ]
+        },
+        options: trendOptions(title)
+    });
+}
+
+// Draws a compliance report's pass and fail counts over time
+function renderComplianceTrend(canvasId, title, points) {
+    trendChartDefaults();
+    new Chart(document.getElementById(canvasId), {
+        type: 'line',
+        data: {
+            labels: trendLabels(points),
+            datasets: [
+                trendDataset('Pass', points.map(p => p.pass_count), '39, 245, 127'),
+                trendDataset('Fail', points.map(p => p.fail_count), '249, 51, 51')
+            ]
+        },
+        options: trendOptions(title)
+    });
+}